@@ -0,0 +1,1773 @@
+// Package absostest provides a conformance test suite for implementations of
+// the absos interfaces.
+//
+// A provider proves it behaves like every other provider by running the
+// suite from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+//			return mystore.New(...)
+//		})
+//	}
+//
+// The factory is called once per subtest and must return an empty store.
+// Stores with a configurable page size should use a small one, such as 2,
+// so that the pagination tests span several pages. Tests of optional
+// capabilities, such as absos.MultipartBucket, are skipped for buckets that
+// do not implement them.
+package absostest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// Factory returns a new, empty object store for a single subtest.
+type Factory func(t *testing.T) absos.ObjectStore
+
+// RunConformance runs the conformance suite against stores created by factory.
+func RunConformance(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store absos.ObjectStore)
+	}{
+		{"CreateBucket", testCreateBucket},
+		{"ListBuckets", testListBuckets},
+		{"DeleteBucket", testDeleteBucket},
+		{"DeleteNonEmptyBucket", testDeleteNonEmptyBucket},
+		{"MissingBucket", testMissingBucket},
+		{"PutGet", testPutGet},
+		{"Overwrite", testOverwrite},
+		{"EmptyObject", testEmptyObject},
+		{"Head", testHead},
+		{"PutOptions", testPutOptions},
+		{"Checksums", testChecksums},
+		{"Delete", testDelete},
+		{"ObjectNotFound", testObjectNotFound},
+		{"PutBatch", testPutBatch},
+		{"Upload", testUpload},
+		{"Copy", testCopy},
+		{"CopyReplaceMetadata", testCopyReplaceMetadata},
+		{"CopyAcrossBuckets", testCopyAcrossBuckets},
+		{"Move", testMove},
+		{"DeleteMany", testDeleteMany},
+		{"DeletePrefix", testDeletePrefix},
+		{"ConditionalRead", testConditionalRead},
+		{"ConditionalWrite", testConditionalWrite},
+		{"Versioning", testVersioning},
+		{"VersioningSuspended", testVersioningSuspended},
+		{"Multipart", testMultipart},
+		{"MultipartAbort", testMultipartAbort},
+		{"ListObjects", testListObjects},
+		{"ListEmpty", testListEmpty},
+		{"ListPrefix", testListPrefix},
+		{"ListDelimiter", testListDelimiter},
+		{"ListPagination", testListPagination},
+		{"ListSpecialKeys", testListSpecialKeys},
+		{"PageObjects", testPageObjects},
+		{"GetRange", testGetRange},
+		{"Concurrent", testConcurrent},
+		{"ContextCanceled", testContextCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, factory(t))
+		})
+	}
+}
+
+// bucketName is the name of the bucket created by newBucket.
+const bucketName = "conformance-bucket"
+
+// newBucket creates a bucket in store and returns it.
+func newBucket(t *testing.T, store absos.ObjectStore) absos.Bucket {
+	t.Helper()
+
+	if err := store.CreateBucket(context.Background(), bucketName); err != nil {
+		t.Fatalf("CreateBucket(%q): %v", bucketName, err)
+	}
+
+	return findBucket(t, store, bucketName)
+}
+
+// findBucket returns the named bucket from store.ListBuckets.
+func findBucket(t *testing.T, store absos.ObjectStore, name string) absos.Bucket {
+	t.Helper()
+
+	buckets, err := store.ListBuckets(context.Background())
+	if err != nil {
+		t.Fatalf("ListBuckets: %v", err)
+	}
+
+	for _, b := range buckets {
+		if b.Name() == name {
+			return b
+		}
+	}
+
+	t.Fatalf("ListBuckets did not return bucket %q", name)
+	return nil
+}
+
+// put stores data under key.
+func put(t *testing.T, b absos.Bucket, key, data string) {
+	t.Helper()
+
+	if err := b.Put(context.Background(), key, strings.NewReader(data)); err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+}
+
+// get returns the contents of key.
+func get(t *testing.T, b absos.Bucket, key string) string {
+	t.Helper()
+
+	r, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading %q: %v", key, err)
+	}
+
+	return string(data)
+}
+
+// listAll walks every page of a listing and returns the keys and prefixes
+// in the order they were returned.
+func listAll(t *testing.T, b absos.Bucket, prefix, delimiter string) (keys, prefixes []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	token := ""
+
+	for pages := 0; ; pages++ {
+		if pages > 1000 {
+			t.Fatalf("listing %q/%q did not terminate", prefix, delimiter)
+		}
+
+		page, err := b.ObjectPage(ctx, prefix, delimiter, token)
+		if err != nil {
+			t.Fatalf("ObjectPage(%q, %q, %q): %v", prefix, delimiter, token, err)
+		}
+
+		for _, obj := range page.Objects() {
+			if seen[obj.Key()] {
+				t.Errorf("key %q returned twice", obj.Key())
+			}
+			seen[obj.Key()] = true
+			keys = append(keys, obj.Key())
+		}
+
+		for _, p := range page.Prefixes() {
+			if seen[p] {
+				t.Errorf("prefix %q returned twice", p)
+			}
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+
+		if page.Last() {
+			if page.NextPage() != "" {
+				t.Errorf("last page returned token %q", page.NextPage())
+			}
+			return keys, prefixes
+		}
+
+		if page.NextPage() == "" {
+			t.Fatalf("page that is not last returned no token")
+		}
+		token = page.NextPage()
+	}
+}
+
+// expectBucketError checks that err wraps target in a *absos.BucketError for bucket.
+func expectBucketError(t *testing.T, op string, err error, bucket string, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Errorf("%s: expected %v, got %v", op, target, err)
+		return
+	}
+
+	var bucketErr *absos.BucketError
+	if !errors.As(err, &bucketErr) {
+		t.Errorf("%s: expected *absos.BucketError, got %T", op, err)
+	} else if bucketErr.Bucket != bucket {
+		t.Errorf("%s: expected bucket %q in error, got %q", op, bucket, bucketErr.Bucket)
+	}
+}
+
+// expectObjectError checks that err wraps target in a *absos.ObjectError for key.
+func expectObjectError(t *testing.T, op string, err error, bucket, key string, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Errorf("%s: expected %v, got %v", op, target, err)
+		return
+	}
+
+	var objErr *absos.ObjectError
+	if !errors.As(err, &objErr) {
+		t.Errorf("%s: expected *absos.ObjectError, got %T", op, err)
+	} else if objErr.Bucket != bucket || objErr.Key != key {
+		t.Errorf("%s: expected %q/%q in error, got %q/%q", op, bucket, key, objErr.Bucket, objErr.Key)
+	}
+}
+
+func testCreateBucket(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+
+	b := newBucket(t, store)
+
+	if b.Name() != bucketName {
+		t.Errorf("expected name %q, got %q", bucketName, b.Name())
+	}
+
+	if created := b.CreationTime(); created.Before(before) || created.After(time.Now().Add(time.Minute)) {
+		t.Errorf("unexpected creation time %v", created)
+	}
+
+	// Owner is optional but must be usable when present
+	if own := b.Owner(); own != nil {
+		_, _ = own.Name(), own.ID()
+	}
+
+	err := store.CreateBucket(ctx, bucketName)
+	expectBucketError(t, "CreateBucket duplicate", err, bucketName, absos.ErrBucketAlreadyExists)
+}
+
+func testListBuckets(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("ListBuckets: %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Errorf("expected no buckets in new store, got %d", len(buckets))
+	}
+
+	names := []string{"bucket-a", "bucket-b", "bucket-c"}
+	for _, name := range names {
+		if err := store.CreateBucket(ctx, name); err != nil {
+			t.Fatalf("CreateBucket(%q): %v", name, err)
+		}
+	}
+
+	buckets, err = store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("ListBuckets: %v", err)
+	}
+
+	var got []string
+	for _, b := range buckets {
+		got = append(got, b.Name())
+	}
+	sort.Strings(got)
+
+	if strings.Join(got, ",") != strings.Join(names, ",") {
+		t.Errorf("expected buckets %v, got %v", names, got)
+	}
+}
+
+func testDeleteBucket(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	newBucket(t, store)
+
+	if err := store.DeleteBucket(ctx, bucketName); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("ListBuckets: %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Errorf("expected no buckets after delete, got %d", len(buckets))
+	}
+
+	err = store.DeleteBucket(ctx, bucketName)
+	expectBucketError(t, "DeleteBucket missing", err, bucketName, absos.ErrBucketNotFound)
+
+	// The name can be reused
+	if err := store.CreateBucket(ctx, bucketName); err != nil {
+		t.Errorf("CreateBucket after delete: %v", err)
+	}
+}
+
+func testDeleteNonEmptyBucket(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+	put(t, b, "dir/key", "data")
+
+	err := store.DeleteBucket(ctx, bucketName)
+	expectBucketError(t, "DeleteBucket non-empty", err, bucketName, absos.ErrBucketNotEmpty)
+
+	if err := b.Delete(ctx, "dir/key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if err := store.DeleteBucket(ctx, bucketName); err != nil {
+		t.Errorf("DeleteBucket after emptying: %v", err)
+	}
+}
+
+func testMissingBucket(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	if err := store.DeleteBucket(ctx, bucketName); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+
+	// Operations through a stale handle report the missing bucket
+	_, err := b.ObjectPage(ctx, "", "", "")
+	expectBucketError(t, "ObjectPage", err, bucketName, absos.ErrBucketNotFound)
+
+	err = b.Put(ctx, "key", strings.NewReader("data"))
+	expectBucketError(t, "Put", err, bucketName, absos.ErrBucketNotFound)
+
+	_, err = b.Get(ctx, "key")
+	if !errors.Is(err, absos.ErrBucketNotFound) && !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("Get: expected ErrBucketNotFound or ErrObjectNotFound, got %v", err)
+	}
+
+	err = absos.DeletePrefix(ctx, b, "")
+	expectBucketError(t, "DeletePrefix", err, bucketName, absos.ErrBucketNotFound)
+}
+
+func testPutGet(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	data := strings.Repeat("0123456789", 10000)
+	put(t, b, "dir/large.bin", data)
+
+	if got := get(t, b, "dir/large.bin"); got != data {
+		t.Errorf("expected %d bytes, got %d", len(data), len(got))
+	}
+
+	// The reader passed to Put may be rewound
+	r := strings.NewReader("prefix:content")
+	if _, err := r.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(context.Background(), "seeked", r); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := get(t, b, "seeked"); got != "content" {
+		t.Errorf("expected data from the current offset %q, got %q", "content", got)
+	}
+}
+
+func testOverwrite(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	put(t, b, "key", "first version")
+	put(t, b, "key", "second")
+
+	if got := get(t, b, "key"); got != "second" {
+		t.Errorf("expected %q, got %q", "second", got)
+	}
+
+	header, err := b.Head(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if header.Size() != int64(len("second")) {
+		t.Errorf("expected size %d, got %d", len("second"), header.Size())
+	}
+}
+
+func testEmptyObject(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+	put(t, b, "empty", "")
+
+	if got := get(t, b, "empty"); got != "" {
+		t.Errorf("expected empty object, got %q", got)
+	}
+
+	header, err := b.Head(context.Background(), "empty")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if header.Size() != 0 {
+		t.Errorf("expected size 0, got %d", header.Size())
+	}
+}
+
+func testHead(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+	before := time.Now().Add(-time.Minute)
+
+	data := "Hello, World!"
+	put(t, b, "dir/hello.txt", data)
+
+	header, err := b.Head(context.Background(), "dir/hello.txt")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+
+	if header.Bucket() != bucketName || header.Key() != "dir/hello.txt" {
+		t.Errorf("expected %q/%q, got %q/%q", bucketName, "dir/hello.txt", header.Bucket(), header.Key())
+	}
+
+	if header.Size() != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), header.Size())
+	}
+
+	if mod := header.ModTime(); mod.Before(before) || mod.After(time.Now().Add(time.Minute)) {
+		t.Errorf("unexpected modification time %v", mod)
+	}
+
+	if header.MimeType() == "" {
+		t.Error("expected a MIME type")
+	}
+
+	if header.StorageClass() == "" {
+		t.Error("expected a storage class")
+	}
+
+	// The remaining fields are optional but must be callable
+	_, _, _ = header.AccessTime(), header.ETag(), header.Metadata()
+	_, _, _ = header.Version(), header.Redirect(), header.ServerSideEncryption()
+	_, _, _ = header.ContentEncoding(), header.CacheControl(), header.ContentDisposition()
+}
+
+func testPutOptions(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	err := b.Put(ctx, "report", strings.NewReader("{}"),
+		absos.WithContentType("application/json"),
+		absos.WithContentEncoding("gzip"),
+		absos.WithCacheControl("max-age=3600"),
+		absos.WithContentDisposition(`attachment; filename="report.json"`),
+		absos.WithMetadata(map[string]string{"owner": "alice"}),
+		absos.WithMetadata(map[string]string{"team": "storage"}),
+		absos.WithStorageClass("STANDARD_IA"),
+		absos.WithSSE(&absos.SSE{ServerSideEncryption: "AES256"}),
+	)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	header, err := b.Head(ctx, "report")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+
+	fields := []struct {
+		name, expected, actual string
+	}{
+		{"MimeType", "application/json", header.MimeType()},
+		{"ContentEncoding", "gzip", header.ContentEncoding()},
+		{"CacheControl", "max-age=3600", header.CacheControl()},
+		{"ContentDisposition", `attachment; filename="report.json"`, header.ContentDisposition()},
+		{"StorageClass", "STANDARD_IA", header.StorageClass()},
+	}
+	for _, f := range fields {
+		if f.actual != f.expected {
+			t.Errorf("expected %s %q, got %q", f.name, f.expected, f.actual)
+		}
+	}
+
+	metadata := header.Metadata()
+	if len(metadata) != 2 || metadata["owner"] != "alice" || metadata["team"] != "storage" {
+		t.Errorf("unexpected metadata %v", metadata)
+	}
+
+	if sse := header.ServerSideEncryption(); sse == nil || sse.ServerSideEncryption != "AES256" {
+		t.Errorf("expected AES256 server-side encryption, got %+v", sse)
+	}
+
+	// Overwriting without options clears the previous attributes
+	put(t, b, "report", "{}")
+
+	header, err = b.Head(ctx, "report")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+
+	if len(header.Metadata()) != 0 || header.CacheControl() != "" {
+		t.Errorf("expected attributes to be cleared, got metadata %v and Cache-Control %q",
+			header.Metadata(), header.CacheControl())
+	}
+}
+
+func testChecksums(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	data := "checksummed contents"
+	computed := absos.ComputeChecksums([]byte(data))
+
+	err := b.Put(ctx, "good", strings.NewReader(data),
+		absos.WithChecksum(absos.ChecksumSHA256, computed[absos.ChecksumSHA256]))
+	if err != nil {
+		t.Fatalf("Put with a matching checksum: %v", err)
+	}
+
+	header, err := b.Head(ctx, "good")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if len(header.ETag()) == 0 {
+		t.Error("expected an ETag")
+	}
+	for a, sum := range header.Checksums() {
+		if !bytes.Equal(sum, computed[a]) {
+			t.Errorf("expected %s checksum %x, got %x", a, computed[a], sum)
+		}
+	}
+
+	if got := get(t, b, "good"); got != data {
+		t.Errorf("expected %q, got %q", data, got)
+	}
+
+	// Mismatching checksums are rejected without storing the object
+	wrong := absos.ComputeChecksums([]byte("other contents"))
+	for _, a := range absos.ChecksumAlgorithms {
+		err := b.Put(ctx, "bad", strings.NewReader(data), absos.WithChecksum(a, wrong[a]))
+		if errors.Is(err, absos.ErrNotSupported) {
+			continue
+		}
+		if !errors.Is(err, absos.ErrChecksumMismatch) {
+			t.Errorf("Put with a wrong %s checksum: expected ErrChecksumMismatch, got %v", a, err)
+		}
+	}
+
+	err = absos.Upload(ctx, b, "bad", strings.NewReader(data),
+		absos.WithChecksum(absos.ChecksumCRC32C, wrong[absos.ChecksumCRC32C]))
+	if !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("Upload with a wrong checksum: expected ErrChecksumMismatch, got %v", err)
+	}
+
+	if _, err := b.Head(ctx, "bad"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound for a rejected object, got %v", err)
+	}
+}
+
+func testDelete(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	put(t, b, "dir/a", "a")
+	put(t, b, "dir/b", "b")
+
+	if err := b.Delete(ctx, "dir/a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := b.Get(ctx, "dir/a")
+	expectObjectError(t, "Get deleted", err, bucketName, "dir/a", absos.ErrObjectNotFound)
+
+	// Other objects are unaffected
+	if got := get(t, b, "dir/b"); got != "b" {
+		t.Errorf("expected %q, got %q", "b", got)
+	}
+
+	keys, _ := listAll(t, b, "", "")
+	if strings.Join(keys, ",") != "dir/b" {
+		t.Errorf("expected [dir/b] after delete, got %v", keys)
+	}
+}
+
+func testObjectNotFound(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	_, err := b.Get(ctx, "missing")
+	expectObjectError(t, "Get", err, bucketName, "missing", absos.ErrObjectNotFound)
+
+	_, err = b.Head(ctx, "missing")
+	expectObjectError(t, "Head", err, bucketName, "missing", absos.ErrObjectNotFound)
+
+	err = b.Delete(ctx, "missing")
+	expectObjectError(t, "Delete", err, bucketName, "missing", absos.ErrObjectNotFound)
+}
+
+func testPutBatch(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	iter := absos.NewBatchIterator(
+		absos.BatchObject{Key: "a", Data: strings.NewReader("a")},
+		absos.BatchObject{Key: "broken", Data: failingReader{}},
+		absos.BatchObject{Key: "b", Data: strings.NewReader("b")},
+	)
+
+	err := b.PutBatch(ctx, iter)
+
+	var batchErr *absos.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("PutBatch: expected *absos.BatchError, got %v", err)
+	}
+
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "broken" {
+		t.Errorf("expected a single failure for %q, got %v", "broken", batchErr.Errors)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		if got := get(t, b, key); got != key {
+			t.Errorf("expected %q, got %q", key, got)
+		}
+	}
+
+	if _, err := b.Head(ctx, "broken"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected failed object not to exist, got %v", err)
+	}
+}
+
+func testUpload(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	// A reader without Seek and of unknown length
+	data := strings.Repeat("stream ", 1000)
+	err := absos.Upload(ctx, b, "stream", io.MultiReader(strings.NewReader(data)), absos.WithContentType("text/plain"))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	if got := get(t, b, "stream"); got != data {
+		t.Errorf("expected %d bytes, got %d", len(data), len(got))
+	}
+
+	header, err := b.Head(ctx, "stream")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if header.MimeType() != "text/plain" {
+		t.Errorf("expected MIME type text/plain, got %q", header.MimeType())
+	}
+
+	// A failing stream leaves the previous object unchanged
+	err = absos.Upload(ctx, b, "stream", io.MultiReader(strings.NewReader("partial"), failingReader{}))
+	if err == nil {
+		t.Fatal("expected error for failing reader")
+	}
+
+	if got := get(t, b, "stream"); got != data {
+		t.Errorf("expected previous object to be kept, got %d bytes", len(got))
+	}
+
+	// A Writer commits on Close and aborts on CloseWithError
+	w := absos.NewWriter(ctx, b, "written")
+	if _, err := io.WriteString(w, "written"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := get(t, b, "written"); got != "written" {
+		t.Errorf("expected %q, got %q", "written", got)
+	}
+
+	w = absos.NewWriter(ctx, b, "aborted")
+	if _, err := io.WriteString(w, "aborted"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	_ = w.CloseWithError(nil)
+
+	_, err = b.Head(ctx, "aborted")
+	expectObjectError(t, "Head", err, bucketName, "aborted", absos.ErrObjectNotFound)
+}
+
+func testCopy(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	err := b.Put(ctx, "src", strings.NewReader("contents"),
+		absos.WithContentType("text/plain"),
+		absos.WithMetadata(map[string]string{"owner": "alice"}))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if err := absos.Copy(ctx, b, "src", b, "dir/dst"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	if got := get(t, b, "dir/dst"); got != "contents" {
+		t.Errorf("expected %q, got %q", "contents", got)
+	}
+
+	// The attributes of the source are copied
+	header, err := b.Head(ctx, "dir/dst")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if header.MimeType() != "text/plain" || header.Metadata()["owner"] != "alice" {
+		t.Errorf("expected copied attributes, got %q and %v", header.MimeType(), header.Metadata())
+	}
+
+	// The source is unchanged, and the copy is independent of it
+	put(t, b, "src", "changed")
+	if got := get(t, b, "dir/dst"); got != "contents" {
+		t.Errorf("expected copy to keep %q, got %q", "contents", got)
+	}
+
+	err = absos.Copy(ctx, b, "missing", b, "dst")
+	expectObjectError(t, "Copy", err, bucketName, "missing", absos.ErrObjectNotFound)
+}
+
+func testCopyReplaceMetadata(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	err := b.Put(ctx, "src", strings.NewReader("{}"),
+		absos.WithContentType("text/plain"),
+		absos.WithMetadata(map[string]string{"owner": "alice"}))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	err = absos.Copy(ctx, b, "src", b, "dst", absos.WithReplacedMetadata(
+		absos.WithContentType("application/json"),
+		absos.WithMetadata(map[string]string{"reviewer": "bob"})))
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	header, err := b.Head(ctx, "dst")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+
+	if header.MimeType() != "application/json" {
+		t.Errorf("expected MIME type application/json, got %q", header.MimeType())
+	}
+	if m := header.Metadata(); len(m) != 1 || m["reviewer"] != "bob" {
+		t.Errorf("expected replaced metadata, got %v", m)
+	}
+
+	// Replacing the attributes of an object in place keeps its contents
+	err = absos.Copy(ctx, b, "src", b, "src", absos.WithReplacedMetadata(absos.WithCacheControl("no-cache")))
+	if err != nil {
+		t.Fatalf("Copy onto itself: %v", err)
+	}
+
+	if got := get(t, b, "src"); got != "{}" {
+		t.Errorf("expected %q, got %q", "{}", got)
+	}
+}
+
+func testCopyAcrossBuckets(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	src := newBucket(t, store)
+
+	if err := store.CreateBucket(ctx, "conformance-target"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	dst := findBucket(t, store, "conformance-target")
+
+	put(t, src, "key", "data")
+
+	if err := absos.Copy(ctx, src, "key", dst, "copied"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	if got := get(t, dst, "copied"); got != "data" {
+		t.Errorf("expected %q, got %q", "data", got)
+	}
+
+	if got := get(t, src, "key"); got != "data" {
+		t.Errorf("expected source to be kept, got %q", got)
+	}
+}
+
+func testMove(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	put(t, b, "old", "data")
+
+	if err := absos.Move(ctx, b, "old", b, "new"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	if got := get(t, b, "new"); got != "data" {
+		t.Errorf("expected %q, got %q", "data", got)
+	}
+
+	_, err := b.Head(ctx, "old")
+	expectObjectError(t, "Head", err, bucketName, "old", absos.ErrObjectNotFound)
+
+	// Moving an object onto itself keeps it
+	if err := absos.Move(ctx, b, "new", b, "new"); err != nil {
+		t.Fatalf("Move onto itself: %v", err)
+	}
+
+	if got := get(t, b, "new"); got != "data" {
+		t.Errorf("expected %q, got %q", "data", got)
+	}
+
+	err = absos.Move(ctx, b, "missing", b, "other")
+	expectObjectError(t, "Move", err, bucketName, "missing", absos.ErrObjectNotFound)
+}
+
+func testDeleteMany(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		put(t, b, key, key)
+	}
+
+	// Missing keys are not failures
+	if err := absos.DeleteMany(ctx, b, []string{"a", "missing", "c"}); err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+
+	keys, _ := listAll(t, b, "", "")
+	if strings.Join(keys, ",") != "b,d" {
+		t.Errorf("expected [b d] after DeleteMany, got %v", keys)
+	}
+
+	bb, ok := b.(absos.BulkDeleteBucket)
+	if !ok {
+		return
+	}
+
+	if err := bb.DeleteMany(ctx, []string{"b", "missing"}); err != nil {
+		t.Fatalf("BulkDeleteBucket.DeleteMany: %v", err)
+	}
+
+	keys, _ = listAll(t, b, "", "")
+	if strings.Join(keys, ",") != "d" {
+		t.Errorf("expected [d] after BulkDeleteBucket.DeleteMany, got %v", keys)
+	}
+}
+
+func testDeletePrefix(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	for _, key := range []string{"dir/1", "dir/2", "dir/3", "dir/4", "dir/5", "dir/sub/6", "dira", "other"} {
+		put(t, b, key, key)
+	}
+
+	if err := absos.DeletePrefix(ctx, b, "dir/"); err != nil {
+		t.Fatalf("DeletePrefix: %v", err)
+	}
+
+	keys, _ := listAll(t, b, "", "")
+	if strings.Join(keys, ",") != "dira,other" {
+		t.Errorf("expected [dira other] after DeletePrefix, got %v", keys)
+	}
+}
+
+func testConditionalRead(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+	put(t, b, "key", "data")
+
+	header, err := b.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	etag, modTime := header.ETag(), header.ModTime()
+	if len(etag) == 0 {
+		t.Skip("bucket does not report ETags")
+	}
+
+	pass := []absos.Conditions{
+		{},
+		{IfMatch: etag},
+		{IfNoneMatch: []byte("other")},
+		{IfModifiedSince: modTime.Add(-time.Hour)},
+		{IfUnmodifiedSince: modTime},
+		// IfMatch takes precedence over IfUnmodifiedSince
+		{IfMatch: etag, IfUnmodifiedSince: modTime.Add(-time.Hour)},
+	}
+	for _, cond := range pass {
+		rc, err := absos.GetIf(ctx, b, "key", cond)
+		if err != nil {
+			t.Errorf("GetIf(%+v): %v", cond, err)
+			continue
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(data) != "data" {
+			t.Errorf("GetIf(%+v): expected %q, got %q (%v)", cond, "data", data, err)
+		}
+
+		if _, err := absos.HeadIf(ctx, b, "key", cond); err != nil {
+			t.Errorf("HeadIf(%+v): %v", cond, err)
+		}
+	}
+
+	fail := []struct {
+		cond   absos.Conditions
+		target error
+	}{
+		{absos.Conditions{IfMatch: []byte("other")}, absos.ErrPreconditionFailed},
+		{absos.Conditions{IfUnmodifiedSince: modTime.Add(-time.Hour)}, absos.ErrPreconditionFailed},
+		{absos.Conditions{IfNoneMatch: etag}, absos.ErrNotModified},
+		{absos.Conditions{IfNotExists: true}, absos.ErrNotModified},
+		{absos.Conditions{IfModifiedSince: modTime}, absos.ErrNotModified},
+	}
+	for _, tt := range fail {
+		rc, err := absos.GetIf(ctx, b, "key", tt.cond)
+		if err == nil {
+			rc.Close()
+		}
+		expectObjectError(t, "GetIf", err, bucketName, "key", tt.target)
+
+		_, err = absos.HeadIf(ctx, b, "key", tt.cond)
+		if !errors.Is(err, tt.target) {
+			t.Errorf("HeadIf(%+v): expected %v, got %v", tt.cond, tt.target, err)
+		}
+	}
+
+	_, err = absos.HeadIf(ctx, b, "missing", absos.Conditions{IfMatch: etag})
+	expectObjectError(t, "HeadIf", err, bucketName, "missing", absos.ErrObjectNotFound)
+}
+
+func testConditionalWrite(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+	if _, ok := b.(absos.ConditionalBucket); !ok {
+		t.Skip("bucket does not implement absos.ConditionalBucket")
+	}
+
+	// Create-only writes succeed once
+	create := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, b, "lock", strings.NewReader("first"), create); err != nil {
+		t.Fatalf("PutIf: %v", err)
+	}
+	err := absos.PutIf(ctx, b, "lock", strings.NewReader("second"), create)
+	expectObjectError(t, "PutIf", err, bucketName, "lock", absos.ErrPreconditionFailed)
+	if got := get(t, b, "lock"); got != "first" {
+		t.Errorf("expected %q, got %q", "first", got)
+	}
+
+	header, err := b.Head(ctx, "lock")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	etag := header.ETag()
+
+	// Writes conditional on the current ETag replace the object
+	err = absos.PutIf(ctx, b, "lock", strings.NewReader("third"), absos.Conditions{IfMatch: etag},
+		absos.WithContentType("text/plain"))
+	if err != nil {
+		t.Fatalf("PutIf: %v", err)
+	}
+	if header, err := b.Head(ctx, "lock"); err != nil || header.MimeType() != "text/plain" {
+		t.Errorf("expected options to be applied, got %v", err)
+	}
+
+	// A stale ETag is rejected
+	err = absos.PutIf(ctx, b, "lock", strings.NewReader("fourth"), absos.Conditions{IfMatch: etag})
+	expectObjectError(t, "PutIf", err, bucketName, "lock", absos.ErrPreconditionFailed)
+	if got := get(t, b, "lock"); got != "third" {
+		t.Errorf("expected %q, got %q", "third", got)
+	}
+
+	err = absos.PutIf(ctx, b, "missing", strings.NewReader("data"), absos.Conditions{IfMatch: etag})
+	expectObjectError(t, "PutIf", err, bucketName, "missing", absos.ErrPreconditionFailed)
+
+	err = absos.DeleteIf(ctx, b, "lock", absos.Conditions{IfMatch: etag})
+	expectObjectError(t, "DeleteIf", err, bucketName, "lock", absos.ErrPreconditionFailed)
+
+	header, err = b.Head(ctx, "lock")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if err := absos.DeleteIf(ctx, b, "lock", absos.Conditions{IfMatch: header.ETag()}); err != nil {
+		t.Fatalf("DeleteIf: %v", err)
+	}
+	if _, err := b.Head(ctx, "lock"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected object to be deleted, got %v", err)
+	}
+
+	err = absos.DeleteIf(ctx, b, "lock", absos.Conditions{})
+	expectObjectError(t, "DeleteIf", err, bucketName, "lock", absos.ErrObjectNotFound)
+}
+
+// versionedBucket returns a new bucket with versioning enabled, skipping
+// the test if the store does not support versioning.
+func versionedBucket(t *testing.T, store absos.ObjectStore) absos.VersionedBucket {
+	t.Helper()
+
+	vb, ok := newBucket(t, store).(absos.VersionedBucket)
+	if !ok {
+		t.Skip("bucket does not implement absos.VersionedBucket")
+	}
+
+	if status, err := vb.Versioning(context.Background()); err != nil || status != absos.VersioningDisabled {
+		t.Fatalf("Versioning: expected disabled, got %q (%v)", status, err)
+	}
+
+	return vb
+}
+
+// listVersions returns every version with the given prefix.
+func listVersions(t *testing.T, b absos.VersionedBucket, prefix string) []absos.ObjectVersion {
+	t.Helper()
+
+	var versions []absos.ObjectVersion
+	token := ""
+	for {
+		page, err := b.ListVersions(context.Background(), prefix, token)
+		if err != nil {
+			t.Fatalf("ListVersions: %v", err)
+		}
+		versions = append(versions, page.Versions()...)
+		if page.Last() {
+			return versions
+		}
+		token = page.NextPage()
+	}
+}
+
+// getVersion reads the contents of a version.
+func getVersion(t *testing.T, b absos.VersionedBucket, key, id string) string {
+	t.Helper()
+
+	rc, err := b.GetVersion(context.Background(), key, id)
+	if err != nil {
+		t.Fatalf("GetVersion(%q, %q): %v", key, id, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("GetVersion(%q, %q): %v", key, id, err)
+	}
+	return string(data)
+}
+
+func testVersioning(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := versionedBucket(t, store)
+
+	put(t, b, "key", "v0")
+	put(t, b, "other", "data")
+
+	if err := b.SetVersioning(ctx, absos.VersioningEnabled); err != nil {
+		t.Fatalf("SetVersioning: %v", err)
+	}
+	if status, err := b.Versioning(ctx); err != nil || status != absos.VersioningEnabled {
+		t.Fatalf("Versioning: expected enabled, got %q (%v)", status, err)
+	}
+
+	put(t, b, "key", "v1")
+	put(t, b, "key", "v2")
+
+	// Versions are listed from the newest, and existing objects became
+	// the null version
+	versions := listVersions(t, b, "key")
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %+v", versions)
+	}
+	if !versions[0].IsLatest || versions[1].IsLatest || versions[2].VersionID != absos.NullVersion {
+		t.Errorf("unexpected versions %+v", versions)
+	}
+	v2, v1 := versions[0].VersionID, versions[1].VersionID
+
+	if got := getVersion(t, b, "key", absos.NullVersion); got != "v0" {
+		t.Errorf("expected %q, got %q", "v0", got)
+	}
+	if got := getVersion(t, b, "key", v1); got != "v1" {
+		t.Errorf("expected %q, got %q", "v1", got)
+	}
+
+	header, err := b.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if header.Version() != v2 {
+		t.Errorf("expected version %q, got %q", v2, header.Version())
+	}
+
+	// Deleting adds a delete marker that hides the object
+	if err := b.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = b.Head(ctx, "key")
+	expectObjectError(t, "Head", err, bucketName, "key", absos.ErrObjectNotFound)
+
+	if keys, _ := listAll(t, b, "", ""); len(keys) != 1 || keys[0] != "other" {
+		t.Errorf("expected only %q to be listed, got %v", "other", keys)
+	}
+
+	versions = listVersions(t, b, "key")
+	if len(versions) != 4 || !versions[0].DeleteMarker || !versions[0].IsLatest {
+		t.Fatalf("expected a delete marker as the latest version, got %+v", versions)
+	}
+	marker := versions[0].VersionID
+
+	_, err = b.GetVersion(ctx, "key", marker)
+	expectObjectError(t, "GetVersion", err, bucketName, "key", absos.ErrObjectNotFound)
+
+	// Deleting a key without a current version adds no delete marker
+	err = b.Delete(ctx, "key")
+	expectObjectError(t, "Delete", err, bucketName, "key", absos.ErrObjectNotFound)
+	err = b.Delete(ctx, "missing")
+	expectObjectError(t, "Delete", err, bucketName, "missing", absos.ErrObjectNotFound)
+	if cb, ok := b.(absos.ConditionalBucket); ok {
+		err = cb.DeleteIf(ctx, "key", absos.Conditions{})
+		expectObjectError(t, "DeleteIf", err, bucketName, "key", absos.ErrObjectNotFound)
+		err = cb.DeleteIf(ctx, "missing", absos.Conditions{})
+		expectObjectError(t, "DeleteIf", err, bucketName, "missing", absos.ErrObjectNotFound)
+	}
+	if versions := listVersions(t, b, "key"); len(versions) != 4 || versions[0].VersionID != marker {
+		t.Errorf("expected no further delete marker, got %+v", versions)
+	}
+	if versions := listVersions(t, b, "missing"); len(versions) != 0 {
+		t.Errorf("expected no delete marker for a missing key, got %+v", versions)
+	}
+
+	// Removing the delete marker restores the object, and removing the
+	// latest version rolls it back
+	if err := b.DeleteVersion(ctx, "key", marker); err != nil {
+		t.Fatalf("DeleteVersion: %v", err)
+	}
+	if got := get(t, b, "key"); got != "v2" {
+		t.Errorf("expected %q, got %q", "v2", got)
+	}
+
+	if err := b.DeleteVersion(ctx, "key", v2); err != nil {
+		t.Fatalf("DeleteVersion: %v", err)
+	}
+	if got := get(t, b, "key"); got != "v1" {
+		t.Errorf("expected %q, got %q", "v1", got)
+	}
+
+	_, err = b.HeadVersion(ctx, "key", v2)
+	expectObjectError(t, "HeadVersion", err, bucketName, "key", absos.ErrVersionNotFound)
+
+	err = b.DeleteVersion(ctx, "missing", v1)
+	expectObjectError(t, "DeleteVersion", err, bucketName, "missing", absos.ErrVersionNotFound)
+}
+
+func testVersioningSuspended(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := versionedBucket(t, store)
+
+	if err := b.SetVersioning(ctx, absos.VersioningEnabled); err != nil {
+		t.Fatalf("SetVersioning: %v", err)
+	}
+	put(t, b, "key", "kept")
+
+	if err := b.SetVersioning(ctx, absos.VersioningSuspended); err != nil {
+		t.Fatalf("SetVersioning: %v", err)
+	}
+
+	// Writes replace the null version and keep the others
+	put(t, b, "key", "first")
+	put(t, b, "key", "second")
+
+	versions := listVersions(t, b, "")
+	if len(versions) != 2 || versions[0].VersionID != absos.NullVersion {
+		t.Fatalf("expected the null version and one other, got %+v", versions)
+	}
+	if got := get(t, b, "key"); got != "second" {
+		t.Errorf("expected %q, got %q", "second", got)
+	}
+
+	// Deleting replaces the null version with a delete marker
+	if err := b.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	versions = listVersions(t, b, "")
+	if len(versions) != 2 || !versions[0].DeleteMarker || versions[0].VersionID != absos.NullVersion {
+		t.Fatalf("expected a null delete marker, got %+v", versions)
+	}
+	if got := getVersion(t, b, "key", versions[1].VersionID); got != "kept" {
+		t.Errorf("expected %q, got %q", "kept", got)
+	}
+
+	// Versioning cannot be disabled again
+	if err := b.SetVersioning(ctx, absos.VersioningDisabled); err == nil {
+		t.Error("expected an error disabling versioning")
+	}
+}
+
+// multipartBucket returns a new bucket as an absos.MultipartBucket, skipping
+// the test if the store does not support multipart uploads.
+func multipartBucket(t *testing.T, store absos.ObjectStore) absos.MultipartBucket {
+	t.Helper()
+
+	mb, ok := newBucket(t, store).(absos.MultipartBucket)
+	if !ok {
+		t.Skip("bucket does not implement absos.MultipartBucket")
+	}
+	return mb
+}
+
+func testMultipart(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := multipartBucket(t, store)
+
+	upload, err := b.InitiateMultipart(ctx, "big", absos.WithContentType("text/plain"))
+	if err != nil {
+		t.Fatalf("InitiateMultipart: %v", err)
+	}
+
+	if upload.Bucket != bucketName || upload.Key != "big" || upload.ID == "" {
+		t.Errorf("unexpected upload %+v", upload)
+	}
+
+	// Every part but the last must be at least MinPartSize bytes
+	first := bytes.Repeat([]byte("a"), absos.MinPartSize)
+	second := []byte("tail")
+
+	// Parts may be uploaded in any order
+	p2, err := b.UploadPart(ctx, upload, 2, bytes.NewReader(second))
+	if err != nil {
+		t.Fatalf("UploadPart(2): %v", err)
+	}
+	if p2.Number != 2 || p2.Size != int64(len(second)) || len(p2.ETag) == 0 {
+		t.Errorf("unexpected part %+v", p2)
+	}
+
+	// The upload survives a round trip through JSON, as when a job resumes
+	data, err := json.Marshal(upload)
+	if err != nil {
+		t.Fatalf("marshaling upload: %v", err)
+	}
+	var resumed absos.MultipartUpload
+	if err := json.Unmarshal(data, &resumed); err != nil {
+		t.Fatalf("unmarshaling upload: %v", err)
+	}
+
+	uploads, err := b.ListMultipartUploads(ctx, "")
+	if err != nil {
+		t.Fatalf("ListMultipartUploads: %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].ID != upload.ID || uploads[0].Key != "big" {
+		t.Errorf("expected the upload to be listed, got %+v", uploads)
+	}
+
+	if uploads, _ := b.ListMultipartUploads(ctx, "other"); len(uploads) != 0 {
+		t.Errorf("expected no uploads with prefix %q, got %+v", "other", uploads)
+	}
+
+	// The object is not visible before the upload completes
+	_, err = b.Head(ctx, "big")
+	expectObjectError(t, "Head", err, bucketName, "big", absos.ErrObjectNotFound)
+
+	p1, err := b.UploadPart(ctx, resumed, 1, bytes.NewReader(first))
+	if err != nil {
+		t.Fatalf("UploadPart(1): %v", err)
+	}
+
+	parts, err := b.ListParts(ctx, resumed)
+	if err != nil {
+		t.Fatalf("ListParts: %v", err)
+	}
+	if len(parts) != 2 || parts[0].Number != 1 || parts[1].Number != 2 {
+		t.Fatalf("expected parts 1 and 2, got %+v", parts)
+	}
+	if parts[0].Size != p1.Size || !bytes.Equal(parts[0].ETag, p1.ETag) {
+		t.Errorf("expected listed part %+v to match %+v", parts[0], p1)
+	}
+
+	// Parts with a wrong ETag or out of order are rejected
+	bad := []absos.Part{{Number: 1, ETag: p2.ETag}, p2}
+	err = b.CompleteMultipart(ctx, resumed, bad)
+	expectObjectError(t, "CompleteMultipart", err, bucketName, "big", absos.ErrInvalidPart)
+
+	err = b.CompleteMultipart(ctx, resumed, []absos.Part{p2, p1})
+	expectObjectError(t, "CompleteMultipart", err, bucketName, "big", absos.ErrInvalidPart)
+
+	if err := b.CompleteMultipart(ctx, resumed, parts); err != nil {
+		t.Fatalf("CompleteMultipart: %v", err)
+	}
+
+	if got := get(t, b, "big"); got != string(first)+string(second) {
+		t.Errorf("expected %d bytes, got %d", len(first)+len(second), len(got))
+	}
+
+	header, err := b.Head(ctx, "big")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if header.MimeType() != "text/plain" {
+		t.Errorf("expected MIME type text/plain, got %q", header.MimeType())
+	}
+
+	// A completed upload no longer exists
+	if uploads, _ := b.ListMultipartUploads(ctx, ""); len(uploads) != 0 {
+		t.Errorf("expected no uploads, got %+v", uploads)
+	}
+
+	_, err = b.ListParts(ctx, resumed)
+	expectObjectError(t, "ListParts", err, bucketName, "big", absos.ErrUploadNotFound)
+}
+
+func testMultipartAbort(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := multipartBucket(t, store)
+
+	put(t, b, "key", "original")
+
+	upload, err := b.InitiateMultipart(ctx, "key")
+	if err != nil {
+		t.Fatalf("InitiateMultipart: %v", err)
+	}
+
+	if _, err := b.UploadPart(ctx, upload, 1, strings.NewReader("replacement")); err != nil {
+		t.Fatalf("UploadPart: %v", err)
+	}
+
+	if err := b.AbortMultipart(ctx, upload); err != nil {
+		t.Fatalf("AbortMultipart: %v", err)
+	}
+
+	// The object is left unchanged and the upload is gone
+	if got := get(t, b, "key"); got != "original" {
+		t.Errorf("expected %q, got %q", "original", got)
+	}
+
+	_, err = b.UploadPart(ctx, upload, 2, strings.NewReader("more"))
+	expectObjectError(t, "UploadPart", err, bucketName, "key", absos.ErrUploadNotFound)
+
+	err = b.AbortMultipart(ctx, upload)
+	expectObjectError(t, "AbortMultipart", err, bucketName, "key", absos.ErrUploadNotFound)
+
+	unknown := absos.MultipartUpload{Bucket: bucketName, Key: "key", ID: "unknown"}
+	_, err = b.ListParts(ctx, unknown)
+	expectObjectError(t, "ListParts", err, bucketName, "key", absos.ErrUploadNotFound)
+}
+
+func testListObjects(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	keys := []string{"c", "a", "b/1", "b/2", "b-1", "d/e/f"}
+	for _, key := range keys {
+		put(t, b, key, key)
+	}
+
+	got, prefixes := listAll(t, b, "", "")
+	expected := []string{"a", "b-1", "b/1", "b/2", "c", "d/e/f"}
+
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected keys %v in lexicographic order, got %v", expected, got)
+	}
+
+	if len(prefixes) != 0 {
+		t.Errorf("expected no prefixes without delimiter, got %v", prefixes)
+	}
+}
+
+func testListEmpty(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	page, err := b.ObjectPage(context.Background(), "", "/", "")
+	if err != nil {
+		t.Fatalf("ObjectPage: %v", err)
+	}
+
+	if len(page.Objects()) != 0 || len(page.Prefixes()) != 0 {
+		t.Errorf("expected empty page, got %d objects and %d prefixes", len(page.Objects()), len(page.Prefixes()))
+	}
+
+	if !page.Last() || page.NextPage() != "" {
+		t.Errorf("expected single last page, got last=%v next=%q", page.Last(), page.NextPage())
+	}
+}
+
+func testListPrefix(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	for _, key := range []string{"a/1", "a/2", "a/b/3", "ab", "b/1"} {
+		put(t, b, key, key)
+	}
+
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"a/", "a/1,a/2,a/b/3"},
+		{"a", "a/1,a/2,a/b/3,ab"},
+		{"a/b", "a/b/3"},
+		{"b/1", "b/1"},
+		{"missing/", ""},
+	}
+
+	for _, tt := range tests {
+		got, _ := listAll(t, b, tt.prefix, "")
+		if strings.Join(got, ",") != tt.expected {
+			t.Errorf("prefix %q: expected [%s], got %v", tt.prefix, tt.expected, got)
+		}
+	}
+}
+
+func testListDelimiter(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	for _, key := range []string{"a", "b/1", "b/2", "b/c/3", "b-d", "e/f/g", "h-i-j"} {
+		put(t, b, key, key)
+	}
+
+	tests := []struct {
+		prefix, delimiter string
+		keys, prefixes    string
+	}{
+		{"", "/", "a,b-d,h-i-j", "b/,e/"},
+		{"b/", "/", "b/1,b/2", "b/c/"},
+		{"b", "/", "b-d", "b/"},
+		{"e/", "/", "", "e/f/"},
+		{"", "-", "a,b/1,b/2,b/c/3,e/f/g", "b-,h-"},
+		{"h-", "-", "", "h-i-"},
+	}
+
+	for _, tt := range tests {
+		keys, prefixes := listAll(t, b, tt.prefix, tt.delimiter)
+		if strings.Join(keys, ",") != tt.keys {
+			t.Errorf("prefix %q delimiter %q: expected keys [%s], got %v", tt.prefix, tt.delimiter, tt.keys, keys)
+		}
+
+		sort.Strings(prefixes)
+		if strings.Join(prefixes, ",") != tt.prefixes {
+			t.Errorf("prefix %q delimiter %q: expected prefixes [%s], got %v", tt.prefix, tt.delimiter, tt.prefixes, prefixes)
+		}
+	}
+}
+
+func testListPagination(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	var expected []string
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("dir%d/obj%02d", i%3, i)
+		expected = append(expected, key)
+		put(t, b, key, key)
+	}
+	sort.Strings(expected)
+
+	keys, _ := listAll(t, b, "", "")
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected all %d keys in order, got %v", len(expected), keys)
+	}
+
+	// Prefixes collapse across page boundaries without repeating
+	_, prefixes := listAll(t, b, "", "/")
+	if strings.Join(prefixes, ",") != "dir0/,dir1/,dir2/" {
+		t.Errorf("expected prefixes [dir0/ dir1/ dir2/], got %v", prefixes)
+	}
+
+	// A token stays usable after the listed objects change
+	ctx := context.Background()
+	first, err := b.ObjectPage(ctx, "", "", "")
+	if err != nil {
+		t.Fatalf("ObjectPage: %v", err)
+	}
+
+	if !first.Last() {
+		put(t, b, "zzz", "new")
+		if _, err := b.ObjectPage(ctx, "", "", first.NextPage()); err != nil {
+			t.Errorf("ObjectPage with token after Put: %v", err)
+		}
+	}
+}
+
+func testListSpecialKeys(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+
+	// Keys differing only by a suffix must not collide in providers that
+	// store attributes next to objects, in either order
+	expected := []string{"a b", "a+b", "a%20b", "dir/ünïcödé", "x=1&y=2", "it's (here)",
+		"j.json/k", "j", "m", "m.json/n"}
+	for _, key := range expected {
+		put(t, b, key, key)
+		if got := get(t, b, key); got != key {
+			t.Errorf("key %q: expected %q, got %q", key, key, got)
+		}
+	}
+	sort.Strings(expected)
+
+	keys, _ := listAll(t, b, "", "")
+	if strings.Join(keys, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected keys %q, got %q", expected, keys)
+	}
+}
+
+func testPageObjects(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+	put(t, b, "dir/object", "object data")
+
+	page, err := b.ObjectPage(ctx, "dir/", "", "")
+	if err != nil {
+		t.Fatalf("ObjectPage: %v", err)
+	}
+
+	if len(page.Objects()) != 1 {
+		t.Fatalf("expected 1 object, got %d", len(page.Objects()))
+	}
+
+	obj := page.Objects()[0]
+	if obj.Bucket() != bucketName || obj.Key() != "dir/object" || obj.Size() != int64(len("object data")) {
+		t.Errorf("unexpected object %q/%q of size %d", obj.Bucket(), obj.Key(), obj.Size())
+	}
+
+	if obj.ModTime().IsZero() {
+		t.Error("expected modification time")
+	}
+
+	if obj.StorageClass() == "" {
+		t.Error("expected a storage class")
+	}
+	_, _ = obj.AccessTime(), obj.ETag()
+
+	header, err := obj.Head(ctx)
+	if err != nil {
+		t.Fatalf("Object.Head: %v", err)
+	}
+	if header.Key() != obj.Key() || header.Size() != obj.Size() {
+		t.Errorf("Object.Head returned %q of size %d", header.Key(), header.Size())
+	}
+	if etag := obj.ETag(); len(etag) > 0 && !bytes.Equal(etag, header.ETag()) {
+		t.Errorf("listing ETag %x differs from Head ETag %x", etag, header.ETag())
+	}
+
+	r, err := obj.Open(ctx)
+	if err != nil {
+		t.Fatalf("Object.Open: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "object data" {
+		t.Errorf("Object.Open: expected %q, got %q (%v)", "object data", data, err)
+	}
+}
+
+func testGetRange(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+	put(t, b, "digits", "0123456789")
+	put(t, b, "empty", "")
+
+	page, err := b.ObjectPage(ctx, "digits", "", "")
+	if err != nil || len(page.Objects()) != 1 {
+		t.Fatalf("ObjectPage: expected 1 object, got %v (%v)", page, err)
+	}
+	obj := page.Objects()[0]
+
+	readers := map[string]func(key string, offset, length int64) (io.ReadCloser, error){
+		"GetRange": func(key string, offset, length int64) (io.ReadCloser, error) {
+			return absos.GetRange(ctx, b, key, offset, length)
+		},
+	}
+	if rb, ok := b.(absos.RangeBucket); ok {
+		readers["RangeBucket"] = func(key string, offset, length int64) (io.ReadCloser, error) {
+			return rb.GetRange(ctx, key, offset, length)
+		}
+	}
+
+	tests := []struct {
+		offset, length int64
+		expected       string
+	}{
+		{0, 4, "0123"},
+		{3, -1, "3456789"},
+		{8, 10, "89"},
+		{9, 1, "9"},
+		{-3, 0, "789"},
+		{-20, 0, "0123456789"},
+	}
+
+	invalid := []struct {
+		key            string
+		offset, length int64
+	}{
+		{"digits", 10, 1},
+		{"digits", 15, -1},
+		{"digits", 0, 0},
+		{"empty", 0, -1},
+		{"empty", -1, 0},
+	}
+
+	for name, read := range readers {
+		for _, tt := range tests {
+			r, err := read("digits", tt.offset, tt.length)
+			if err != nil {
+				t.Errorf("%s(%d, %d): %v", name, tt.offset, tt.length, err)
+				continue
+			}
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || string(data) != tt.expected {
+				t.Errorf("%s(%d, %d): expected %q, got %q (%v)", name, tt.offset, tt.length, tt.expected, data, err)
+			}
+		}
+
+		for _, tt := range invalid {
+			r, err := read(tt.key, tt.offset, tt.length)
+			if err == nil {
+				r.Close()
+			}
+			op := fmt.Sprintf("%s(%q, %d, %d)", name, tt.key, tt.offset, tt.length)
+			expectObjectError(t, op, err, bucketName, tt.key, absos.ErrInvalidRange)
+		}
+
+		_, err := read("missing", 0, 1)
+		expectObjectError(t, name+" missing", err, bucketName, "missing", absos.ErrObjectNotFound)
+	}
+
+	r, err := absos.OpenRange(ctx, obj, 2, 3)
+	if err != nil {
+		t.Fatalf("OpenRange: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "234" {
+		t.Errorf("OpenRange: expected %q, got %q (%v)", "234", data, err)
+	}
+}
+
+func testConcurrent(t *testing.T, store absos.ObjectStore) {
+	ctx := context.Background()
+	b := newBucket(t, store)
+
+	const workers = 8
+	const rounds = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*4)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			own := fmt.Sprintf("worker-%d", w)
+			for i := 0; i < rounds; i++ {
+				data := fmt.Sprintf("%s-%d", own, i)
+
+				// Private key: every operation must succeed
+				if err := b.Put(ctx, own, strings.NewReader(data)); err != nil {
+					errs <- fmt.Errorf("Put(%q): %w", own, err)
+					continue
+				}
+				r, err := b.Get(ctx, own)
+				if err != nil {
+					errs <- fmt.Errorf("Get(%q): %w", own, err)
+					continue
+				}
+				got, err := io.ReadAll(r)
+				r.Close()
+				if err != nil || string(got) != data {
+					errs <- fmt.Errorf("Get(%q) = %q, expected %q (%v)", own, got, data, err)
+				}
+
+				// Shared key: objects may vanish but never be torn
+				if err := b.Put(ctx, "shared", strings.NewReader(data)); err != nil {
+					errs <- fmt.Errorf("Put(shared): %w", err)
+				}
+				if r, err := b.Get(ctx, "shared"); err == nil {
+					got, err := io.ReadAll(r)
+					r.Close()
+					if err == nil && !strings.HasPrefix(string(got), "worker-") {
+						errs <- fmt.Errorf("Get(shared) returned torn data %q", got)
+					}
+				} else if !errors.Is(err, absos.ErrObjectNotFound) {
+					errs <- fmt.Errorf("Get(shared): %w", err)
+				}
+				if err := b.Delete(ctx, "shared"); err != nil && !errors.Is(err, absos.ErrObjectNotFound) {
+					errs <- fmt.Errorf("Delete(shared): %w", err)
+				}
+			}
+
+			if err := b.Delete(ctx, own); err != nil {
+				errs <- fmt.Errorf("Delete(%q): %w", own, err)
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	keys, _ := listAll(t, b, "", "")
+	if len(keys) != 0 {
+		t.Errorf("expected empty bucket, got %v", keys)
+	}
+}
+
+func testContextCanceled(t *testing.T, store absos.ObjectStore) {
+	b := newBucket(t, store)
+	put(t, b, "key", "data")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ops := map[string]func() error{
+		"CreateBucket": func() error { return store.CreateBucket(ctx, "other-bucket") },
+		"DeleteBucket": func() error { return store.DeleteBucket(ctx, bucketName) },
+		"ListBuckets": func() error {
+			_, err := store.ListBuckets(ctx)
+			return err
+		},
+		"ObjectPage": func() error {
+			_, err := b.ObjectPage(ctx, "", "", "")
+			return err
+		},
+		"Head": func() error {
+			_, err := b.Head(ctx, "key")
+			return err
+		},
+		"Put": func() error { return b.Put(ctx, "key", strings.NewReader("new data")) },
+		"Get": func() error {
+			r, err := b.Get(ctx, "key")
+			if err == nil {
+				r.Close()
+			}
+			return err
+		},
+		"Delete": func() error { return b.Delete(ctx, "key") },
+		"PutBatch": func() error {
+			return b.PutBatch(ctx, absos.NewBatchIterator(absos.BatchObject{Key: "batch", Data: strings.NewReader("data")}))
+		},
+		"DeleteMany": func() error { return absos.DeleteMany(ctx, b, []string{"key"}) },
+	}
+
+	for name, op := range ops {
+		if err := op(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+	}
+
+	// Nothing was changed by the canceled operations
+	if got := get(t, b, "key"); got != "data" {
+		t.Errorf("expected %q, got %q", "data", got)
+	}
+}
+
+var errRead = errors.New("absostest: read failed")
+
+// failingReader fails every read.
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error)                   { return 0, errRead }
+func (failingReader) Seek(offset int64, whence int) (int64, error) { return 0, nil }
@@ -0,0 +1,147 @@
+package absos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BatchObject describes a single object to be uploaded by PutBatch.
+type BatchObject struct {
+	// Key is the key the object is stored under.
+	Key string
+
+	// Data provides the contents of the object.
+	Data io.ReadSeeker
+
+	// Options are passed to Put when the object is uploaded.
+	Options []PutOption
+}
+
+// BatchIterator iterates over the objects uploaded by PutBatch.
+// Next advances the iterator and reports whether an object is available,
+// Object returns the current object, and Err returns the error, if any,
+// that stopped the iteration early.
+type BatchIterator interface {
+	// Next advances to the next object and returns false when the iteration is done.
+	Next() bool
+
+	// Err returns the error that ended the iteration, or nil if it completed normally.
+	Err() error
+
+	// Object returns the current object.
+	Object() BatchObject
+}
+
+// NewBatchIterator returns a BatchIterator over the given objects.
+func NewBatchIterator(objects ...BatchObject) BatchIterator {
+	return &sliceIterator{objects: objects, pos: -1}
+}
+
+type sliceIterator struct {
+	objects []BatchObject
+	pos     int
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.pos+1 >= len(it.objects) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Err() error          { return nil }
+func (it *sliceIterator) Object() BatchObject { return it.objects[it.pos] }
+
+// BatchError is returned when one or more objects in a batch operation fail.
+// Each failure is reported as an *ObjectError carrying the key of the object.
+type BatchError struct {
+	Bucket string
+	Errors []*ObjectError
+
+	// Err is the error that stopped the batch before all objects were
+	// processed, such as the cancellation of the context, or nil if the
+	// batch ran to completion.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	var msg string
+	switch len(e.Errors) {
+	case 0:
+		msg = fmt.Sprintf("bucket %q: batch failed", e.Bucket)
+	case 1:
+		msg = fmt.Sprintf("bucket %q: batch failed for 1 object: %v", e.Bucket, e.Errors[0])
+	default:
+		msg = fmt.Sprintf("bucket %q: batch failed for %d objects: %v", e.Bucket, len(e.Errors), e.Errors[0])
+	}
+
+	if e.Err != nil {
+		msg += fmt.Sprintf(" (stopped: %v)", e.Err)
+	}
+	return msg
+}
+
+// Unwrap returns the errors of the failed objects and the error that stopped
+// the batch, so that errors.Is and errors.As match any of them.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors)+1)
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// PutEach uploads every object produced by iter with b.Put. It is intended
+// for providers without a native batch API to implement Bucket.PutBatch.
+//
+// A failing object does not stop the batch; all failures are collected into
+// a *BatchError. An error from the iterator or the context ends the batch
+// early and is returned wrapped in a *BucketError, or, if objects already
+// failed, as the Err of the *BatchError reporting them.
+func PutEach(ctx context.Context, b Bucket, iter BatchIterator) error {
+	var failed []*ObjectError
+	stop := func(err error) error {
+		err = &BucketError{Bucket: b.Name(), Err: err}
+		if len(failed) > 0 {
+			return &BatchError{Bucket: b.Name(), Errors: failed, Err: err}
+		}
+		return err
+	}
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return stop(err)
+		}
+
+		obj := iter.Object()
+		if err := b.Put(ctx, obj.Key, obj.Data, obj.Options...); err != nil {
+			failed = append(failed, objectError(b.Name(), obj.Key, err))
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return stop(err)
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{Bucket: b.Name(), Errors: failed}
+	}
+
+	return nil
+}
+
+// objectError returns err as an *ObjectError, wrapping it if necessary.
+func objectError(bucket, key string, err error) *ObjectError {
+	var oerr *ObjectError
+	if errors.As(err, &oerr) {
+		return oerr
+	}
+	return &ObjectError{Bucket: bucket, Key: key, Err: err}
+}
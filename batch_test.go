@@ -0,0 +1,101 @@
+package absos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewBatchIterator(t *testing.T) {
+	iter := NewBatchIterator(
+		BatchObject{Key: "a", Data: strings.NewReader("a")},
+		BatchObject{Key: "b", Data: strings.NewReader("b")},
+	)
+
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, iter.Object().Key)
+	}
+
+	if iter.Err() != nil {
+		t.Errorf("expected no error, got %v", iter.Err())
+	}
+
+	if strings.Join(keys, ",") != "a,b" {
+		t.Errorf("expected keys a,b, got %v", keys)
+	}
+
+	if iter.Next() {
+		t.Error("expected exhausted iterator to stay exhausted")
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	err := &BatchError{
+		Bucket: "test-bucket",
+		Errors: []*ObjectError{
+			{Bucket: "test-bucket", Key: "a", Err: ErrInvalidKey},
+			{Bucket: "test-bucket", Key: "b", Err: ErrPermissionDenied},
+		},
+	}
+
+	expected := `bucket "test-bucket": batch failed for 2 objects: object "a" in bucket "test-bucket": invalid object key`
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	// errors.Is and errors.As reach every failed object
+	if !errors.Is(err, ErrInvalidKey) || !errors.Is(err, ErrPermissionDenied) {
+		t.Error("errors.Is should match the errors of all failed objects")
+	}
+
+	var objErr *ObjectError
+	if !errors.As(err, &objErr) || objErr.Key != "a" {
+		t.Errorf("expected errors.As to find the first object error, got %v", objErr)
+	}
+}
+
+// cancelingBucket fails Put for the key "bad" and cancels the context of the
+// batch when the key "last" is put.
+type cancelingBucket struct {
+	Bucket
+	cancel context.CancelFunc
+}
+
+func (b *cancelingBucket) Name() string { return "test-bucket" }
+
+func (b *cancelingBucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...PutOption) error {
+	switch key {
+	case "bad":
+		return ErrPermissionDenied
+	case "last":
+		b.cancel()
+	}
+	return nil
+}
+
+func TestPutEachCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	iter := NewBatchIterator(
+		BatchObject{Key: "bad", Data: strings.NewReader("a")},
+		BatchObject{Key: "last", Data: strings.NewReader("b")},
+		BatchObject{Key: "skipped", Data: strings.NewReader("c")},
+	)
+
+	err := PutEach(ctx, &cancelingBucket{cancel: cancel}, iter)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected *BatchError, got %v", err)
+	}
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "bad" {
+		t.Errorf("expected the failure of bad to be kept, got %v", batchErr.Errors)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected both the failure and the cancellation, got %v", err)
+	}
+}
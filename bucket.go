@@ -4,8 +4,6 @@ import (
 	"context"
 	"io"
 	"time"
-
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 // Owner represents the owner of a bucket or object.
@@ -53,11 +51,14 @@ type Bucket interface {
 	// Head retrieves metadata for the object with the specified key without downloading the object.
 	Head(ctx context.Context, key string) (ObjectHeader, error)
 
-	// PutBatch uploads multiple objects in batch using the provided iterator.
-	PutBatch(ctx context.Context, iter s3manager.BatchUploadIterator) error
+	// PutBatch uploads every object produced by the iterator.
+	// Failures of individual objects are reported together in a *BatchError.
+	PutBatch(ctx context.Context, iter BatchIterator) error
 
 	// Put uploads an object with the specified key from the provided reader.
-	Put(ctx context.Context, key string, data io.ReadSeeker) error
+	// The options set the content headers, metadata, storage class and
+	// encryption stored with the object.
+	Put(ctx context.Context, key string, data io.ReadSeeker, opts ...PutOption) error
 
 	// Get retrieves the object with the specified key and returns a reader for its contents.
 	Get(ctx context.Context, key string) (io.ReadCloser, error)
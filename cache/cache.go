@@ -0,0 +1,428 @@
+// Package cache provides a read-through cache for buckets whose objects are
+// read repeatedly, such as immutable assets or configuration.
+//
+// A Bucket wraps any absos.Bucket and keeps the results of Head and the
+// bodies returned by Get in a memory LRU bounded in bytes, optionally backed
+// by a larger tier on disk:
+//
+//	cached, err := cache.New(bucket,
+//		cache.WithMemoryLimit(256<<20),
+//		cache.WithDisk("/var/cache/assets", 10<<30),
+//		cache.WithTTL(5*time.Minute))
+//
+// Cached headers are trusted for the TTL. After it, the header is fetched
+// again and cached bodies are only reused if their ETag still matches, so an
+// object is never downloaded twice while it is unchanged. Concurrent misses
+// of the same key share a single request to the bucket.
+//
+// Every write through the wrapper invalidates the keys it changes, including
+// conditional writes, streamed and multipart uploads, copies and deletions of
+// versions. Changes made by other clients are seen once the TTL has elapsed,
+// or immediately after calling Invalidate.
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// Default configuration values.
+const (
+	DefaultMemoryLimit   = 64 << 20
+	DefaultMaxObjectSize = 8 << 20
+	DefaultTTL           = time.Minute
+)
+
+// entryOverhead approximates the memory used by an entry besides its key
+// and body, so that header-only entries count against the memory limit.
+const entryOverhead = 512
+
+// Option configures a Bucket.
+type Option func(*Bucket)
+
+// WithMemoryLimit sets the number of bytes of headers and bodies kept in
+// memory.
+func WithMemoryLimit(n int64) Option {
+	return func(b *Bucket) {
+		if n > 0 {
+			b.memoryLimit = n
+		}
+	}
+}
+
+// WithDisk adds a tier storing up to limit bytes of bodies in files below
+// dir. Bodies evicted from memory are read back from disk, and files left by
+// a previous Bucket are reused. Each Bucket needs a directory of its own.
+func WithDisk(dir string, limit int64) Option {
+	return func(b *Bucket) {
+		if dir != "" && limit > 0 {
+			b.diskDir, b.diskLimit = dir, limit
+		}
+	}
+}
+
+// WithMaxObjectSize sets the size of the largest body that is cached.
+// Larger objects are always read from the bucket.
+func WithMaxObjectSize(n int64) Option {
+	return func(b *Bucket) {
+		if n >= 0 {
+			b.maxObjectSize = n
+		}
+	}
+}
+
+// WithTTL sets how long cached headers are trusted before the object is
+// checked again. Zero checks the ETag on every call, which still saves
+// downloading unchanged bodies.
+func WithTTL(d time.Duration) Option {
+	return func(b *Bucket) {
+		if d >= 0 {
+			b.ttl = d
+		}
+	}
+}
+
+// Bucket is a caching absos.Bucket. Range reads are served from cached
+// bodies; conditional reads, versions and listings always go to the wrapped
+// bucket. The optional interfaces the wrapped bucket lacks fall back like
+// the package helpers of absos or fail with absos.ErrNotSupported.
+//
+// Bucket implements every optional interface, so type assertions on it do
+// not tell what the wrapped bucket supports; assert on the Bucket field
+// instead. In particular, conditional reads are atomic only if the wrapped
+// bucket is an absos.ConditionalBucket.
+type Bucket struct {
+	absos.Bucket
+
+	memoryLimit   int64
+	maxObjectSize int64
+	ttl           time.Duration
+	diskDir       string
+	diskLimit     int64
+	now           func() time.Time
+
+	disk *disk
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	used    int64
+	pending map[flightKey]*call
+}
+
+// entry is the cached state of an object.
+type entry struct {
+	key     string
+	header  absos.ObjectHeader
+	checked time.Time
+
+	// data is the body of the object if cached is true.
+	data   []byte
+	cached bool
+}
+
+func (e *entry) size() int64 {
+	return entryOverhead + int64(len(e.key)) + int64(len(e.data))
+}
+
+// flightKey identifies a fetch shared by concurrent callers. Fetches of
+// headers and of bodies are kept apart, so that Head never waits for a body.
+type flightKey struct {
+	key  string
+	body bool
+}
+
+// call is an in-flight fetch.
+type call struct {
+	done  chan struct{}
+	entry *entry
+	err   error
+}
+
+// New returns a Bucket caching the objects of b.
+func New(b absos.Bucket, opts ...Option) (*Bucket, error) {
+	c := &Bucket{
+		Bucket:        b,
+		memoryLimit:   DefaultMemoryLimit,
+		maxObjectSize: DefaultMaxObjectSize,
+		ttl:           DefaultTTL,
+		now:           time.Now,
+		entries:       make(map[string]*list.Element),
+		lru:           list.New(),
+		pending:       make(map[flightKey]*call),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.diskDir != "" {
+		d, err := openDisk(c.diskDir, c.diskLimit)
+		if err != nil {
+			return nil, &absos.BucketError{Bucket: b.Name(), Err: err}
+		}
+		c.disk = d
+	}
+
+	return c, nil
+}
+
+// Head returns the header of the object, from the cache while it is fresh.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	if e := b.lookup(key, false); e != nil {
+		return e.header, nil
+	}
+
+	e, err := b.do(ctx, key, false)
+	if err != nil {
+		return nil, err
+	}
+	return e.header, nil
+}
+
+// Get returns the contents of the object, from the cache if they are
+// unchanged.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if e := b.lookup(key, true); e != nil {
+		return io.NopCloser(bytes.NewReader(e.data)), nil
+	}
+
+	e, err := b.do(ctx, key, true)
+	if err != nil {
+		return nil, err
+	}
+	if e.cached {
+		return io.NopCloser(bytes.NewReader(e.data)), nil
+	}
+
+	// Too large to cache, or changed while it was fetched
+	return b.Bucket.Get(ctx, key)
+}
+
+// Put uploads the object and invalidates its cached state.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	defer b.Invalidate(key)
+	return b.Bucket.Put(ctx, key, data, opts...)
+}
+
+// PutBatch uploads the objects of iter and invalidates their cached state.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	it := &recordingIterator{BatchIterator: iter}
+	defer func() {
+		for _, key := range it.keys {
+			b.Invalidate(key)
+		}
+	}()
+	return b.Bucket.PutBatch(ctx, it)
+}
+
+// Delete removes the object and its cached state.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	defer b.Invalidate(key)
+	return b.Bucket.Delete(ctx, key)
+}
+
+// Invalidate removes the cached state of key, so that the next call reads
+// it from the bucket. Fetches of key in progress are not cached.
+func (b *Bucket) Invalidate(key string) {
+	b.mu.Lock()
+	b.removeLocked(key)
+	delete(b.pending, flightKey{key, false})
+	delete(b.pending, flightKey{key, true})
+	b.mu.Unlock()
+
+	if b.disk != nil {
+		b.disk.remove(key)
+	}
+}
+
+// lookup returns the entry of key if it is fresh and, if body is set, holds
+// the body.
+func (b *Bucket) lookup(key string, body bool) *entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*entry)
+	if !b.freshLocked(e) || (body && !e.cached) {
+		return nil
+	}
+
+	b.lru.MoveToFront(el)
+	return e
+}
+
+// freshLocked reports whether the header of e is within the TTL.
+// The caller must hold b.mu.
+func (b *Bucket) freshLocked(e *entry) bool {
+	return b.now().Sub(e.checked) < b.ttl
+}
+
+// do fetches the entry of key, sharing the fetch with concurrent callers.
+// The fetch is not canceled with ctx, as other callers may be waiting for
+// it, but do returns when ctx is done.
+func (b *Bucket) do(ctx context.Context, key string, body bool) (*entry, error) {
+	id := flightKey{key, body}
+
+	b.mu.Lock()
+	c, ok := b.pending[id]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		b.pending[id] = c
+		go b.run(context.WithoutCancel(ctx), id, c)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.entry, c.err
+	case <-ctx.Done():
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: ctx.Err()}
+	}
+}
+
+// run performs the fetch of c and caches its result, unless the key was
+// invalidated in the meantime.
+func (b *Bucket) run(ctx context.Context, id flightKey, c *call) {
+	c.entry, c.err = b.fetch(ctx, id.key, id.body)
+
+	b.mu.Lock()
+	if b.pending[id] == c {
+		delete(b.pending, id)
+		switch {
+		case c.err == nil:
+			b.storeLocked(c.entry)
+		case errors.Is(c.err, absos.ErrObjectNotFound):
+			b.removeLocked(id.key)
+		}
+	}
+	b.mu.Unlock()
+
+	close(c.done)
+}
+
+// fetch reads the header of key from the bucket, unless the cached one is
+// fresh, and, if body is set, its contents. Bodies with the ETag of the
+// header are reused from memory or disk. The returned entry only holds the
+// body if it can be cached.
+func (b *Bucket) fetch(ctx context.Context, key string, body bool) (*entry, error) {
+	e := &entry{key: key}
+
+	b.mu.Lock()
+	var old *entry
+	if el, ok := b.entries[key]; ok {
+		old = el.Value.(*entry)
+		if b.freshLocked(old) {
+			e.header, e.checked = old.header, old.checked
+		}
+	}
+	b.mu.Unlock()
+
+	if e.header == nil {
+		header, err := b.Bucket.Head(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		e.header, e.checked = header, b.now()
+	}
+
+	etag := e.header.ETag()
+	if len(etag) == 0 || e.header.Size() > b.maxObjectSize {
+		return e, nil
+	}
+
+	if old != nil && old.cached && bytes.Equal(old.header.ETag(), etag) {
+		e.data, e.cached = old.data, true
+		return e, nil
+	}
+
+	if !body {
+		return e, nil
+	}
+
+	if b.disk != nil {
+		if data, ok := b.disk.get(key, etag); ok {
+			e.data, e.cached = data, true
+			return e, nil
+		}
+	}
+
+	// The ETag is a precondition, so that the body matches the header
+	r, err := absos.GetIf(ctx, b.Bucket, key, absos.Conditions{IfMatch: etag})
+	if errors.Is(err, absos.ErrPreconditionFailed) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	e.data, e.cached = data, true
+
+	if b.disk != nil {
+		b.disk.put(key, etag, data)
+	}
+
+	return e, nil
+}
+
+// storeLocked caches e, evicting the least recently used entries to stay
+// within the memory limit. Bodies too large for the limit are left to the
+// disk tier. The caller must hold b.mu.
+func (b *Bucket) storeLocked(e *entry) {
+	b.removeLocked(e.key)
+
+	if e.cached && e.size() > b.memoryLimit {
+		e = &entry{key: e.key, header: e.header, checked: e.checked}
+	}
+
+	b.entries[e.key] = b.lru.PushFront(e)
+	b.used += e.size()
+
+	for b.used > b.memoryLimit {
+		oldest := b.lru.Back()
+		if oldest == nil {
+			break
+		}
+		b.removeLocked(oldest.Value.(*entry).key)
+	}
+}
+
+// removeLocked removes the entry of key from memory.
+// The caller must hold b.mu.
+func (b *Bucket) removeLocked(key string) {
+	el, ok := b.entries[key]
+	if !ok {
+		return
+	}
+
+	b.lru.Remove(el)
+	delete(b.entries, key)
+	b.used -= el.Value.(*entry).size()
+}
+
+// recordingIterator records the keys of the objects uploaded by PutBatch.
+type recordingIterator struct {
+	absos.BatchIterator
+	keys []string
+}
+
+func (it *recordingIterator) Object() absos.BatchObject {
+	obj := it.BatchIterator.Object()
+	it.keys = append(it.keys, obj.Key)
+	return obj
+}
@@ -0,0 +1,427 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// countingBucket counts the reads issued against the wrapped bucket.
+// If gate is set, Get blocks until it is closed.
+type countingBucket struct {
+	absos.Bucket
+	heads atomic.Int64
+	gets  atomic.Int64
+	gate  chan struct{}
+}
+
+func (b *countingBucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	b.heads.Add(1)
+	return b.Bucket.Head(ctx, key)
+}
+
+func (b *countingBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.gets.Add(1)
+	if b.gate != nil {
+		<-b.gate
+	}
+	return b.Bucket.Get(ctx, key)
+}
+
+func newTestBucket(t *testing.T) *countingBucket {
+	t.Helper()
+
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	return &countingBucket{Bucket: buckets[0]}
+}
+
+// newTestCache returns a cache over a new bucket and a function advancing
+// its clock.
+func newTestCache(t *testing.T, opts ...Option) (*Bucket, *countingBucket, func(time.Duration)) {
+	t.Helper()
+
+	backend := newTestBucket(t)
+	c, err := New(backend, opts...)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	return c, backend, func(d time.Duration) { now = now.Add(d) }
+}
+
+func put(t *testing.T, b absos.Bucket, key, data string) {
+	t.Helper()
+
+	if err := b.Put(context.Background(), key, strings.NewReader(data)); err != nil {
+		t.Fatalf("failed to put %q: %v", key, err)
+	}
+}
+
+func get(t *testing.T, b absos.Bucket, key string) string {
+	t.Helper()
+
+	r, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("failed to get %q: %v", key, err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read %q: %v", key, err)
+	}
+
+	return string(data)
+}
+
+func TestGetCached(t *testing.T) {
+	c, backend, _ := newTestCache(t)
+	put(t, backend.Bucket, "key", "data")
+
+	for i := 0; i < 3; i++ {
+		if got := get(t, c, "key"); got != "data" {
+			t.Fatalf("expected %q, got %q", "data", got)
+		}
+	}
+
+	header, err := c.Head(context.Background(), "key")
+	if err != nil || header.Size() != 4 {
+		t.Fatalf("expected a header of 4 bytes, got %v and %v", header, err)
+	}
+
+	if heads, gets := backend.heads.Load(), backend.gets.Load(); gets != 1 {
+		t.Errorf("expected 1 Get of the bucket, got %d (and %d Heads)", gets, heads)
+	}
+}
+
+func TestRevalidate(t *testing.T) {
+	c, backend, advance := newTestCache(t, WithTTL(time.Minute))
+	put(t, backend.Bucket, "key", "old")
+	get(t, c, "key")
+
+	// Changes made past the cache are seen after the TTL
+	put(t, backend.Bucket, "key", "new")
+	if got := get(t, c, "key"); got != "old" {
+		t.Errorf("expected the cached %q within the TTL, got %q", "old", got)
+	}
+
+	advance(2 * time.Minute)
+	if got := get(t, c, "key"); got != "new" {
+		t.Errorf("expected %q after the TTL, got %q", "new", got)
+	}
+
+	// Unchanged bodies are not downloaded again
+	gets := backend.gets.Load()
+	advance(2 * time.Minute)
+	if got := get(t, c, "key"); got != "new" {
+		t.Errorf("expected %q, got %q", "new", got)
+	}
+	if n := backend.gets.Load(); n != gets {
+		t.Errorf("expected no download of an unchanged object, got %d", n-gets)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c, backend, _ := newTestCache(t)
+
+	put(t, c, "key", "old")
+	get(t, c, "key")
+
+	put(t, c, "key", "new")
+	if got := get(t, c, "key"); got != "new" {
+		t.Errorf("expected %q after Put, got %q", "new", got)
+	}
+
+	err := c.PutBatch(ctx, absos.NewBatchIterator(absos.BatchObject{Key: "key", Data: strings.NewReader("batch")}))
+	if err != nil {
+		t.Fatalf("failed to put batch: %v", err)
+	}
+	if got := get(t, c, "key"); got != "batch" {
+		t.Errorf("expected %q after PutBatch, got %q", "batch", got)
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound after Delete, got %v", err)
+	}
+
+	put(t, c, "key", "many")
+	put(t, c, "other", "many")
+	get(t, c, "key")
+	get(t, c, "other")
+	if err := absos.DeleteMany(ctx, c, []string{"key", "other"}); err != nil {
+		t.Fatalf("failed to delete many: %v", err)
+	}
+	for _, key := range []string{"key", "other"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, absos.ErrObjectNotFound) {
+			t.Errorf("expected ErrObjectNotFound for %q after DeleteMany, got %v", key, err)
+		}
+	}
+
+	put(t, backend.Bucket, "key", "external")
+	c.Invalidate("key")
+	if got := get(t, c, "key"); got != "external" {
+		t.Errorf("expected %q after Invalidate, got %q", "external", got)
+	}
+}
+
+func TestGetRangeCached(t *testing.T) {
+	ctx := context.Background()
+	c, backend, _ := newTestCache(t)
+
+	put(t, c, "key", "hello")
+	get(t, c, "key")
+
+	r, err := absos.GetRange(ctx, c, "key", 1, 3)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "ell" {
+		t.Errorf("expected ell, got %q", data)
+	}
+	if n := backend.gets.Load(); n != 1 {
+		t.Errorf("expected the range to be read from the cache, got %d gets", n)
+	}
+
+	if _, err := absos.GetRange(ctx, c, "key", 5, 1); !errors.Is(err, absos.ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+}
+
+func TestConditional(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBucket(t)
+
+	// The memory bucket itself supports conditional writes
+	c, err := New(backend.Bucket)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	cond := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, c, "key", strings.NewReader("first"), cond); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := absos.PutIf(ctx, c, "key", strings.NewReader("second"), cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	header, err := c.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	if got := get(t, c, "key"); got != "first" {
+		t.Errorf("expected %q, got %q", "first", got)
+	}
+
+	err = absos.PutIf(ctx, c, "key", strings.NewReader("third"), absos.Conditions{IfMatch: header.ETag()})
+	if err != nil {
+		t.Fatalf("failed to replace: %v", err)
+	}
+	if got := get(t, c, "key"); got != "third" {
+		t.Errorf("expected %q after PutIf, got %q", "third", got)
+	}
+
+	if err := absos.Upload(ctx, c, "key", strings.NewReader("streamed")); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if got := get(t, c, "key"); got != "streamed" {
+		t.Errorf("expected %q after PutStream, got %q", "streamed", got)
+	}
+}
+
+func TestPresign(t *testing.T) {
+	c, _, _ := newTestCache(t)
+
+	// The memory bucket cannot presign URLs
+	_, err := c.Presign(context.Background(), "GET", "key", time.Minute)
+	if !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestConcurrentMisses(t *testing.T) {
+	c, backend, _ := newTestCache(t)
+	put(t, backend.Bucket, "key", "data")
+	backend.gate = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := get(t, c, "key"); got != "data" {
+				t.Errorf("expected %q, got %q", "data", got)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(backend.gate)
+	wg.Wait()
+
+	if gets := backend.gets.Load(); gets != 1 {
+		t.Errorf("expected 1 Get of the bucket, got %d", gets)
+	}
+}
+
+func TestCanceledWaiter(t *testing.T) {
+	c, backend, _ := newTestCache(t)
+	put(t, backend.Bucket, "key", "data")
+	backend.gate = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	// The fetch continues for other callers
+	close(backend.gate)
+	if got := get(t, c, "key"); got != "data" {
+		t.Errorf("expected %q, got %q", "data", got)
+	}
+}
+
+func TestMaxObjectSize(t *testing.T) {
+	c, backend, _ := newTestCache(t, WithMaxObjectSize(4))
+	put(t, backend.Bucket, "small", "data")
+	put(t, backend.Bucket, "large", "more data")
+
+	for i := 0; i < 2; i++ {
+		get(t, c, "small")
+		get(t, c, "large")
+	}
+
+	if gets := backend.gets.Load(); gets != 3 {
+		t.Errorf("expected 1 Get of the small and 2 of the large object, got %d", gets)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	c, backend, _ := newTestCache(t, WithMemoryLimit(2*entryOverhead+20))
+	for _, key := range []string{"a", "b", "c"} {
+		put(t, backend.Bucket, key, key)
+		get(t, c, key)
+	}
+
+	if c.used > c.memoryLimit || len(c.entries) != 2 {
+		t.Errorf("expected 2 entries within the limit, got %d using %d bytes", len(c.entries), c.used)
+	}
+
+	// The least recently used entry was evicted
+	gets := backend.gets.Load()
+	get(t, c, "a")
+	if backend.gets.Load() != gets+1 {
+		t.Error("expected the evicted object to be fetched again")
+	}
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	// Bodies are too large for memory and kept on disk only
+	c, backend, _ := newTestCache(t, WithMemoryLimit(entryOverhead+10), WithDisk(dir, 1<<20))
+	put(t, backend.Bucket, "key", "data on disk")
+
+	for i := 0; i < 2; i++ {
+		if got := get(t, c, "key"); got != "data on disk" {
+			t.Fatalf("expected %q, got %q", "data on disk", got)
+		}
+	}
+	if gets := backend.gets.Load(); gets != 1 {
+		t.Errorf("expected 1 Get of the bucket, got %d", gets)
+	}
+
+	// A new cache reuses the files of the previous one
+	reopened, err := New(backend, WithDisk(dir, 1<<20))
+	if err != nil {
+		t.Fatalf("failed to reopen cache: %v", err)
+	}
+	if got := get(t, reopened, "key"); got != "data on disk" {
+		t.Errorf("expected %q, got %q", "data on disk", got)
+	}
+	if gets := backend.gets.Load(); gets != 1 {
+		t.Errorf("expected the reopened cache to read from disk, got %d Gets", gets)
+	}
+
+	// Files of a different ETag are not used
+	put(t, backend.Bucket, "key", "changed")
+	reopened.Invalidate("key")
+	if got := get(t, reopened, "key"); got != "changed" {
+		t.Errorf("expected %q, got %q", "changed", got)
+	}
+}
+
+func TestDiskLimit(t *testing.T) {
+	dir := t.TempDir()
+	c, backend, _ := newTestCache(t, WithMemoryLimit(1), WithDisk(dir, 100))
+
+	for _, key := range []string{"a", "b", "c"} {
+		put(t, backend.Bucket, key, strings.Repeat(key, 30))
+		get(t, c, key)
+	}
+
+	if c.disk.used > 100 || len(c.disk.files) != 1 {
+		t.Errorf("expected 1 file within the limit, got %d using %d bytes", len(c.disk.files), c.disk.used)
+	}
+}
+
+// cachingStore wraps the buckets of a store in caches, keeping the cache of
+// each bucket across listings.
+type cachingStore struct {
+	absos.ObjectStore
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+func (s *cachingStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := s.ObjectStore.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, b := range buckets {
+		c, ok := s.buckets[b.Name()]
+		if !ok {
+			if c, err = New(b); err != nil {
+				return nil, err
+			}
+			s.buckets[b.Name()] = c
+		}
+		buckets[i] = c
+	}
+	return buckets, nil
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return &cachingStore{ObjectStore: memory.NewStore(), buckets: make(map[string]*Bucket)}
+	})
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/capability"
+)
+
+var (
+	_ absos.RangeBucket       = (*Bucket)(nil)
+	_ absos.ConditionalBucket = (*Bucket)(nil)
+	_ absos.StreamBucket      = (*Bucket)(nil)
+	_ absos.CopyBucket        = (*Bucket)(nil)
+	_ absos.MultipartBucket   = (*Bucket)(nil)
+	_ absos.VersionedBucket   = (*Bucket)(nil)
+	_ absos.BulkDeleteBucket  = (*Bucket)(nil)
+	_ absos.Presigner         = (*Bucket)(nil)
+)
+
+// GetRange returns part of the contents of the object, from the cache if
+// they are fresh, and otherwise from the bucket without caching them.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	e := b.lookup(key, true)
+	if e == nil {
+		return absos.GetRange(ctx, b.Bucket, key, offset, length)
+	}
+
+	start, n, err := absos.ResolveRange(int64(len(e.data)), offset, length)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	return io.NopCloser(bytes.NewReader(e.data[start : start+n])), nil
+}
+
+// PutIf uploads the object if cond holds and invalidates its cached state.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	defer b.Invalidate(key)
+	return absos.PutIf(ctx, b.Bucket, key, data, cond, opts...)
+}
+
+// GetIf opens the object if cond holds. Conditional reads are not cached,
+// as they are used to check the state of the bucket.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	return absos.GetIf(ctx, b.Bucket, key, cond)
+}
+
+// HeadIf returns the header of the object if cond holds, from the bucket.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	return absos.HeadIf(ctx, b.Bucket, key, cond)
+}
+
+// DeleteIf removes the object if cond holds and invalidates its cached
+// state.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	defer b.Invalidate(key)
+	return absos.DeleteIf(ctx, b.Bucket, key, cond)
+}
+
+// PutStream uploads the object read from data and invalidates its cached
+// state.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	defer b.Invalidate(key)
+	return absos.Upload(ctx, b.Bucket, key, data, opts...)
+}
+
+// Copy copies an object to dstKey and invalidates its cached state. It fails
+// with absos.ErrNotSupported if the wrapped bucket cannot copy from src.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	if s, ok := src.(*Bucket); ok {
+		src = s.Bucket
+	}
+
+	cb, err := capability.As[absos.CopyBucket](b.Bucket, dstKey)
+	if err != nil {
+		return err
+	}
+
+	defer b.Invalidate(dstKey)
+	return cb.Copy(ctx, src, srcKey, dstKey, opts...)
+}
+
+// DeleteLimit returns the number of keys the wrapped bucket deletes at
+// once, or 1 if it deletes them one by one.
+func (b *Bucket) DeleteLimit() int {
+	return capability.DeleteLimit(b.Bucket)
+}
+
+// DeleteMany deletes the objects with the given keys and invalidates the
+// cached state of every key, whether or not its deletion failed.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	defer func() {
+		for _, key := range keys {
+			b.Invalidate(key)
+		}
+	}()
+	return absos.DeleteMany(ctx, b.Bucket, keys)
+}
+
+// Presign returns a URL for method on the object from the wrapped bucket.
+// Requests made with the URL bypass the cache, so a presigned upload is not
+// seen by the readers of the cache until the cached state of the object
+// expires or is invalidated.
+func (b *Bucket) Presign(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	p, err := capability.As[absos.Presigner](b.Bucket, key)
+	if err != nil {
+		return "", err
+	}
+	return p.Presign(ctx, method, key, expires)
+}
+
+// InitiateMultipart starts a multipart upload. The multipart operations fail
+// with absos.ErrNotSupported if the wrapped bucket does not support them.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, key)
+	if err != nil {
+		return absos.MultipartUpload{}, err
+	}
+	return mb.InitiateMultipart(ctx, key, opts...)
+}
+
+// UploadPart uploads a part of a multipart upload.
+func (b *Bucket) UploadPart(ctx context.Context, upload absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return absos.Part{}, err
+	}
+	return mb.UploadPart(ctx, upload, number, data)
+}
+
+// ListParts lists the parts of a multipart upload.
+func (b *Bucket) ListParts(ctx context.Context, upload absos.MultipartUpload) ([]absos.Part, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+	return mb.ListParts(ctx, upload)
+}
+
+// CompleteMultipart assembles the parts of a multipart upload and
+// invalidates the cached state of its key.
+func (b *Bucket) CompleteMultipart(ctx context.Context, upload absos.MultipartUpload, parts []absos.Part) error {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return err
+	}
+
+	defer b.Invalidate(upload.Key)
+	return mb.CompleteMultipart(ctx, upload, parts)
+}
+
+// AbortMultipart aborts a multipart upload.
+func (b *Bucket) AbortMultipart(ctx context.Context, upload absos.MultipartUpload) error {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return err
+	}
+	return mb.AbortMultipart(ctx, upload)
+}
+
+// ListMultipartUploads lists the multipart uploads in progress.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	return mb.ListMultipartUploads(ctx, prefix)
+}
+
+// Versioning returns the versioning state of the bucket. The versioning
+// operations fail with absos.ErrNotSupported if the wrapped bucket does not
+// support them. Versions are read from the bucket and never cached.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return "", err
+	}
+	return vb.Versioning(ctx)
+}
+
+// SetVersioning enables or suspends versioning.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return err
+	}
+	return vb.SetVersioning(ctx, status)
+}
+
+// ListVersions lists a page of versions.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	return vb.ListVersions(ctx, prefix, token)
+}
+
+// GetVersion opens a version of an object.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return vb.GetVersion(ctx, key, versionID)
+}
+
+// HeadVersion retrieves the metadata of a version of an object.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return vb.HeadVersion(ctx, key, versionID)
+}
+
+// DeleteVersion permanently removes a version or delete marker and
+// invalidates the cached state of key, whose current version may change.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return err
+	}
+
+	defer b.Invalidate(key)
+	return vb.DeleteVersion(ctx, key, versionID)
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tmpPrefix starts the names of files being written.
+const tmpPrefix = "tmp-"
+
+// disk is the on-disk tier. Each body is stored in a file named after the
+// hash of its key, starting with the hexadecimal ETag of the body on a line
+// of its own. Errors are not reported: a body that cannot be written or read
+// is fetched from the bucket instead.
+type disk struct {
+	dir   string
+	limit int64
+
+	mu    sync.Mutex
+	files map[string]*list.Element
+	lru   *list.List
+	used  int64
+}
+
+// diskFile is a file of the disk tier.
+type diskFile struct {
+	name string
+	size int64
+}
+
+// openDisk returns the disk tier stored in dir, indexing the files left by
+// a previous run from the most to the least recently modified.
+func openDisk(dir string, limit int64) (*disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type existing struct {
+		diskFile
+		modTime time.Time
+	}
+	var files []existing
+	for _, de := range entries {
+		if strings.HasPrefix(de.Name(), tmpPrefix) {
+			_ = os.Remove(filepath.Join(dir, de.Name()))
+			continue
+		}
+		if !de.Type().IsRegular() {
+			continue
+		}
+
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, existing{diskFile{de.Name(), info.Size()}, info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	d := &disk{dir: dir, limit: limit, files: make(map[string]*list.Element), lru: list.New()}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, f := range files {
+		d.addLocked(f.diskFile)
+	}
+	d.evictLocked()
+
+	return d, nil
+}
+
+// get returns the body of key if it is stored with etag.
+func (d *disk) get(key string, etag []byte) ([]byte, bool) {
+	name := fileName(key)
+
+	d.mu.Lock()
+	el, ok := d.files[name]
+	if ok {
+		d.lru.MoveToFront(el)
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(d.dir, name))
+	if err != nil {
+		return nil, false
+	}
+
+	line, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok || string(line) != hex.EncodeToString(etag) {
+		return nil, false
+	}
+
+	return body, true
+}
+
+// put stores the body of key, evicting the least recently used files to
+// stay within the limit.
+func (d *disk) put(key string, etag, data []byte) {
+	tmp, err := os.CreateTemp(d.dir, tmpPrefix+"*")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.WriteString(hex.EncodeToString(etag) + "\n")
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	info, serr := os.Stat(tmp.Name())
+	if err != nil || serr != nil || info.Size() > d.limit {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+
+	name := fileName(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.Rename(tmp.Name(), filepath.Join(d.dir, name)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+
+	d.forgetLocked(name)
+	d.addLocked(diskFile{name, info.Size()})
+	d.evictLocked()
+}
+
+// remove deletes the body of key.
+func (d *disk) remove(key string) {
+	name := fileName(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.files[name]; ok {
+		_ = os.Remove(filepath.Join(d.dir, name))
+		d.forgetLocked(name)
+	}
+}
+
+// addLocked indexes f as the most recently used file.
+// The caller must hold d.mu.
+func (d *disk) addLocked(f diskFile) {
+	d.files[f.name] = d.lru.PushFront(f)
+	d.used += f.size
+}
+
+// forgetLocked removes the file name from the index.
+// The caller must hold d.mu.
+func (d *disk) forgetLocked(name string) {
+	el, ok := d.files[name]
+	if !ok {
+		return
+	}
+
+	d.lru.Remove(el)
+	delete(d.files, name)
+	d.used -= el.Value.(diskFile).size
+}
+
+// evictLocked deletes the least recently used files until the tier is
+// within its limit. The caller must hold d.mu.
+func (d *disk) evictLocked() {
+	for d.used > d.limit {
+		oldest := d.lru.Back()
+		if oldest == nil {
+			return
+		}
+
+		name := oldest.Value.(diskFile).name
+		_ = os.Remove(filepath.Join(d.dir, name))
+		d.forgetLocked(name)
+	}
+}
+
+// fileName returns the name of the file storing the body of key.
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,152 @@
+package absos
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ChecksumAlgorithm names an algorithm used to verify the contents of
+// objects. The names match the x-amz-checksum-* headers of S3.
+type ChecksumAlgorithm string
+
+// Supported checksum algorithms.
+const (
+	ChecksumCRC32C ChecksumAlgorithm = "CRC32C"
+	ChecksumSHA1   ChecksumAlgorithm = "SHA1"
+	ChecksumSHA256 ChecksumAlgorithm = "SHA256"
+)
+
+// ChecksumAlgorithms lists the supported algorithms.
+var ChecksumAlgorithms = []ChecksumAlgorithm{ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// New returns a hash computing checksums with a, or nil if a is not supported.
+func (a ChecksumAlgorithm) New() hash.Hash {
+	switch a {
+	case ChecksumCRC32C:
+		return crc32.New(crc32c)
+	case ChecksumSHA1:
+		return sha1.New()
+	case ChecksumSHA256:
+		return sha256.New()
+	}
+	return nil
+}
+
+// Checksums holds checksums of the contents of an object by algorithm. Unlike
+// ETags, they are always digests of the complete contents, including for
+// objects uploaded in parts.
+type Checksums map[ChecksumAlgorithm][]byte
+
+// Verify compares c with the checksums computed for the contents, returning
+// ErrChecksumMismatch if any of them differs. Algorithms of c missing from
+// computed are reported as ErrNotSupported.
+func (c Checksums) Verify(computed Checksums) error {
+	for a, expected := range c {
+		sum, ok := computed[a]
+		if !ok {
+			return fmt.Errorf("%w: %s checksums", ErrNotSupported, a)
+		}
+		if !bytes.Equal(expected, sum) {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, a)
+		}
+	}
+	return nil
+}
+
+// ChecksumHasher computes checksums of the data written to it.
+type ChecksumHasher struct {
+	hashes map[ChecksumAlgorithm]hash.Hash
+}
+
+// NewChecksumHasher returns a ChecksumHasher for the given algorithms.
+// Unsupported algorithms are ignored.
+func NewChecksumHasher(algorithms ...ChecksumAlgorithm) *ChecksumHasher {
+	h := &ChecksumHasher{hashes: make(map[ChecksumAlgorithm]hash.Hash, len(algorithms))}
+	for _, a := range algorithms {
+		if hh := a.New(); hh != nil {
+			h.hashes[a] = hh
+		}
+	}
+	return h
+}
+
+// Write adds p to the checksums. It never returns an error.
+func (h *ChecksumHasher) Write(p []byte) (int, error) {
+	for _, hh := range h.hashes {
+		hh.Write(p)
+	}
+	return len(p), nil
+}
+
+// Sum returns the checksums of the data written so far.
+func (h *ChecksumHasher) Sum() Checksums {
+	sums := make(Checksums, len(h.hashes))
+	for a, hh := range h.hashes {
+		sums[a] = hh.Sum(nil)
+	}
+	return sums
+}
+
+// ComputeChecksums returns the checksums of data with every supported algorithm.
+func ComputeChecksums(data []byte) Checksums {
+	h := NewChecksumHasher(ChecksumAlgorithms...)
+	_, _ = h.Write(data)
+	return h.Sum()
+}
+
+// VerifyReader returns a reader computing the checksums in expected while r
+// is read. Instead of io.EOF, it returns an error wrapping
+// ErrChecksumMismatch if the contents do not match. Algorithms it cannot
+// compute are not verified, and r is returned unchanged if there is nothing
+// to verify.
+func VerifyReader(r io.ReadCloser, expected Checksums) io.ReadCloser {
+	var algorithms []ChecksumAlgorithm
+	for a := range expected {
+		if a.New() != nil {
+			algorithms = append(algorithms, a)
+		}
+	}
+	if len(algorithms) == 0 {
+		return r
+	}
+
+	return &verifyReader{r: r, expected: expected, hasher: NewChecksumHasher(algorithms...)}
+}
+
+type verifyReader struct {
+	r        io.ReadCloser
+	expected Checksums
+	hasher   *ChecksumHasher
+	err      error
+}
+
+func (v *verifyReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.r.Read(p)
+	_, _ = v.hasher.Write(p[:n])
+
+	if err == io.EOF {
+		for a, sum := range v.hasher.Sum() {
+			if !bytes.Equal(sum, v.expected[a]) {
+				v.err = fmt.Errorf("%w: %s", ErrChecksumMismatch, a)
+				return n, v.err
+			}
+		}
+	}
+
+	return n, err
+}
+
+func (v *verifyReader) Close() error {
+	return v.r.Close()
+}
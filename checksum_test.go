@@ -0,0 +1,62 @@
+package absos_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+)
+
+func TestComputeChecksums(t *testing.T) {
+	sums := absos.ComputeChecksums([]byte("hello"))
+
+	expected := map[absos.ChecksumAlgorithm]string{
+		absos.ChecksumCRC32C: "9a71bb4c",
+		absos.ChecksumSHA1:   "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		absos.ChecksumSHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+	for a, want := range expected {
+		if got := hex.EncodeToString(sums[a]); got != want {
+			t.Errorf("%s: expected %s, got %s", a, want, got)
+		}
+	}
+}
+
+func TestChecksumsVerify(t *testing.T) {
+	computed := absos.ComputeChecksums([]byte("hello"))
+	other := absos.ComputeChecksums([]byte("world"))
+
+	if err := (absos.Checksums{absos.ChecksumSHA256: computed[absos.ChecksumSHA256]}).Verify(computed); err != nil {
+		t.Errorf("expected matching checksums to verify, got %v", err)
+	}
+
+	if err := (absos.Checksums{absos.ChecksumSHA1: other[absos.ChecksumSHA1]}).Verify(computed); !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	if err := (absos.Checksums{"MD4": []byte{1}}).Verify(computed); !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for an unknown algorithm, got %v", err)
+	}
+}
+
+func TestVerifyReader(t *testing.T) {
+	computed := absos.ComputeChecksums([]byte("hello"))
+
+	r := absos.VerifyReader(io.NopCloser(strings.NewReader("hello")), computed)
+	if data, err := io.ReadAll(r); err != nil || string(data) != "hello" {
+		t.Errorf("expected %q, got %q and %v", "hello", data, err)
+	}
+
+	r = absos.VerifyReader(io.NopCloser(strings.NewReader("hullo")), computed)
+	if _, err := io.ReadAll(r); !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	// The error is reported again instead of EOF
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch on the next read, got %v", err)
+	}
+}
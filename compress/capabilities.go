@@ -0,0 +1,184 @@
+package compress
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/capability"
+)
+
+var (
+	_ absos.ConditionalBucket = (*Bucket)(nil)
+	_ absos.CopyBucket        = (*Bucket)(nil)
+	_ absos.VersionedBucket   = (*Bucket)(nil)
+	_ absos.BulkDeleteBucket  = (*Bucket)(nil)
+)
+
+// PutIf compresses and uploads an object if cond holds. The compressed
+// contents are spooled to a temporary file, so that the wrapped bucket can
+// read them again. It fails with absos.ErrNotSupported if the wrapped bucket
+// does not support conditional writes.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	options := absos.NewPutOptions(opts...)
+
+	encoding := b.encodingOf(key, options)
+	if encoding == Identity {
+		return absos.PutIf(ctx, b.Bucket, key, data, cond, opts...)
+	}
+
+	checksums, err := describe(data, encoding, &options)
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	f, err := os.CreateTemp("", "absos-compress-*")
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = compress(f, data, encoding, checksums)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	return absos.PutIf(ctx, b.Bucket, key, f, cond, func(o *absos.PutOptions) { *o = options })
+}
+
+// GetIf retrieves and decompresses an object if cond holds.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	raw, err := absos.HeadIf(ctx, b.Bucket, key, cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.open(key, raw, func() (io.ReadCloser, error) {
+		return absos.GetIf(ctx, b.Bucket, key, absos.Conditions{IfMatch: raw.ETag()})
+	})
+}
+
+// HeadIf retrieves the metadata of an object if cond holds, with the size
+// of its decompressed contents.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	raw, err := absos.HeadIf(ctx, b.Bucket, key, cond)
+	if err != nil {
+		return nil, err
+	}
+	return b.header(key, raw)
+}
+
+// DeleteIf removes an object if cond holds. It fails with
+// absos.ErrNotSupported if the wrapped bucket does not support conditional
+// writes.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	return absos.DeleteIf(ctx, b.Bucket, key, cond)
+}
+
+// Copy copies an object without decompressing it, keeping the entries
+// describing its compression. It fails with absos.ErrNotSupported if the
+// attributes of the source are replaced or the wrapped bucket cannot copy
+// from src, so that absos.Copy falls back to decompressing the object and
+// compressing it again.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	if s, ok := src.(*Bucket); ok {
+		src = s.Bucket
+	}
+
+	if absos.NewCopyOptions(opts...).Directive != absos.MetadataCopy {
+		return &absos.ObjectError{Bucket: b.Name(), Key: dstKey, Err: absos.ErrNotSupported}
+	}
+
+	cb, err := capability.As[absos.CopyBucket](b.Bucket, dstKey)
+	if err != nil {
+		return err
+	}
+	return cb.Copy(ctx, src, srcKey, dstKey, opts...)
+}
+
+// DeleteLimit returns the number of keys the wrapped bucket deletes at
+// once, or 1 if it deletes them one by one.
+func (b *Bucket) DeleteLimit() int {
+	return capability.DeleteLimit(b.Bucket)
+}
+
+// DeleteMany deletes the objects with the given keys from the wrapped
+// bucket.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	return absos.DeleteMany(ctx, b.Bucket, keys)
+}
+
+// Versioning returns the versioning state of the bucket. The versioning
+// operations fail with absos.ErrNotSupported if the wrapped bucket does not
+// support them.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return "", err
+	}
+	return vb.Versioning(ctx)
+}
+
+// SetVersioning enables or suspends versioning.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return err
+	}
+	return vb.SetVersioning(ctx, status)
+}
+
+// ListVersions lists a page of versions, with their stored sizes.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	return vb.ListVersions(ctx, prefix, token)
+}
+
+// GetVersion retrieves and decompresses a version of an object.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := vb.HeadVersion(ctx, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.open(key, raw, func() (io.ReadCloser, error) {
+		return vb.GetVersion(ctx, key, versionID)
+	})
+}
+
+// HeadVersion retrieves the metadata of a version of an object, with the
+// size of its decompressed contents.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := vb.HeadVersion(ctx, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return b.header(key, raw)
+}
+
+// DeleteVersion permanently removes a version or delete marker.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return err
+	}
+	return vb.DeleteVersion(ctx, key, versionID)
+}
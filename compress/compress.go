@@ -0,0 +1,477 @@
+// Package compress wraps buckets to compress the contents of objects
+// transparently, whatever their backend:
+//
+//	bucket = compress.New(bucket,
+//		compress.WithEncoding("logs/*", compress.Zstd),
+//		compress.WithEncoding("backups/*", compress.Identity))
+//
+// Objects are compressed on Put with the encoding of the first pattern
+// matching their key, or gzip by default, and decompressed on Get and Open.
+// The encoding and the original size are stored in the user metadata of the
+// object, so Head reports the size of the decompressed contents. Objects
+// with a Content-Encoding or with the MIME type of already compressed data,
+// such as images, video and archives, are stored as is.
+package compress
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/measure"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported encodings.
+const (
+	Gzip     = "gzip"
+	Zstd     = "zstd"
+	Identity = "identity"
+)
+
+// User metadata entries describing the compression of an object.
+const (
+	MetadataEncoding = "absos-compression-encoding"
+	MetadataSize     = "absos-compression-size"
+)
+
+// CompressedTypes lists the MIME types, or type/* families, of contents
+// that are already compressed and stored as is.
+var CompressedTypes = []string{
+	"image/*",
+	"audio/*",
+	"video/*",
+	"font/woff",
+	"font/woff2",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/zip",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-rar-compressed",
+	"application/x-xz",
+	"application/vnd.rar",
+}
+
+// uncompressedImages are images in text formats, which compress well.
+var uncompressedImages = map[string]bool{
+	"image/svg+xml": true,
+	"image/bmp":     true,
+}
+
+// Option configures a Bucket.
+type Option func(*Bucket)
+
+// WithEncoding compresses objects whose key matches pattern with encoding,
+// which is Gzip, Zstd or Identity to store them as is. Patterns have the
+// syntax of path.Match, where * does not match /, and are tried in the order
+// they were added.
+func WithEncoding(pattern, encoding string) Option {
+	return func(b *Bucket) {
+		b.rules = append(b.rules, rule{pattern: pattern, encoding: encoding})
+	}
+}
+
+// WithDefaultEncoding sets the encoding of objects matching no pattern,
+// Gzip by default.
+func WithDefaultEncoding(encoding string) Option {
+	return func(b *Bucket) {
+		b.encoding = encoding
+	}
+}
+
+// WithCompressedTypes replaces CompressedTypes as the MIME types of contents
+// stored as is.
+func WithCompressedTypes(types ...string) Option {
+	return func(b *Bucket) {
+		b.compressedTypes = types
+	}
+}
+
+type rule struct {
+	pattern  string
+	encoding string
+}
+
+// Bucket is an absos.Bucket compressing the objects it stores and
+// decompressing the objects it reads. Conditional requests and versions are
+// forwarded to the wrapped bucket and decompressed like Put and Get, bulk
+// deletes are forwarded as is, and copies keep the stored contents of their
+// source. Streams are buffered by absos.Upload and compressed with Put, as
+// the size recorded with an object must be known before it is uploaded.
+//
+// Head reports the size of the decompressed contents, and no checksums.
+// Listed objects report their stored size unless the listing includes user
+// metadata, but their range reads use the decompressed size. Listed versions
+// report their stored size. Checksums passed to Put are verified against the
+// contents before they are compressed, and not stored.
+//
+// Bucket does not implement absos.Presigner, even if the wrapped bucket
+// does: a presigned GET would serve the compressed bytes, and a presigned
+// PUT would store an object without the metadata describing its encoding.
+// The other optional interfaces are implemented whether or not the wrapped
+// bucket supports them, so type assertions on Bucket do not tell what the
+// wrapped bucket supports; assert on the Bucket field instead.
+type Bucket struct {
+	absos.Bucket
+	rules           []rule
+	encoding        string
+	compressedTypes []string
+}
+
+// New returns a Bucket compressing the objects of b.
+func New(b absos.Bucket, opts ...Option) *Bucket {
+	c := &Bucket{Bucket: b, encoding: Gzip, compressedTypes: CompressedTypes}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ObjectPage lists a page of objects, which are read through b.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	page, err := b.Bucket.ObjectPage(ctx, prefix, delimiter, token)
+	if err != nil {
+		return nil, err
+	}
+	return &compressedPage{Page: page, b: b}, nil
+}
+
+// Head retrieves the metadata of an object, with the size of its
+// decompressed contents.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	raw, err := b.Bucket.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return b.header(key, raw)
+}
+
+// header returns the header of the decompressed contents of the object
+// described by raw, or raw if it is not compressed.
+func (b *Bucket) header(key string, raw absos.ObjectHeader) (absos.ObjectHeader, error) {
+	h, err := newHeader(raw)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	if h == nil {
+		return raw, nil
+	}
+	return h, nil
+}
+
+// PutBatch compresses and uploads the objects of iter one at a time.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	return absos.PutEach(ctx, b, iter)
+}
+
+// Put compresses and uploads an object. The compressed contents are
+// streamed to the wrapped bucket with an absos.Writer.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	options := absos.NewPutOptions(opts...)
+
+	encoding := b.encodingOf(key, options)
+	if encoding == Identity {
+		return b.Bucket.Put(ctx, key, data, opts...)
+	}
+
+	checksums, err := describe(data, encoding, &options)
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	w := absos.NewWriter(ctx, b.Bucket, key, func(o *absos.PutOptions) { *o = options })
+	if err := compress(w, data, encoding, checksums); err != nil {
+		_ = w.CloseWithError(err)
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	return w.Close()
+}
+
+// describe adds the encoding and the remaining length of data to the
+// metadata of options, and removes and returns their checksums, which
+// compress verifies against data.
+func describe(data io.ReadSeeker, encoding string, options *absos.PutOptions) (absos.Checksums, error) {
+	size, err := measure.Remaining(data)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := options.Checksums
+	options.Checksums = nil
+	metadata := make(map[string]string, len(options.Metadata)+2)
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataEncoding] = encoding
+	metadata[MetadataSize] = strconv.FormatInt(size, 10)
+	options.Metadata = metadata
+
+	return checksums, nil
+}
+
+// encodingOf returns the encoding of an object stored with options.
+func (b *Bucket) encodingOf(key string, options absos.PutOptions) string {
+	if options.ContentEncoding != "" || b.compressed(options.ContentType) {
+		return Identity
+	}
+
+	for _, r := range b.rules {
+		if ok, _ := path.Match(r.pattern, key); ok {
+			return r.encoding
+		}
+	}
+	return b.encoding
+}
+
+// compressed reports whether contentType is the type of compressed data.
+func (b *Bucket) compressed(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || uncompressedImages[mediaType] {
+		return false
+	}
+
+	for _, t := range b.compressedTypes {
+		if family, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, family+"/") {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// compress writes the contents of data compressed with encoding to w,
+// verifying checksums against them.
+func compress(w io.Writer, data io.Reader, encoding string, checksums absos.Checksums) error {
+	var enc io.WriteCloser
+	switch encoding {
+	case Gzip:
+		enc = gzip.NewWriter(w)
+	case Zstd:
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		enc = zw
+	default:
+		return fmt.Errorf("%w: encoding %q", absos.ErrNotSupported, encoding)
+	}
+
+	var hasher *absos.ChecksumHasher
+	if len(checksums) > 0 {
+		var algorithms []absos.ChecksumAlgorithm
+		for a := range checksums {
+			algorithms = append(algorithms, a)
+		}
+		hasher = absos.NewChecksumHasher(algorithms...)
+		data = io.TeeReader(data, hasher)
+	}
+
+	if _, err := io.Copy(enc, data); err != nil {
+		_ = enc.Close()
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if hasher != nil {
+		return checksums.Verify(hasher.Sum())
+	}
+	return nil
+}
+
+// Get retrieves and decompresses an object. Objects that were not
+// compressed are returned as is.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	raw, err := b.Bucket.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.open(key, raw, func() (io.ReadCloser, error) {
+		return absos.GetIf(ctx, b.Bucket, key, absos.Conditions{IfMatch: raw.ETag()})
+	})
+}
+
+// open decompresses the object described by raw, whose stored contents are
+// returned by get.
+func (b *Bucket) open(key string, raw absos.ObjectHeader, get func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	h, err := newHeader(raw)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	body, err := get()
+	if err != nil || h == nil {
+		return body, err
+	}
+
+	r, err := decompress(body, h.encoding)
+	if err != nil {
+		body.Close()
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	return &objectReader{ReadCloser: r, bucket: b.Name(), key: key, remaining: h.size}, nil
+}
+
+// decompress returns a reader decompressing body.
+func decompress(body io.ReadCloser, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case Gzip:
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressReader{Reader: zr, body: body, close: zr.Close}, nil
+	case Zstd:
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &decompressReader{Reader: zr, body: body, close: func() error { zr.Close(); return nil }}, nil
+	}
+	return nil, fmt.Errorf("%w: encoding %q", absos.ErrNotSupported, encoding)
+}
+
+// decompressReader closes a decompressor along with the body it reads.
+type decompressReader struct {
+	io.Reader
+	body  io.Closer
+	close func() error
+}
+
+func (r *decompressReader) Close() error {
+	err := r.close()
+	if cerr := r.body.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// objectReader checks that the decompressed contents of an object have the
+// recorded size, and wraps errors in an *absos.ObjectError.
+type objectReader struct {
+	io.ReadCloser
+	bucket, key string
+	remaining   int64
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+
+	switch {
+	case err == io.EOF && r.remaining != 0:
+		err = errors.New("decompressed size differs from the recorded size")
+	case err == nil || err == io.EOF:
+		return n, err
+	}
+	return n, &absos.ObjectError{Bucket: r.bucket, Key: r.key, Err: err}
+}
+
+// header is the header of the decompressed contents of an object.
+type header struct {
+	absos.ObjectHeader
+	encoding string
+	size     int64
+	metadata map[string]string
+}
+
+// newHeader returns the header of the decompressed contents of the object
+// described by raw, or nil if it is not compressed.
+func newHeader(raw absos.ObjectHeader) (*header, error) {
+	metadata := raw.Metadata()
+	encoding := metadata[MetadataEncoding]
+	if encoding == "" {
+		return nil, nil
+	}
+	if encoding != Gzip && encoding != Zstd {
+		return nil, fmt.Errorf("%w: encoding %q", absos.ErrNotSupported, encoding)
+	}
+
+	size, err := strconv.ParseInt(metadata[MetadataSize], 10, 64)
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid %s %q", MetadataSize, metadata[MetadataSize])
+	}
+
+	h := &header{ObjectHeader: raw, encoding: encoding, size: size}
+	for k, v := range metadata {
+		if k == MetadataEncoding || k == MetadataSize {
+			continue
+		}
+		if h.metadata == nil {
+			h.metadata = make(map[string]string)
+		}
+		h.metadata[k] = v
+	}
+	return h, nil
+}
+
+func (h *header) Size() int64                 { return h.size }
+func (h *header) Metadata() map[string]string { return h.metadata }
+func (h *header) Checksums() absos.Checksums  { return nil }
+
+// compressedPage reads the objects of a page through a Bucket.
+type compressedPage struct {
+	absos.Page
+	b *Bucket
+}
+
+func (p *compressedPage) Objects() []absos.Object {
+	objects := p.Page.Objects()
+	wrapped := make([]absos.Object, len(objects))
+	for i, obj := range objects {
+		wrapped[i] = &object{Object: obj, b: p.b}
+	}
+	return wrapped
+}
+
+// object is an object of a page read through a Bucket. Its size is that of
+// its decompressed contents when the listing includes the user metadata of
+// objects, as the memory and file stores do, and of its stored contents
+// otherwise.
+type object struct {
+	absos.Object
+	b *Bucket
+}
+
+var _ absos.RangeObject = (*object)(nil)
+
+func (o *object) Size() int64 {
+	if m, ok := o.Object.(interface{ Metadata() map[string]string }); ok {
+		if size, err := strconv.ParseInt(m.Metadata()[MetadataSize], 10, 64); err == nil && size >= 0 {
+			return size
+		}
+	}
+	return o.Object.Size()
+}
+
+func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
+	return o.b.Head(ctx, o.Key())
+}
+
+func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
+	return o.b.Get(ctx, o.Key())
+}
+
+// OpenRange reads part of the decompressed contents of the object, whose
+// size is read with Head.
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return absos.GetRange(ctx, o.b, o.Key(), offset, length)
+}
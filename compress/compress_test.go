@@ -0,0 +1,338 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// newTestBucket returns a compressing bucket of a memory store, and the
+// wrapped bucket.
+func newTestBucket(t *testing.T, opts ...Option) (*Bucket, absos.Bucket) {
+	t.Helper()
+
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	return New(buckets[0], opts...), buckets[0]
+}
+
+// readAll reads and closes the reader returned by Get or Open.
+func readAll(r io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	b, backend := newTestBucket(t, WithEncoding("logs/*", Zstd))
+	ctx := context.Background()
+
+	data := []byte(strings.Repeat(`{"level":"info","msg":"request served"}`+"\n", 1000))
+
+	for key, encoding := range map[string]string{"data.json": Gzip, "logs/app.log": Zstd} {
+		err := b.Put(ctx, key, bytes.NewReader(data),
+			absos.WithContentType("application/json"),
+			absos.WithMetadata(map[string]string{"owner": "alice"}))
+		if err != nil {
+			t.Fatalf("failed to put %s: %v", key, err)
+		}
+
+		raw, err := backend.Head(ctx, key)
+		if err != nil {
+			t.Fatalf("failed to head: %v", err)
+		}
+		if raw.Metadata()[MetadataEncoding] != encoding || raw.Size() >= int64(len(data))/10 {
+			t.Errorf("%s: expected %s compressed contents, got %d bytes with %v", key, encoding, raw.Size(), raw.Metadata())
+		}
+
+		header, err := b.Head(ctx, key)
+		if err != nil {
+			t.Fatalf("failed to head: %v", err)
+		}
+		if header.Size() != int64(len(data)) {
+			t.Errorf("%s: expected size %d, got %d", key, len(data), header.Size())
+		}
+		if len(header.Metadata()) != 1 || header.Metadata()["owner"] != "alice" || header.MimeType() != "application/json" {
+			t.Errorf("%s: unexpected header %v %q", key, header.Metadata(), header.MimeType())
+		}
+
+		got, err := readAll(b.Get(ctx, key))
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", key, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: decompressed contents differ", key)
+		}
+
+		got, err = readAll(absos.GetRange(ctx, b, key, 10, 20))
+		if err != nil || !bytes.Equal(got, data[10:30]) {
+			t.Errorf("%s: range differs: %v", key, err)
+		}
+	}
+}
+
+func TestStoredAsIs(t *testing.T) {
+	b, backend := newTestBucket(t, WithEncoding("raw/*", Identity))
+	ctx := context.Background()
+
+	tests := map[string][]absos.PutOption{
+		"photo.jpg":      {absos.WithContentType("image/jpeg")},
+		"archive.zip":    {absos.WithContentType("application/zip")},
+		"page.html.gz":   {absos.WithContentType("text/html"), absos.WithContentEncoding("gzip")},
+		"raw/data.json":  {absos.WithContentType("application/json")},
+		"logo.svg":       {absos.WithContentType("image/svg+xml; charset=utf-8")},
+		"unknown.binary": nil,
+	}
+
+	for key, opts := range tests {
+		if err := b.Put(ctx, key, strings.NewReader("contents"), opts...); err != nil {
+			t.Fatalf("failed to put %s: %v", key, err)
+		}
+
+		raw, err := backend.Head(ctx, key)
+		if err != nil {
+			t.Fatalf("failed to head: %v", err)
+		}
+		compressed := raw.Metadata()[MetadataEncoding] != ""
+		if expected := key == "logo.svg" || key == "unknown.binary"; compressed != expected {
+			t.Errorf("%s: expected compressed %v, got %v", key, expected, compressed)
+		}
+
+		got, err := readAll(b.Get(ctx, key))
+		if err != nil || string(got) != "contents" {
+			t.Errorf("%s: expected contents, got %q: %v", key, got, err)
+		}
+	}
+}
+
+func TestPutChecksumMismatch(t *testing.T) {
+	b, backend := newTestBucket(t)
+	ctx := context.Background()
+
+	err := b.Put(ctx, "key", strings.NewReader("data"),
+		absos.WithChecksum(absos.ChecksumSHA256, absos.ComputeChecksums([]byte("other"))[absos.ChecksumSHA256]))
+	if !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	if _, err := backend.Head(ctx, "key"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected the object not to be stored, got %v", err)
+	}
+}
+
+func TestObjectPage(t *testing.T) {
+	b, _ := newTestBucket(t, WithDefaultEncoding(Zstd))
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "dir/key", strings.NewReader("contents")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	page, err := b.ObjectPage(ctx, "dir/", "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	obj := page.Objects()[0]
+	if got, err := readAll(obj.Open(ctx)); err != nil || string(got) != "contents" {
+		t.Errorf("expected contents, got %q: %v", got, err)
+	}
+
+	header, err := obj.Head(ctx)
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	if header.Size() != 8 {
+		t.Errorf("expected size 8, got %d", header.Size())
+	}
+}
+
+func TestObjectRange(t *testing.T) {
+	b, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	data := []byte(strings.Repeat("0123456789", 1000))
+	if err := b.Put(ctx, "key", bytes.NewReader(data)); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	page, err := b.ObjectPage(ctx, "", "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	obj := page.Objects()[0]
+
+	if obj.Size() != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), obj.Size())
+	}
+
+	tests := []struct {
+		offset, length int64
+		expected       string
+	}{
+		{-5, 0, "56789"},
+		{500, 10, "0123456789"},
+		{9995, -1, "56789"},
+	}
+	for _, tt := range tests {
+		got, err := readAll(absos.OpenRange(ctx, obj, tt.offset, tt.length))
+		if err != nil || string(got) != tt.expected {
+			t.Errorf("OpenRange(%d, %d): expected %q, got %q: %v", tt.offset, tt.length, tt.expected, got, err)
+		}
+	}
+
+	if _, err := absos.OpenRange(ctx, obj, 10000, 1); !errors.Is(err, absos.ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	b, backend := newTestBucket(t)
+	ctx := context.Background()
+
+	data := strings.Repeat("compressible ", 100)
+
+	cond := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader(data), cond); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("other"), cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	raw, err := backend.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	if raw.Metadata()[MetadataEncoding] != Gzip {
+		t.Errorf("expected gzip compressed contents, got %v", raw.Metadata())
+	}
+
+	header, err := absos.HeadIf(ctx, b, "key", absos.Conditions{IfMatch: raw.ETag()})
+	if err != nil || header.Size() != int64(len(data)) {
+		t.Fatalf("expected %d bytes, got %v", len(data), err)
+	}
+	if got, err := readAll(absos.GetIf(ctx, b, "key", absos.Conditions{IfMatch: raw.ETag()})); err != nil || string(got) != data {
+		t.Errorf("unexpected contents: %v", err)
+	}
+
+	// Copies keep the compressed contents, unless their metadata is replaced
+	if err := absos.Copy(ctx, b, "key", b, "copy"); err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	if copied, err := backend.Head(ctx, "copy"); err != nil || !bytes.Equal(copied.ETag(), raw.ETag()) {
+		t.Errorf("expected the copy to keep the compressed contents: %v", err)
+	}
+
+	err = absos.Copy(ctx, b, "key", b, "replaced", absos.WithReplacedMetadata(absos.WithContentType("text/plain")))
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	for _, key := range []string{"copy", "replaced"} {
+		if got, err := readAll(b.Get(ctx, key)); err != nil || string(got) != data {
+			t.Errorf("%s: unexpected contents: %v", key, err)
+		}
+	}
+
+	if err := absos.DeleteMany(ctx, b, []string{"copy", "replaced"}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := b.Head(ctx, "copy"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	// Presigned URLs would give access to the compressed bytes
+	if _, ok := absos.Bucket(b).(absos.Presigner); ok {
+		t.Error("expected Bucket not to presign URLs")
+	}
+
+	if err := b.SetVersioning(ctx, absos.VersioningEnabled); err != nil {
+		t.Fatalf("failed to enable versioning: %v", err)
+	}
+	if err := b.Put(ctx, "key", strings.NewReader("newer")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	page, err := b.ListVersions(ctx, "key", "")
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	versions := page.Versions()
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+	if got, err := readAll(b.GetVersion(ctx, "key", versions[1].VersionID)); err != nil || string(got) != data {
+		t.Errorf("unexpected contents of the previous version: %v", err)
+	}
+	if header, err := b.HeadVersion(ctx, "key", versions[1].VersionID); err != nil || header.Size() != int64(len(data)) {
+		t.Errorf("expected %d bytes, got %v", len(data), err)
+	}
+}
+
+func TestSizeMismatch(t *testing.T) {
+	b, backend := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "key", strings.NewReader("contents")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	raw, err := backend.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	body, err := readAll(backend.Get(ctx, "key"))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	options := absos.HeaderOptions(raw)
+	options.Metadata[MetadataSize] = "100"
+	if err := backend.Put(ctx, "key", bytes.NewReader(body), func(o *absos.PutOptions) { *o = options }); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	var oe *absos.ObjectError
+	if _, err := readAll(b.Get(ctx, "key")); !errors.As(err, &oe) {
+		t.Errorf("expected an *absos.ObjectError, got %v", err)
+	}
+}
+
+// compressingStore wraps the buckets of a store in buckets compressing every
+// object.
+type compressingStore struct {
+	absos.ObjectStore
+}
+
+func (s *compressingStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := s.ObjectStore.ListBuckets(ctx)
+	for i, b := range buckets {
+		buckets[i] = New(b, WithDefaultEncoding(Gzip))
+	}
+	return buckets, err
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return &compressingStore{ObjectStore: memory.NewStore()}
+	})
+}
@@ -0,0 +1,8 @@
+module github.com/absfs/absos/compress
+
+go 1.21
+
+require (
+	github.com/absfs/absos v0.2.0
+	github.com/klauspost/compress v1.17.9
+)
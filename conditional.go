@@ -0,0 +1,170 @@
+package absos
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+)
+
+// Conditions holds the preconditions of a conditional request, modeled on
+// the HTTP If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since
+// headers. ETags are compared with the value returned by ObjectHeader.ETag,
+// and times with a resolution of one second. Zero fields are not checked.
+type Conditions struct {
+	// IfMatch requires the object to exist with this ETag.
+	IfMatch []byte
+
+	// IfNoneMatch requires the object not to have this ETag.
+	IfNoneMatch []byte
+
+	// IfNotExists requires the object not to exist (If-None-Match: *).
+	// Combined with a write, it creates an object only once.
+	IfNotExists bool
+
+	// IfModifiedSince requires the object to have been modified after this
+	// time. It is only checked by reads, and not when IfNoneMatch is set.
+	IfModifiedSince time.Time
+
+	// IfUnmodifiedSince requires the object not to have been modified after
+	// this time. It is not checked when IfMatch is set.
+	IfUnmodifiedSince time.Time
+}
+
+// CheckRead evaluates the conditions of a Get or Head against the header of
+// the object, which must exist. It returns ErrNotModified if the object
+// matches IfNoneMatch or IfNotExists, or was not modified since
+// IfModifiedSince, and ErrPreconditionFailed for the other conditions.
+func (c Conditions) CheckRead(header ObjectHeader) error {
+	if err := c.checkMatch(header); err != nil {
+		return err
+	}
+
+	if c.IfNotExists || c.IfNoneMatch != nil && bytes.Equal(header.ETag(), c.IfNoneMatch) {
+		return ErrNotModified
+	}
+
+	if c.IfNoneMatch == nil && !c.IfModifiedSince.IsZero() && !modifiedAfter(header.ModTime(), c.IfModifiedSince) {
+		return ErrNotModified
+	}
+
+	return nil
+}
+
+// CheckWrite evaluates the conditions of a Put or Delete against the header
+// of the object, which is nil if the object does not exist. It returns
+// ErrPreconditionFailed if any condition is not met. IfModifiedSince is
+// ignored.
+func (c Conditions) CheckWrite(header ObjectHeader) error {
+	if header == nil {
+		if c.IfMatch != nil {
+			return ErrPreconditionFailed
+		}
+		return nil
+	}
+
+	if err := c.checkMatch(header); err != nil {
+		return err
+	}
+
+	if c.IfNotExists || c.IfNoneMatch != nil && bytes.Equal(header.ETag(), c.IfNoneMatch) {
+		return ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// checkMatch evaluates IfMatch and IfUnmodifiedSince against an existing object.
+func (c Conditions) checkMatch(header ObjectHeader) error {
+	if c.IfMatch != nil {
+		if !bytes.Equal(header.ETag(), c.IfMatch) {
+			return ErrPreconditionFailed
+		}
+	} else if !c.IfUnmodifiedSince.IsZero() && modifiedAfter(header.ModTime(), c.IfUnmodifiedSince) {
+		return ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// modifiedAfter compares times at the one second resolution of HTTP dates.
+func modifiedAfter(modTime, t time.Time) bool {
+	return modTime.Truncate(time.Second).After(t.Truncate(time.Second))
+}
+
+// ConditionalBucket is implemented by buckets that can evaluate Conditions
+// atomically with the operation they guard, which makes them suitable for
+// optimistic concurrency control between writers.
+//
+// Failed conditions are reported as an *ObjectError wrapping
+// ErrPreconditionFailed or ErrNotModified, as described for
+// Conditions.CheckRead and Conditions.CheckWrite.
+type ConditionalBucket interface {
+	Bucket
+
+	// PutIf stores the object like Put if the conditions hold.
+	PutIf(ctx context.Context, key string, data io.ReadSeeker, cond Conditions, opts ...PutOption) error
+
+	// GetIf retrieves the object like Get if the conditions hold.
+	GetIf(ctx context.Context, key string, cond Conditions) (io.ReadCloser, error)
+
+	// HeadIf retrieves the object metadata like Head if the conditions hold.
+	HeadIf(ctx context.Context, key string, cond Conditions) (ObjectHeader, error)
+
+	// DeleteIf removes the object like Delete if the conditions hold.
+	// Missing objects are reported as ErrObjectNotFound, as by Delete.
+	DeleteIf(ctx context.Context, key string, cond Conditions) error
+}
+
+// PutIf stores an object in b if cond holds. Conditional writes cannot be
+// emulated safely, so it returns ErrNotSupported unless b implements
+// ConditionalBucket.
+func PutIf(ctx context.Context, b Bucket, key string, data io.ReadSeeker, cond Conditions, opts ...PutOption) error {
+	if cb, ok := b.(ConditionalBucket); ok {
+		return cb.PutIf(ctx, key, data, cond, opts...)
+	}
+	return &ObjectError{Bucket: b.Name(), Key: key, Err: ErrNotSupported}
+}
+
+// DeleteIf removes an object from b if cond holds. Like PutIf, it returns
+// ErrNotSupported unless b implements ConditionalBucket.
+func DeleteIf(ctx context.Context, b Bucket, key string, cond Conditions) error {
+	if cb, ok := b.(ConditionalBucket); ok {
+		return cb.DeleteIf(ctx, key, cond)
+	}
+	return &ObjectError{Bucket: b.Name(), Key: key, Err: ErrNotSupported}
+}
+
+// HeadIf retrieves the metadata of an object in b if cond holds. Buckets
+// that do not implement ConditionalBucket are checked with Head.
+func HeadIf(ctx context.Context, b Bucket, key string, cond Conditions) (ObjectHeader, error) {
+	if cb, ok := b.(ConditionalBucket); ok {
+		return cb.HeadIf(ctx, key, cond)
+	}
+
+	header, err := b.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cond.CheckRead(header); err != nil {
+		return nil, &ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	return header, nil
+}
+
+// GetIf retrieves an object from b if cond holds. Buckets that do not
+// implement ConditionalBucket are checked with Head before Get, so the
+// object may change in between.
+func GetIf(ctx context.Context, b Bucket, key string, cond Conditions) (io.ReadCloser, error) {
+	if cb, ok := b.(ConditionalBucket); ok {
+		return cb.GetIf(ctx, key, cond)
+	}
+
+	if _, err := HeadIf(ctx, b, key, cond); err != nil {
+		return nil, err
+	}
+
+	return b.Get(ctx, key)
+}
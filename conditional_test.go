@@ -0,0 +1,89 @@
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+func TestConditionsCheckWrite(t *testing.T) {
+	ctx := context.Background()
+	b := newStreamBucket(t)
+
+	if err := b.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	header, err := b.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+	etag, modTime := header.ETag(), header.ModTime()
+
+	tests := []struct {
+		name   string
+		header absos.ObjectHeader
+		cond   absos.Conditions
+		want   error
+	}{
+		{"None", header, absos.Conditions{}, nil},
+		{"Match", header, absos.Conditions{IfMatch: etag}, nil},
+		{"NoMatch", header, absos.Conditions{IfMatch: []byte("other")}, absos.ErrPreconditionFailed},
+		{"MatchMissing", nil, absos.Conditions{IfMatch: etag}, absos.ErrPreconditionFailed},
+		{"NoneMatch", header, absos.Conditions{IfNoneMatch: etag}, absos.ErrPreconditionFailed},
+		{"NotExists", header, absos.Conditions{IfNotExists: true}, absos.ErrPreconditionFailed},
+		{"NotExistsMissing", nil, absos.Conditions{IfNotExists: true}, nil},
+		{"Unmodified", header, absos.Conditions{IfUnmodifiedSince: modTime}, nil},
+		{"Modified", header, absos.Conditions{IfUnmodifiedSince: modTime.Add(-time.Second)}, absos.ErrPreconditionFailed},
+		{"ModifiedSinceIgnored", header, absos.Conditions{IfModifiedSince: modTime}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cond.CheckWrite(tt.header); err != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConditionalFallback(t *testing.T) {
+	ctx := context.Background()
+	b := plainBucket{newStreamBucket(t)}
+
+	if err := b.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	header, err := b.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	// Reads are checked with Head
+	rc, err := absos.GetIf(ctx, b, "key", absos.Conditions{IfMatch: header.ETag()})
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	rc.Close()
+
+	_, err = absos.GetIf(ctx, b, "key", absos.Conditions{IfNoneMatch: header.ETag()})
+	if !errors.Is(err, absos.ErrNotModified) {
+		t.Errorf("expected ErrNotModified, got %v", err)
+	}
+
+	// Writes cannot be emulated atomically
+	err = absos.PutIf(ctx, b, "key", strings.NewReader("new"), absos.Conditions{IfNotExists: true})
+	if !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+
+	err = absos.DeleteIf(ctx, b, "key", absos.Conditions{IfMatch: header.ETag()})
+	if !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
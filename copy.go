@@ -0,0 +1,136 @@
+package absos
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// MetadataDirective selects the attributes of the object created by Copy.
+type MetadataDirective int
+
+const (
+	// MetadataCopy keeps the content headers and user metadata of the source
+	// object. It is the default. Like S3, providers may apply their default
+	// storage class and encryption to the copy.
+	MetadataCopy MetadataDirective = iota
+
+	// MetadataReplace replaces the attributes of the source object with
+	// CopyOptions.Replace.
+	MetadataReplace
+)
+
+// CopyOptions holds the options of Copy and Move.
+type CopyOptions struct {
+	// Directive selects whether the attributes of the source are kept.
+	Directive MetadataDirective
+
+	// Replace holds the attributes of the new object when Directive is
+	// MetadataReplace.
+	Replace PutOptions
+}
+
+// CopyOption configures Copy and Move.
+type CopyOption func(*CopyOptions)
+
+// NewCopyOptions returns the CopyOptions resulting from applying opts in order.
+func NewCopyOptions(opts ...CopyOption) CopyOptions {
+	var o CopyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithReplacedMetadata selects MetadataReplace, giving the new object the
+// attributes set by opts instead of those of the source.
+func WithReplacedMetadata(opts ...PutOption) CopyOption {
+	return func(o *CopyOptions) {
+		o.Directive = MetadataReplace
+		o.Replace = NewPutOptions(opts...)
+	}
+}
+
+// CopyBucket is implemented by buckets that can copy objects without
+// transferring their contents through the client.
+type CopyBucket interface {
+	Bucket
+
+	// Copy copies the object srcKey in src to dstKey in this bucket.
+	// It returns ErrNotSupported if src belongs to a store it cannot copy
+	// from, in which case the package-level Copy falls back to Get and Put.
+	Copy(ctx context.Context, src Bucket, srcKey, dstKey string, opts ...CopyOption) error
+}
+
+// Copy copies the object srcKey in src to dstKey in dst, which may be the
+// same bucket. It uses dst's CopyBucket implementation when available and
+// otherwise reads the object from src and uploads it to dst.
+func Copy(ctx context.Context, src Bucket, srcKey string, dst Bucket, dstKey string, opts ...CopyOption) error {
+	if cb, ok := dst.(CopyBucket); ok {
+		err := cb.Copy(ctx, src, srcKey, dstKey, opts...)
+		if !errors.Is(err, ErrNotSupported) {
+			return err
+		}
+	}
+
+	options := NewCopyOptions(opts...)
+	attributes := options.Replace
+
+	if options.Directive == MetadataCopy {
+		header, err := src.Head(ctx, srcKey)
+		if err != nil {
+			return err
+		}
+		attributes = HeaderOptions(header)
+	}
+
+	body, err := src.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return Upload(ctx, dst, dstKey, body, withOptions(attributes))
+}
+
+// Move moves the object srcKey in src to dstKey in dst by copying it and
+// deleting the source. Moving an object onto itself does nothing.
+//
+// Buckets are only known by name, so when src and dst have the same name
+// and the keys are equal, the source may be the destination and is never
+// deleted. The object is still copied unless src and dst are the same value.
+func Move(ctx context.Context, src Bucket, srcKey string, dst Bucket, dstKey string, opts ...CopyOption) error {
+	if src.Name() == dst.Name() && srcKey == dstKey {
+		if sameBucket(src, dst) {
+			_, err := src.Head(ctx, srcKey)
+			return err
+		}
+		return Copy(ctx, src, srcKey, dst, dstKey, opts...)
+	}
+
+	if err := Copy(ctx, src, srcKey, dst, dstKey, opts...); err != nil {
+		return err
+	}
+
+	return src.Delete(ctx, srcKey)
+}
+
+// sameBucket reports whether a and b are the same comparable value.
+func sameBucket(a, b Bucket) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// HeaderOptions returns the PutOptions that recreate the attributes of an
+// object described by header.
+func HeaderOptions(header ObjectHeader) PutOptions {
+	return PutOptions{
+		ContentType:        header.MimeType(),
+		ContentEncoding:    header.ContentEncoding(),
+		CacheControl:       header.CacheControl(),
+		ContentDisposition: header.ContentDisposition(),
+		Metadata:           header.Metadata(),
+		StorageClass:       header.StorageClass(),
+		SSE:                header.ServerSideEncryption(),
+	}
+}
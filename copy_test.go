@@ -0,0 +1,113 @@
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+)
+
+func TestCopyFallback(t *testing.T) {
+	ctx := context.Background()
+	native := newStreamBucket(t)
+	plain := plainBucket{newStreamBucket(t)}
+
+	if err := native.Put(ctx, "src", strings.NewReader("data"), absos.WithContentType("text/plain")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		src, dst absos.Bucket
+	}{
+		// The memory bucket cannot copy from a foreign bucket and reports
+		// ErrNotSupported, so Copy falls back to Get and Upload
+		{"NotSupported", plainBucket{native}, native},
+		{"NoCapability", native, plain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := absos.Copy(ctx, tt.src, "src", tt.dst, "dst"); err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+
+			if content := readObject(t, tt.dst, "dst"); string(content) != "data" {
+				t.Errorf("expected %q, got %q", "data", content)
+			}
+
+			header, err := tt.dst.Head(ctx, "dst")
+			if err != nil {
+				t.Fatalf("failed to head copy: %v", err)
+			}
+			if header.MimeType() != "text/plain" {
+				t.Errorf("expected MIME type text/plain, got %s", header.MimeType())
+			}
+		})
+	}
+}
+
+func TestCopyFallbackReplaceMetadata(t *testing.T) {
+	ctx := context.Background()
+	src := newStreamBucket(t)
+	dst := plainBucket{newStreamBucket(t)}
+
+	err := src.Put(ctx, "src", strings.NewReader("data"), absos.WithMetadata(map[string]string{"a": "1"}))
+	if err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	err = absos.Copy(ctx, src, "src", dst, "dst", absos.WithReplacedMetadata(absos.WithMetadata(map[string]string{"b": "2"})))
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+
+	header, err := dst.Head(ctx, "dst")
+	if err != nil {
+		t.Fatalf("failed to head copy: %v", err)
+	}
+	if m := header.Metadata(); len(m) != 1 || m["b"] != "2" {
+		t.Errorf("expected replaced metadata, got %v", m)
+	}
+}
+
+func TestMoveAcrossBuckets(t *testing.T) {
+	ctx := context.Background()
+	src := newStreamBucket(t)
+	dst := plainBucket{newStreamBucket(t)}
+
+	if err := src.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := absos.Move(ctx, src, "key", dst, "moved"); err != nil {
+		t.Fatalf("failed to move: %v", err)
+	}
+
+	if content := readObject(t, dst, "moved"); string(content) != "data" {
+		t.Errorf("expected %q, got %q", "data", content)
+	}
+
+	if _, err := src.Head(ctx, "key"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected source to be deleted, got %v", err)
+	}
+
+	// Buckets of the same name may be the same bucket, so the source is kept
+	if err := src.Put(ctx, "key", strings.NewReader("same")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := absos.Move(ctx, src, "key", dst, "key"); err != nil {
+		t.Fatalf("failed to move: %v", err)
+	}
+
+	if content := readObject(t, dst, "key"); string(content) != "same" {
+		t.Errorf("expected %q, got %q", "same", content)
+	}
+
+	if _, err := src.Head(ctx, "key"); err != nil {
+		t.Errorf("expected source to be kept, got %v", err)
+	}
+}
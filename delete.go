@@ -0,0 +1,216 @@
+package absos
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultDeleteConcurrency is the number of batches DeleteMany deletes at
+// the same time unless WithDeleteConcurrency is used.
+const DefaultDeleteConcurrency = 8
+
+// BulkDeleteBucket is implemented by buckets that can delete several objects
+// with a single request.
+type BulkDeleteBucket interface {
+	Bucket
+
+	// DeleteLimit returns the maximum number of keys accepted by DeleteMany,
+	// such as 1000 for S3. Zero means there is no limit.
+	DeleteLimit() int
+
+	// DeleteMany deletes the objects with the given keys. Keys without an
+	// object are not reported as failures. Objects that could not be
+	// deleted are reported in a *BatchError; any other error, such as a
+	// missing bucket, applies to all keys.
+	DeleteMany(ctx context.Context, keys []string) error
+}
+
+// DeleteOptions holds the options of DeleteMany and DeletePrefix.
+type DeleteOptions struct {
+	// Concurrency is the number of batches deleted at the same time.
+	Concurrency int
+}
+
+// DeleteOption configures DeleteMany and DeletePrefix.
+type DeleteOption func(*DeleteOptions)
+
+// WithDeleteConcurrency sets the number of batches deleted at the same time.
+func WithDeleteConcurrency(n int) DeleteOption {
+	return func(o *DeleteOptions) {
+		if n > 0 {
+			o.Concurrency = n
+		}
+	}
+}
+
+// DeleteMany deletes the objects with the given keys from b. Buckets
+// implementing BulkDeleteBucket receive the keys in batches of up to their
+// DeleteLimit; other buckets delete each key with Delete. Batches are
+// deleted concurrently.
+//
+// Like S3's DeleteObjects, keys without an object are not failures. Keys
+// that could not be deleted are reported in a *BatchError in the order they
+// were given. A missing bucket or a canceled context stops the deletion and
+// is returned as a *BucketError.
+func DeleteMany(ctx context.Context, b Bucket, keys []string, opts ...DeleteOption) error {
+	options := DeleteOptions{Concurrency: DefaultDeleteConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	deleteBatch, size := deleteEach(b), 1
+	if bb, ok := b.(BulkDeleteBucket); ok {
+		deleteBatch, size = bb.DeleteMany, bb.DeleteLimit()
+		if size <= 0 {
+			size = len(keys)
+		}
+	}
+
+	batches := (len(keys) + size - 1) / size
+	batch := func(i int) []string {
+		return keys[i*size : min((i+1)*size, len(keys))]
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		stopped error
+		results = make([]error, batches)
+		next    = make(chan int)
+	)
+
+	for w := 0; w < min(options.Concurrency, batches); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				err := deleteBatch(ctx, batch(i))
+
+				// Errors of the whole bucket stop the remaining batches
+				var bucketErr *BucketError
+				if errors.As(err, &bucketErr) {
+					mu.Lock()
+					if stopped == nil {
+						stopped = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+
+				results[i] = err
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < batches; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if stopped != nil {
+		return stopped
+	}
+	if ctx.Err() != nil {
+		return &BucketError{Bucket: b.Name(), Err: ctx.Err()}
+	}
+
+	var failed []*ObjectError
+	for i, err := range results {
+		var batchErr *BatchError
+		switch {
+		case err == nil:
+		case errors.As(err, &batchErr):
+			failed = append(failed, batchErr.Errors...)
+		default:
+			for _, key := range batch(i) {
+				failed = append(failed, &ObjectError{Bucket: b.Name(), Key: key, Err: err})
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{Bucket: b.Name(), Errors: failed}
+	}
+
+	return nil
+}
+
+// deleteEach returns a function deleting keys one by one with b.Delete,
+// with the semantics of BulkDeleteBucket.DeleteMany.
+func deleteEach(b Bucket) func(ctx context.Context, keys []string) error {
+	return func(ctx context.Context, keys []string) error {
+		var failed []*ObjectError
+		for _, key := range keys {
+			err := b.Delete(ctx, key)
+			if err == nil || errors.Is(err, ErrObjectNotFound) {
+				continue
+			}
+
+			var bucketErr *BucketError
+			if errors.As(err, &bucketErr) {
+				return err
+			}
+			failed = append(failed, objectError(b.Name(), key, err))
+		}
+
+		if len(failed) > 0 {
+			return &BatchError{Bucket: b.Name(), Errors: failed}
+		}
+
+		return nil
+	}
+}
+
+// DeletePrefix deletes every object in b whose key begins with prefix. Each
+// page of the listing is deleted with DeleteMany while the next page is
+// fetched. Failures are reported as by DeleteMany; an empty prefix deletes
+// all objects of the bucket.
+func DeletePrefix(ctx context.Context, b Bucket, prefix string, opts ...DeleteOption) error {
+	p := newPager(ctx, b, prefix, "")
+	defer p.close()
+
+	var failed []*ObjectError
+	for {
+		page, err := p.next()
+		if err != nil {
+			return err
+		}
+		if page == nil {
+			break
+		}
+
+		objects := page.Objects()
+		keys := make([]string, len(objects))
+		for i, obj := range objects {
+			keys[i] = obj.Key()
+		}
+
+		err = DeleteMany(ctx, b, keys, opts...)
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) {
+			failed = append(failed, batchErr.Errors...)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{Bucket: b.Name(), Errors: failed}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/absfs/absos"
+)
+
+// batchBucket deletes at most limit keys per call and records the batches.
+type batchBucket struct {
+	absos.BulkDeleteBucket
+	limit int
+
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (b *batchBucket) DeleteLimit() int { return b.limit }
+
+func (b *batchBucket) DeleteMany(ctx context.Context, keys []string) error {
+	b.mu.Lock()
+	b.batches = append(b.batches, keys)
+	b.mu.Unlock()
+	return b.BulkDeleteBucket.DeleteMany(ctx, keys)
+}
+
+// failingDeleteBucket fails to delete the keys in failures.
+type failingDeleteBucket struct {
+	absos.Bucket
+	failures map[string]error
+}
+
+func (b failingDeleteBucket) Delete(ctx context.Context, key string) error {
+	if err, ok := b.failures[key]; ok {
+		return err
+	}
+	return b.Bucket.Delete(ctx, key)
+}
+
+func TestDeleteManyBatches(t *testing.T) {
+	ctx := context.Background()
+
+	var keys []string
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+	native, ok := newListBucket(t, keys...).(absos.BulkDeleteBucket)
+	if !ok {
+		t.Fatal("expected the memory bucket to support bulk deletes")
+	}
+	b := &batchBucket{BulkDeleteBucket: native, limit: 3}
+
+	if err := absos.DeleteMany(ctx, b, append(keys, "missing"), absos.WithDeleteConcurrency(2)); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	if len(b.batches) != 4 {
+		t.Errorf("expected 4 batches, got %v", b.batches)
+	}
+	for _, batch := range b.batches {
+		if len(batch) > 3 {
+			t.Errorf("expected at most 3 keys per batch, got %v", batch)
+		}
+	}
+
+	page, err := b.ObjectPage(ctx, "", "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(page.Objects()) != 0 {
+		t.Errorf("expected no objects, got %d", len(page.Objects()))
+	}
+}
+
+func TestDeleteManyFailures(t *testing.T) {
+	ctx := context.Background()
+	b := failingDeleteBucket{
+		Bucket: plainBucket{newListBucket(t, "a", "b", "c", "d")},
+		failures: map[string]error{
+			"b": &absos.ObjectError{Bucket: "test-bucket", Key: "b", Err: absos.ErrPermissionDenied},
+			"d": errors.New("connection reset"),
+		},
+	}
+
+	err := absos.DeleteMany(ctx, b, []string{"a", "b", "c", "d", "missing"}, absos.WithDeleteConcurrency(4))
+
+	var batchErr *absos.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected *absos.BatchError, got %v", err)
+	}
+
+	// Failures are reported in the order of the keys
+	var failed []string
+	for _, e := range batchErr.Errors {
+		failed = append(failed, e.Key)
+	}
+	if strings.Join(failed, ",") != "b,d" {
+		t.Errorf("expected failures for b and d, got %v", failed)
+	}
+	if !errors.Is(err, absos.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied in %v", err)
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, err := b.Head(ctx, key); !errors.Is(err, absos.ErrObjectNotFound) {
+			t.Errorf("expected %s to be deleted, got %v", key, err)
+		}
+	}
+}
+
+func TestDeleteManyBucketError(t *testing.T) {
+	ctx := context.Background()
+	missing := &absos.BucketError{Bucket: "test-bucket", Err: absos.ErrBucketNotFound}
+	b := failingDeleteBucket{
+		Bucket:   plainBucket{newListBucket(t)},
+		failures: map[string]error{"a": missing, "b": missing},
+	}
+
+	err := absos.DeleteMany(ctx, b, []string{"a", "b"})
+
+	var bucketErr *absos.BucketError
+	if !errors.As(err, &bucketErr) || !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected a *absos.BucketError for the missing bucket, got %v", err)
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	b := failingDeleteBucket{
+		Bucket:   plainBucket{newListBucket(t, walkKeys...)},
+		failures: map[string]error{"b/c/3": absos.ErrPermissionDenied},
+	}
+
+	err := absos.DeletePrefix(ctx, b, "b/")
+
+	var batchErr *absos.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "b/c/3" {
+		t.Fatalf("expected a failure for b/c/3, got %v", err)
+	}
+
+	var keys []string
+	err = absos.Walk(ctx, b, "", "", func(key string, obj absos.Object) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk: %v", err)
+	}
+
+	if strings.Join(keys, ",") != "a,b/c/3,d,e/4,f" {
+		t.Errorf("expected the other objects to remain, got %v", keys)
+	}
+}
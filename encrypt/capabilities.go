@@ -0,0 +1,196 @@
+package encrypt
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/capability"
+)
+
+var (
+	_ absos.ConditionalBucket = (*Bucket)(nil)
+	_ absos.CopyBucket        = (*Bucket)(nil)
+	_ absos.VersionedBucket   = (*Bucket)(nil)
+	_ absos.BulkDeleteBucket  = (*Bucket)(nil)
+)
+
+// PutIf encrypts and uploads an object with a new data key if cond holds.
+// The ETags of the conditions are those of the encrypted objects, as
+// reported by Head. It fails with absos.ErrNotSupported if the wrapped
+// bucket does not support conditional writes.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	options := absos.NewPutOptions(opts...)
+
+	body, err := b.encrypt(ctx, data, &options)
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	return absos.PutIf(ctx, b.Bucket, key, body, cond, func(o *absos.PutOptions) { *o = options })
+}
+
+// GetIf retrieves and decrypts an object if cond holds.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	raw, err := absos.HeadIf(ctx, b.Bucket, key, cond)
+	raw, h, err := b.plainHeader(key, raw, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.open(ctx, raw, h, func() (io.ReadCloser, error) {
+		return absos.GetIf(ctx, b.Bucket, key, absos.Conditions{IfMatch: raw.ETag()})
+	})
+}
+
+// HeadIf retrieves the metadata of an object if cond holds, without the
+// entries describing its encryption.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	raw, err := absos.HeadIf(ctx, b.Bucket, key, cond)
+	_, h, err := b.plainHeader(key, raw, err)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// DeleteIf removes an object if cond holds. It fails with
+// absos.ErrNotSupported if the wrapped bucket does not support conditional
+// writes.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	return absos.DeleteIf(ctx, b.Bucket, key, cond)
+}
+
+// Copy copies an encrypted object without decrypting it, keeping its data
+// key. It fails with absos.ErrNotSupported unless src is a Bucket with the
+// same KeyProvider, the attributes of the source are kept, and the wrapped
+// bucket can copy from the bucket wrapped by src, so that absos.Copy falls
+// back to decrypting the object and encrypting it again.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	s, ok := src.(*Bucket)
+	if !ok || !b.sameKeys(s) || absos.NewCopyOptions(opts...).Directive != absos.MetadataCopy {
+		return &absos.ObjectError{Bucket: b.Name(), Key: dstKey, Err: absos.ErrNotSupported}
+	}
+
+	cb, err := capability.As[absos.CopyBucket](b.Bucket, dstKey)
+	if err != nil {
+		return err
+	}
+	return cb.Copy(ctx, s.Bucket, srcKey, dstKey, opts...)
+}
+
+// sameKeys reports whether the data keys of the objects of src are unwrapped
+// by the KeyProvider of b.
+func (b *Bucket) sameKeys(src *Bucket) bool {
+	t := reflect.TypeOf(b.keys)
+	return t != nil && t.Comparable() && t == reflect.TypeOf(src.keys) && b.keys == src.keys
+}
+
+// DeleteLimit returns the number of keys the wrapped bucket deletes at
+// once, or 1 if it deletes them one by one.
+func (b *Bucket) DeleteLimit() int {
+	return capability.DeleteLimit(b.Bucket)
+}
+
+// DeleteMany deletes the objects with the given keys from the wrapped
+// bucket. Deleting objects does not need their data keys.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	return absos.DeleteMany(ctx, b.Bucket, keys)
+}
+
+// Versioning returns the versioning state of the bucket. The versioning
+// operations fail with absos.ErrNotSupported if the wrapped bucket does not
+// support them.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return "", err
+	}
+	return vb.Versioning(ctx)
+}
+
+// SetVersioning enables or suspends versioning.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return err
+	}
+	return vb.SetVersioning(ctx, status)
+}
+
+// ListVersions lists a page of versions, with the sizes of their decrypted
+// contents.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := vb.ListVersions(ctx, prefix, token)
+	if err != nil {
+		return nil, err
+	}
+	return &versionPage{VersionPage: page}, nil
+}
+
+// GetVersion retrieves and decrypts a version of an object.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := vb.HeadVersion(ctx, key, versionID)
+	raw, h, err := b.plainHeader(key, raw, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.open(ctx, raw, h, func() (io.ReadCloser, error) {
+		return vb.GetVersion(ctx, key, versionID)
+	})
+}
+
+// HeadVersion retrieves the metadata of a version of an object, without the
+// entries describing its encryption.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := vb.HeadVersion(ctx, key, versionID)
+	_, h, err := b.plainHeader(key, raw, err)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// DeleteVersion permanently removes a version or delete marker.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return err
+	}
+	return vb.DeleteVersion(ctx, key, versionID)
+}
+
+// versionPage reports the sizes of the decrypted contents of versions, or
+// of their stored contents if they are not encrypted.
+type versionPage struct {
+	absos.VersionPage
+}
+
+func (p *versionPage) Versions() []absos.ObjectVersion {
+	versions := p.VersionPage.Versions()
+	plain := make([]absos.ObjectVersion, len(versions))
+	for i, v := range versions {
+		if size, err := plainSize(v.Size); err == nil && !v.DeleteMarker {
+			v.Size = size
+		}
+		plain[i] = v
+	}
+	return plain
+}
@@ -0,0 +1,443 @@
+// Package encrypt wraps buckets to encrypt the contents of objects on the
+// client before they are uploaded, whatever their backend:
+//
+//	keys, err := encrypt.LoadKeyring("/etc/myapp/keyring.json")
+//	...
+//	bucket = encrypt.New(bucket, keys)
+//
+// Each object is encrypted with its own random data key using AES-256-GCM,
+// in chunks of 64 KiB so that ranges can be read and authenticated without
+// downloading the whole object. The data key is wrapped by a KeyProvider and
+// stored, with the ID of the key wrapping it and the algorithm, in the user
+// metadata of the object. Rotating keys only requires rewrapping data keys
+// with Bucket.Rewrap; the contents are not encrypted again.
+//
+// Keys, content headers and user metadata are stored in clear, as are the
+// sizes of objects.
+package encrypt
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/absfs/absos"
+)
+
+// Algorithm identifies the encryption format of objects.
+const Algorithm = "AES256-GCM-64K"
+
+// User metadata entries describing the encryption of an object.
+const (
+	MetadataAlgorithm = "absos-encryption-algorithm"
+	MetadataKeyID     = "absos-encryption-key-id"
+	MetadataKey       = "absos-encryption-key"
+)
+
+var (
+	// ErrNotEncrypted is returned when reading an object that was not
+	// encrypted by this package.
+	ErrNotEncrypted = errors.New("object not encrypted")
+
+	// ErrUnknownKey is returned by a KeyProvider that does not have the key
+	// a data key was wrapped with.
+	ErrUnknownKey = errors.New("unknown encryption key")
+
+	// ErrCorrupt is returned when an object or its wrapped data key fails
+	// authentication, because it was modified, truncated or wrapped with
+	// another key.
+	ErrCorrupt = errors.New("encrypted object corrupt")
+)
+
+// Bucket is an absos.Bucket encrypting the objects it stores and decrypting
+// the objects it reads. Conditional requests and versions are forwarded to
+// the wrapped bucket and decrypted like Put and Get, bulk deletes are
+// forwarded as is, and copies keep the encrypted contents and data key of
+// their source. Multipart uploads are not supported, as parts cannot be
+// encrypted independently: absos.Upload buffers streams and encrypts them
+// with Put.
+//
+// Head, listed objects and versions report the size of the decrypted
+// contents, and no checksums. Checksums passed to Put are verified against
+// the contents before they are encrypted, and not stored.
+//
+// Bucket does not implement absos.Presigner, even if the wrapped bucket
+// does: a presigned GET would serve the ciphertext, and a presigned PUT
+// would store an object that cannot be decrypted. The other optional
+// interfaces are implemented whether or not the wrapped bucket supports
+// them, so type assertions on Bucket do not tell what the wrapped bucket
+// supports; assert on the Bucket field instead.
+type Bucket struct {
+	absos.Bucket
+	keys KeyProvider
+}
+
+var _ absos.RangeBucket = (*Bucket)(nil)
+
+// New returns a Bucket encrypting the objects of b with data keys wrapped by
+// keys.
+func New(b absos.Bucket, keys KeyProvider) *Bucket {
+	return &Bucket{Bucket: b, keys: keys}
+}
+
+// ObjectPage lists a page of objects, which are read through b.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	page, err := b.Bucket.ObjectPage(ctx, prefix, delimiter, token)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedPage{Page: page, b: b}, nil
+}
+
+// Head retrieves the metadata of an object, without the entries describing
+// its encryption.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	_, h, err := b.head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// PutBatch encrypts and uploads the objects of iter one at a time.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	return absos.PutEach(ctx, b, iter)
+}
+
+// Put encrypts and uploads an object with a new data key.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	options := absos.NewPutOptions(opts...)
+
+	body, err := b.encrypt(ctx, data, &options)
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	return b.Bucket.Put(ctx, key, body, func(o *absos.PutOptions) { *o = options })
+}
+
+// encrypt returns a reader encrypting the rest of data with a new data key,
+// whose wrapped key it adds to the metadata of options. The checksums of
+// options are verified against data and removed.
+func (b *Bucket) encrypt(ctx context.Context, data io.ReadSeeker, options *absos.PutOptions) (io.ReadSeeker, error) {
+	start, err := data.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := data.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.Checksums) > 0 {
+		if _, err := data.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := verify(data, options.Checksums); err != nil {
+			return nil, err
+		}
+		options.Checksums = nil
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	keyID, wrapped, err := b.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string, len(options.Metadata)+3)
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataAlgorithm] = Algorithm
+	metadata[MetadataKeyID] = keyID
+	metadata[MetadataKey] = base64.StdEncoding.EncodeToString(wrapped)
+	options.Metadata = metadata
+
+	return newEncryptReader(data, start, end-start, aead), nil
+}
+
+// verify checks the checksums of the contents read from r.
+func verify(r io.Reader, checksums absos.Checksums) error {
+	var algorithms []absos.ChecksumAlgorithm
+	for a := range checksums {
+		algorithms = append(algorithms, a)
+	}
+
+	h := absos.NewChecksumHasher(algorithms...)
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	return checksums.Verify(h.Sum())
+}
+
+// Get retrieves and decrypts an object.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	raw, h, err := b.head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.open(ctx, raw, h, func() (io.ReadCloser, error) {
+		return absos.GetIf(ctx, b.Bucket, key, absos.Conditions{IfMatch: raw.ETag()})
+	})
+}
+
+// open decrypts the object described by raw and h, whose encrypted contents
+// are returned by get.
+func (b *Bucket) open(ctx context.Context, raw absos.ObjectHeader, h *header, get func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	aead, err := b.aead(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := get()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.decrypt(raw.Key(), body, aead, h.size, 0, h.size), nil
+}
+
+// GetRange retrieves and decrypts part of an object, reading only the
+// chunks containing the range from the wrapped bucket. The chunks are read
+// from the object described by its header, so that they are decrypted with
+// its data key; GetRange fails with absos.ErrPreconditionFailed if the
+// object is replaced in between.
+// The range is interpreted as described for absos.ResolveRange.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	raw, h, err := b.head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	start, n, err := absos.ResolveRange(h.size, offset, length)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	aead, err := b.aead(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	first := start / chunkSize * sealedChunk
+	end := min((start+n-1)/chunkSize*sealedChunk+sealedChunk, raw.Size())
+
+	body, err := absos.GetRangeIf(ctx, b.Bucket, key, first, end-first, absos.Conditions{IfMatch: raw.ETag()})
+	if err != nil {
+		return nil, err
+	}
+
+	return b.decrypt(key, body, aead, h.size, start, n), nil
+}
+
+// Rewrap wraps the data key of an object again with the current key of the
+// KeyProvider, after keys were rotated. Only the metadata of the object is
+// replaced, by copying it onto itself; its contents are not encrypted again.
+// Objects already wrapped with the current key are not modified.
+//
+// The object must not be replaced while it is rewrapped, since the data key
+// of the old object would be stored with the new one.
+func (b *Bucket) Rewrap(ctx context.Context, key string) error {
+	raw, _, err := b.head(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := b.dataKey(ctx, raw)
+	if err != nil {
+		return err
+	}
+
+	keyID, wrapped, err := b.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	if keyID == raw.Metadata()[MetadataKeyID] {
+		return nil
+	}
+
+	options := absos.HeaderOptions(raw)
+	metadata := make(map[string]string, len(options.Metadata))
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataKeyID] = keyID
+	metadata[MetadataKey] = base64.StdEncoding.EncodeToString(wrapped)
+	options.Metadata = metadata
+
+	return absos.Copy(ctx, b.Bucket, key, b.Bucket, key,
+		absos.WithReplacedMetadata(func(o *absos.PutOptions) { *o = options }))
+}
+
+// head returns the header of the encrypted object in the wrapped bucket and
+// the header of its decrypted contents.
+func (b *Bucket) head(ctx context.Context, key string) (absos.ObjectHeader, *header, error) {
+	raw, err := b.Bucket.Head(ctx, key)
+	return b.plainHeader(key, raw, err)
+}
+
+// plainHeader returns raw, the header of an encrypted object returned with
+// err, and the header of its decrypted contents.
+func (b *Bucket) plainHeader(key string, raw absos.ObjectHeader, err error) (absos.ObjectHeader, *header, error) {
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := newHeader(raw)
+	if err != nil {
+		return nil, nil, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	return raw, h, nil
+}
+
+// dataKey unwraps the data key of the object described by raw.
+func (b *Bucket) dataKey(ctx context.Context, raw absos.ObjectHeader) ([]byte, error) {
+	metadata := raw.Metadata()
+
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[MetadataKey])
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: raw.Key(), Err: fmt.Errorf("%w: invalid wrapped key", ErrCorrupt)}
+	}
+
+	dataKey, err := b.keys.UnwrapKey(ctx, metadata[MetadataKeyID], wrapped)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: raw.Key(), Err: err}
+	}
+	return dataKey, nil
+}
+
+// aead returns the cipher of the object described by raw.
+func (b *Bucket) aead(ctx context.Context, raw absos.ObjectHeader) (cipher.AEAD, error) {
+	dataKey, err := b.dataKey(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.Name(), Key: raw.Key(), Err: fmt.Errorf("%w: %w", ErrCorrupt, err)}
+	}
+	return aead, nil
+}
+
+// decrypt returns a reader decrypting n bytes starting at start from body,
+// which begins with the chunk containing start. Errors are wrapped in an
+// *absos.ObjectError.
+func (b *Bucket) decrypt(key string, body io.ReadCloser, aead cipher.AEAD, size, start, n int64) io.ReadCloser {
+	return &objectReader{
+		decryptReader: newDecryptReader(body, aead, size, start, n),
+		bucket:        b.Name(),
+		key:           key,
+	}
+}
+
+type objectReader struct {
+	*decryptReader
+	bucket, key string
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	n, err := r.decryptReader.Read(p)
+	if errors.Is(err, ErrCorrupt) {
+		err = &absos.ObjectError{Bucket: r.bucket, Key: r.key, Err: err}
+	}
+	return n, err
+}
+
+// header is the header of the decrypted contents of an object.
+type header struct {
+	absos.ObjectHeader
+	size     int64
+	metadata map[string]string
+}
+
+// newHeader returns the header of the decrypted contents of the object
+// described by raw.
+func newHeader(raw absos.ObjectHeader) (*header, error) {
+	metadata := raw.Metadata()
+	if metadata[MetadataAlgorithm] == "" {
+		return nil, ErrNotEncrypted
+	}
+	if metadata[MetadataAlgorithm] != Algorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", absos.ErrNotSupported, metadata[MetadataAlgorithm])
+	}
+
+	size, err := plainSize(raw.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	h := &header{ObjectHeader: raw, size: size}
+	for k, v := range metadata {
+		if k == MetadataAlgorithm || k == MetadataKeyID || k == MetadataKey {
+			continue
+		}
+		if h.metadata == nil {
+			h.metadata = make(map[string]string)
+		}
+		h.metadata[k] = v
+	}
+	return h, nil
+}
+
+func (h *header) Size() int64                 { return h.size }
+func (h *header) Metadata() map[string]string { return h.metadata }
+func (h *header) Checksums() absos.Checksums  { return nil }
+
+// encryptedPage reads the objects of a page through a Bucket.
+type encryptedPage struct {
+	absos.Page
+	b *Bucket
+}
+
+func (p *encryptedPage) Objects() []absos.Object {
+	objects := p.Page.Objects()
+	wrapped := make([]absos.Object, len(objects))
+	for i, obj := range objects {
+		wrapped[i] = &object{Object: obj, b: p.b}
+	}
+	return wrapped
+}
+
+// object is an object of a page read through a Bucket. Its size is that of
+// its decrypted contents, or of its stored contents if it is not encrypted.
+type object struct {
+	absos.Object
+	b *Bucket
+}
+
+var _ absos.RangeObject = (*object)(nil)
+
+func (o *object) Size() int64 {
+	if size, err := plainSize(o.Object.Size()); err == nil {
+		return size
+	}
+	return o.Object.Size()
+}
+
+func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
+	return o.b.Head(ctx, o.Key())
+}
+
+func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
+	return o.b.Get(ctx, o.Key())
+}
+
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return o.b.GetRange(ctx, o.Key(), offset, length)
+}
@@ -0,0 +1,474 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// rangeBucket records the length of the ranges read from the wrapped bucket,
+// after calling before if it is set.
+type rangeBucket struct {
+	absos.Bucket
+	read   int64
+	before func()
+}
+
+func (b *rangeBucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if b.before != nil {
+		b.before()
+	}
+	b.read += length
+	return absos.GetRange(ctx, b.Bucket, key, offset, length)
+}
+
+// newTestBucket returns an encrypting bucket of a memory store with a single
+// key, and the wrapped bucket.
+func newTestBucket(t *testing.T) (*Bucket, *rangeBucket, *Keyring) {
+	t.Helper()
+
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	keys, err := NewStaticKey("k1", bytes.Repeat([]byte{1}, KeySize))
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+
+	backend := &rangeBucket{Bucket: buckets[0]}
+	return New(backend, keys), backend, keys
+}
+
+func randomData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data)
+	return data
+}
+
+// readAll reads and closes the reader returned by Get or GetRange.
+func readAll(r io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	b, backend, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	for _, n := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5} {
+		data := randomData(n)
+
+		err := b.Put(ctx, "key", bytes.NewReader(data),
+			absos.WithMetadata(map[string]string{"owner": "alice"}),
+			absos.WithChecksum(absos.ChecksumSHA256, absos.ComputeChecksums(data)[absos.ChecksumSHA256]))
+		if err != nil {
+			t.Fatalf("failed to put %d bytes: %v", n, err)
+		}
+
+		got, err := readAll(b.Get(ctx, "key"))
+		if err != nil {
+			t.Fatalf("failed to get %d bytes: %v", n, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%d bytes: decrypted contents differ", n)
+		}
+
+		raw, err := readAll(backend.Get(ctx, "key"))
+		if err != nil {
+			t.Fatalf("failed to get encrypted contents: %v", err)
+		}
+		// Shorter contents may occur in the ciphertext by chance
+		if int64(len(raw)) != sealedSize(int64(n)) || (n >= 16 && bytes.Contains(raw, data)) {
+			t.Fatalf("%d bytes: expected encrypted contents of %d bytes, got %d", n, sealedSize(int64(n)), len(raw))
+		}
+
+		header, err := b.Head(ctx, "key")
+		if err != nil {
+			t.Fatalf("failed to head: %v", err)
+		}
+		if header.Size() != int64(n) {
+			t.Errorf("expected size %d, got %d", n, header.Size())
+		}
+		if len(header.Metadata()) != 1 || header.Metadata()["owner"] != "alice" {
+			t.Errorf("expected only user metadata, got %v", header.Metadata())
+		}
+	}
+}
+
+func TestPutChecksumMismatch(t *testing.T) {
+	b, _, _ := newTestBucket(t)
+
+	err := b.Put(context.Background(), "key", strings.NewReader("data"),
+		absos.WithChecksum(absos.ChecksumSHA256, absos.ComputeChecksums([]byte("other"))[absos.ChecksumSHA256]))
+	if !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+// failingReader fails every Read, but seeks like an empty reader.
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error)                   { return 0, errors.New("read failed") }
+func (failingReader) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+
+func TestPutReadError(t *testing.T) {
+	b, _, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "key", failingReader{}); err == nil {
+		t.Error("expected the read error")
+	}
+	if _, err := b.Head(ctx, "key"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected no object, got %v", err)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	b, backend, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	data := randomData(4*chunkSize + 100)
+	if err := b.Put(ctx, "key", bytes.NewReader(data)); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	tests := []struct {
+		offset, length int64
+		start, end     int
+	}{
+		{0, 10, 0, 10},
+		{chunkSize - 5, 10, chunkSize - 5, chunkSize + 5},
+		{2 * chunkSize, chunkSize, 2 * chunkSize, 3 * chunkSize},
+		{3*chunkSize + 1, -1, 3*chunkSize + 1, len(data)},
+		{-50, 0, len(data) - 50, len(data)},
+	}
+
+	for _, tt := range tests {
+		backend.read = 0
+		got, err := readAll(b.GetRange(ctx, "key", tt.offset, tt.length))
+		if err != nil {
+			t.Fatalf("failed to get range %d+%d: %v", tt.offset, tt.length, err)
+		}
+		if !bytes.Equal(got, data[tt.start:tt.end]) {
+			t.Errorf("range %d+%d: contents differ", tt.offset, tt.length)
+		}
+		if backend.read > 2*sealedChunk {
+			t.Errorf("range %d+%d: read %d bytes of ciphertext", tt.offset, tt.length, backend.read)
+		}
+	}
+
+	if _, err := b.GetRange(ctx, "key", int64(len(data)), 1); !errors.Is(err, absos.ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+
+	// A range of an object replaced after its header was read is not
+	// decrypted with the data key of the old object
+	backend.before = func() {
+		if err := b.Put(ctx, "key", bytes.NewReader(data)); err != nil {
+			t.Fatalf("failed to replace: %v", err)
+		}
+	}
+	if _, err := b.GetRange(ctx, "key", 0, 10); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+func TestTampered(t *testing.T) {
+	b, backend, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	data := randomData(2*chunkSize + 10)
+	if err := b.Put(ctx, "key", bytes.NewReader(data)); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	header, err := backend.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	options := absos.HeaderOptions(header)
+	raw, err := readAll(backend.Get(ctx, "key"))
+	if err != nil {
+		t.Fatalf("failed to get encrypted contents: %v", err)
+	}
+
+	tests := map[string][]byte{
+		"modified":  append(append([]byte(nil), raw[:chunkSize]...), append([]byte{raw[chunkSize] ^ 1}, raw[chunkSize+1:]...)...),
+		"truncated": raw[:2*sealedChunk],
+		"reordered": append(append(append([]byte(nil), raw[sealedChunk:2*sealedChunk]...), raw[:sealedChunk]...), raw[2*sealedChunk:]...),
+	}
+
+	for name, tampered := range tests {
+		if err := backend.Put(ctx, "key", bytes.NewReader(tampered), func(o *absos.PutOptions) { *o = options }); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+
+		r, err := b.Get(ctx, "key")
+		if err == nil {
+			_, err = io.ReadAll(r)
+			r.Close()
+		}
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+}
+
+func TestNotEncrypted(t *testing.T) {
+	b, backend, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := backend.Put(ctx, "plain", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	if _, err := b.Get(ctx, "plain"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestObjectPage(t *testing.T) {
+	b, _, _ := newTestBucket(t)
+	ctx := context.Background()
+
+	data := randomData(chunkSize + 1)
+	if err := b.Put(ctx, "dir/key", bytes.NewReader(data)); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	page, err := b.ObjectPage(ctx, "dir/", "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	obj := page.Objects()[0]
+	if obj.Size() != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), obj.Size())
+	}
+	if got, err := readAll(obj.Open(ctx)); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decrypted contents differ: %v", err)
+	}
+	if got, err := readAll(absos.OpenRange(ctx, obj, chunkSize, -1)); err != nil || !bytes.Equal(got, data[chunkSize:]) {
+		t.Errorf("decrypted range differs: %v", err)
+	}
+}
+
+func TestRotateRewrap(t *testing.T) {
+	b, backend, keys := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "key", strings.NewReader("secret")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	before, err := readAll(backend.Get(ctx, "key"))
+	if err != nil {
+		t.Fatalf("failed to get encrypted contents: %v", err)
+	}
+
+	if err := keys.Rotate("k2"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	// Objects wrapped with the previous key remain readable
+	if got, err := readAll(b.Get(ctx, "key")); err != nil || string(got) != "secret" {
+		t.Fatalf("expected secret, got %q: %v", got, err)
+	}
+
+	if err := b.Rewrap(ctx, "key"); err != nil {
+		t.Fatalf("failed to rewrap: %v", err)
+	}
+
+	header, err := backend.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	if id := header.Metadata()[MetadataKeyID]; id != "k2" {
+		t.Errorf("expected key k2, got %q", id)
+	}
+	if after, err := readAll(backend.Get(ctx, "key")); err != nil || !bytes.Equal(after, before) {
+		t.Error("expected the contents not to be encrypted again")
+	}
+
+	// A keyring without the previous key can read the object
+	k2 := keys.keys["k2"]
+	only, err := NewStaticKey("k2", k2)
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if got, err := readAll(New(backend, only).Get(ctx, "key")); err != nil || string(got) != "secret" {
+		t.Errorf("expected secret, got %q: %v", got, err)
+	}
+
+	other, err := NewStaticKey("k3", k2)
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if _, err := New(backend, other).Get(ctx, "key"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	_, backend, keys := newTestBucket(t)
+	ctx := context.Background()
+
+	// The memory bucket itself supports conditions, copies and versions
+	b := New(backend.Bucket, keys)
+
+	cond := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("secret"), cond); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("other"), cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	header, err := b.Head(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	if got, err := readAll(absos.GetIf(ctx, b, "key", absos.Conditions{IfMatch: header.ETag()})); err != nil || string(got) != "secret" {
+		t.Errorf("expected secret, got %q: %v", got, err)
+	}
+	if _, err := absos.GetIf(ctx, b, "key", absos.Conditions{IfNoneMatch: header.ETag()}); !errors.Is(err, absos.ErrNotModified) {
+		t.Errorf("expected ErrNotModified, got %v", err)
+	}
+
+	// Copies keep the encrypted contents, unless their metadata is replaced
+	if err := absos.Copy(ctx, b, "key", b, "copy"); err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	source, _ := readAll(backend.Get(ctx, "key"))
+	copied, _ := readAll(backend.Get(ctx, "copy"))
+	if !bytes.Equal(source, copied) {
+		t.Error("expected the copy to keep the encrypted contents")
+	}
+
+	err = absos.Copy(ctx, b, "key", b, "replaced", absos.WithReplacedMetadata(absos.WithContentType("text/plain")))
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	for _, key := range []string{"copy", "replaced"} {
+		if got, err := readAll(b.Get(ctx, key)); err != nil || string(got) != "secret" {
+			t.Errorf("%s: expected secret, got %q: %v", key, got, err)
+		}
+	}
+
+	if err := absos.DeleteMany(ctx, b, []string{"copy", "replaced"}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := b.Head(ctx, "copy"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	// Presigned URLs would give access to the ciphertext
+	if _, ok := absos.Bucket(b).(absos.Presigner); ok {
+		t.Error("expected Bucket not to presign URLs")
+	}
+
+	if err := b.SetVersioning(ctx, absos.VersioningEnabled); err != nil {
+		t.Fatalf("failed to enable versioning: %v", err)
+	}
+	if err := b.Put(ctx, "key", strings.NewReader("newer secret")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	page, err := b.ListVersions(ctx, "key", "")
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	versions := page.Versions()
+	if len(versions) != 2 || versions[0].Size != 12 || versions[1].Size != 6 {
+		t.Fatalf("expected versions of 12 and 6 bytes, got %+v", versions)
+	}
+	if got, err := readAll(b.GetVersion(ctx, "key", versions[1].VersionID)); err != nil || string(got) != "secret" {
+		t.Errorf("expected secret, got %q: %v", got, err)
+	}
+}
+
+func TestKeyringFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	ctx := context.Background()
+
+	keys, err := NewStaticKey("k1", bytes.Repeat([]byte{1}, KeySize))
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if err := keys.Rotate("k2"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if err := keys.Save(path); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	loaded, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if loaded.Current() != "k2" {
+		t.Errorf("expected current key k2, got %q", loaded.Current())
+	}
+
+	dataKey := randomData(KeySize)
+	id, wrapped, err := keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		t.Fatalf("failed to wrap: %v", err)
+	}
+	unwrapped, err := loaded.UnwrapKey(ctx, id, wrapped)
+	if err != nil {
+		t.Fatalf("failed to unwrap: %v", err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Error("unwrapped key differs")
+	}
+
+	if _, err := loaded.UnwrapKey(ctx, "k1", wrapped); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt for the wrong key, got %v", err)
+	}
+}
+
+// encryptingStore wraps the buckets of a store in encrypting buckets with
+// the same key.
+type encryptingStore struct {
+	absos.ObjectStore
+	keys KeyProvider
+}
+
+func (s *encryptingStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := s.ObjectStore.ListBuckets(ctx)
+	for i, b := range buckets {
+		buckets[i] = New(b, s.keys)
+	}
+	return buckets, err
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		keys, err := NewStaticKey("k1", bytes.Repeat([]byte{1}, KeySize))
+		if err != nil {
+			t.Fatalf("failed to create key: %v", err)
+		}
+		return &encryptingStore{ObjectStore: memory.NewStore(), keys: keys}
+	})
+}
@@ -0,0 +1,196 @@
+package encrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// KeySize is the size of the keys of a Keyring and of the data keys of
+// objects, in bytes.
+const KeySize = 32
+
+// KeyProvider wraps and unwraps the data keys of objects with key
+// encryption keys, such as the keys of a Keyring or of a key management
+// service.
+type KeyProvider interface {
+	// WrapKey encrypts a data key with the current key encryption key and
+	// returns the ID of that key along with the wrapped data key.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+
+	// UnwrapKey decrypts a data key wrapped with the key keyID. It returns
+	// an error wrapping ErrUnknownKey if it does not have that key.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Keyring is a KeyProvider holding AES-256 keys in memory. Data keys are
+// wrapped with the current key and can be unwrapped with any key of the
+// ring, so that keys can be rotated while objects wrapped with older keys
+// remain readable until they are rewrapped.
+//
+// A Keyring is safe for concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+var _ KeyProvider = (*Keyring)(nil)
+
+// NewStaticKey returns a Keyring holding the single key id.
+func NewStaticKey(id string, key []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	if err := k.Add(id, key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// keyringFile is the JSON encoding of a Keyring. Keys are encoded in base64.
+type keyringFile struct {
+	Current string            `json:"current"`
+	Keys    map[string][]byte `json:"keys"`
+}
+
+// LoadKeyring reads a Keyring from the JSON file at path, as written by
+// Save:
+//
+//	{"current": "2024-06", "keys": {"2024-01": "<base64>", "2024-06": "<base64>"}}
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f keyringFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("encrypt: invalid keyring %s: %w", path, err)
+	}
+
+	k := &Keyring{keys: make(map[string][]byte)}
+	for id, key := range f.Keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("encrypt: invalid keyring %s: key %q is not %d bytes", path, id, KeySize)
+		}
+		k.keys[id] = key
+	}
+	if _, ok := k.keys[f.Current]; !ok {
+		return nil, fmt.Errorf("encrypt: invalid keyring %s: %w: current key %q", path, ErrUnknownKey, f.Current)
+	}
+	k.current = f.Current
+
+	return k, nil
+}
+
+// Save writes the keyring to the file at path, readable by its owner only.
+// The file is replaced atomically.
+func (k *Keyring) Save(path string) error {
+	k.mu.RLock()
+	data, err := json.MarshalIndent(keyringFile{Current: k.current, Keys: k.keys}, "", "  ")
+	k.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keyring-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Add adds the key id to the ring and makes it the current key.
+func (k *Keyring) Add(id string, key []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("encrypt: key %q is not %d bytes", id, KeySize)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("encrypt: key %q already exists", id)
+	}
+	k.keys[id] = append([]byte(nil), key...)
+	k.current = id
+
+	return nil
+}
+
+// Rotate adds a random key id to the ring and makes it the current key.
+// Objects wrapped with the previous keys can be rewrapped with
+// Bucket.Rewrap.
+func (k *Keyring) Rotate(id string) error {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	return k.Add(id, key)
+}
+
+// Current returns the ID of the key wrapping new data keys.
+func (k *Keyring) Current() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// WrapKey encrypts dataKey with the current key using AES-256-GCM. The
+// wrapped key is the random nonce followed by the sealed data key.
+func (k *Keyring) WrapKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	k.mu.RLock()
+	id, key := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	n := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(n); err != nil {
+		return "", nil, err
+	}
+
+	return id, aead.Seal(n, n, dataKey, []byte(id)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey with the key keyID.
+func (k *Keyring) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	k.mu.RLock()
+	key, ok := k.keys[keyID]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid wrapped key", ErrCorrupt)
+	}
+	n, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+
+	dataKey, err := aead.Open(nil, n, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid wrapped key", ErrCorrupt)
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,228 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Objects are encrypted in chunks of chunkSize bytes of plaintext, each
+// sealed with AES-256-GCM under the data key of the object and stored
+// followed by its tag. The nonce of a chunk is its index, with a flag marking
+// the final chunk so that truncated objects are detected. An empty object is
+// a single empty final chunk.
+const (
+	chunkSize   = 64 << 10
+	tagSize     = 16
+	sealedChunk = chunkSize + tagSize
+)
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of chunk i.
+func nonce(i int64, last bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n, uint64(i))
+	if last {
+		n[11] = 1
+	}
+	return n
+}
+
+// chunks returns the number of chunks of size bytes of plaintext.
+func chunks(size int64) int64 {
+	if size == 0 {
+		return 1
+	}
+	return (size + chunkSize - 1) / chunkSize
+}
+
+// sealedSize returns the size of size bytes of plaintext once encrypted.
+func sealedSize(size int64) int64 {
+	return size + chunks(size)*tagSize
+}
+
+// plainSize returns the size of the plaintext of an encrypted object of
+// the given size.
+func plainSize(sealed int64) (int64, error) {
+	n := (sealed + sealedChunk - 1) / sealedChunk
+	size := sealed - n*tagSize
+	if size < 0 || sealedSize(size) != sealed {
+		return 0, fmt.Errorf("%w: invalid size %d", ErrCorrupt, sealed)
+	}
+	return size, nil
+}
+
+// encryptReader encrypts size bytes of plaintext read from src starting at
+// offset base. It seeks within the ciphertext by encrypting the chunk
+// containing the new offset again, so that uploads can be retried.
+type encryptReader struct {
+	src  io.ReadSeeker
+	aead cipher.AEAD
+	base int64
+	size int64
+
+	pos   int64
+	chunk int64
+	buf   []byte
+}
+
+func newEncryptReader(src io.ReadSeeker, base, size int64, aead cipher.AEAD) *encryptReader {
+	return &encryptReader{src: src, aead: aead, base: base, size: size, chunk: -1}
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	if r.pos >= sealedSize(r.size) {
+		return 0, io.EOF
+	}
+
+	i := r.pos / sealedChunk
+	if i != r.chunk {
+		if err := r.seal(i); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf[r.pos-i*sealedChunk:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// seal encrypts chunk i into r.buf.
+func (r *encryptReader) seal(i int64) error {
+	start := i * chunkSize
+	n := min(chunkSize, r.size-start)
+
+	if _, err := r.src.Seek(r.base+start, io.SeekStart); err != nil {
+		return err
+	}
+
+	plain := make([]byte, n)
+	if _, err := io.ReadFull(r.src, plain); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	// src is read past the last chunk, so that an error reported at its end
+	// fails the upload even if Seek did not report the size of src
+	last := i == chunks(r.size)-1
+	if last {
+		if _, err := r.src.Read(make([]byte, 1)); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	r.buf = r.aead.Seal(r.buf[:0], nonce(i, last), plain, nil)
+	r.chunk = i
+	return nil
+}
+
+func (r *encryptReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += sealedSize(r.size)
+	default:
+		return 0, errors.New("encrypt: invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("encrypt: negative offset")
+	}
+
+	r.pos = offset
+	return offset, nil
+}
+
+// decryptReader decrypts the chunks of an object read from r and returns
+// a range of its plaintext.
+type decryptReader struct {
+	r    io.ReadCloser
+	aead cipher.AEAD
+
+	chunk     int64
+	last      int64
+	lastSize  int64
+	skip      int64
+	remaining int64
+
+	buf []byte
+	out []byte
+}
+
+// newDecryptReader returns a reader decrypting the plaintext range [start,
+// start+n) of an object of size bytes, from the ciphertext read by r starting
+// at the chunk containing start.
+func newDecryptReader(r io.ReadCloser, aead cipher.AEAD, size, start, n int64) *decryptReader {
+	last := chunks(size) - 1
+	return &decryptReader{
+		r:         r,
+		aead:      aead,
+		chunk:     start / chunkSize,
+		last:      last,
+		lastSize:  size - last*chunkSize + tagSize,
+		skip:      start % chunkSize,
+		remaining: n,
+		buf:       make([]byte, sealedChunk),
+	}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	for len(d.out) == 0 {
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.out[:min(int64(len(d.out)), d.remaining)])
+	d.out = d.out[n:]
+	d.remaining -= int64(n)
+	return n, nil
+}
+
+// open reads and decrypts the next chunk into d.out.
+func (d *decryptReader) open() error {
+	size := int64(sealedChunk)
+	if d.chunk == d.last {
+		size = d.lastSize
+	}
+
+	sealed := d.buf[:size]
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	plain, err := d.aead.Open(sealed[:0], nonce(d.chunk, d.chunk == d.last), sealed, nil)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d", ErrCorrupt, d.chunk)
+	}
+
+	d.out = plain[d.skip:]
+	d.skip = 0
+	d.chunk++
+	return nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.r.Close()
+}
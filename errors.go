@@ -1,6 +1,7 @@
 package absos
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -13,6 +14,9 @@ var (
 	// ErrBucketAlreadyExists is returned when attempting to create a bucket that already exists.
 	ErrBucketAlreadyExists = errors.New("bucket already exists")
 
+	// ErrInvalidBucketName is returned when a bucket name is invalid.
+	ErrInvalidBucketName = errors.New("invalid bucket name")
+
 	// ErrBucketNotEmpty is returned when attempting to delete a non-empty bucket.
 	ErrBucketNotEmpty = errors.New("bucket not empty")
 
@@ -24,8 +28,65 @@ var (
 
 	// ErrPermissionDenied is returned when access to a resource is denied.
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrInvalidRange is returned when a requested byte range lies outside an object.
+	ErrInvalidRange = errors.New("invalid range")
+
+	// ErrUploadAborted is returned when an upload is aborted by its writer.
+	ErrUploadAborted = errors.New("upload aborted")
+
+	// ErrUploadNotFound is returned when a multipart upload does not exist.
+	ErrUploadNotFound = errors.New("multipart upload not found")
+
+	// ErrInvalidPart is returned when the parts of a multipart upload are
+	// missing, out of order or do not match the uploaded parts.
+	ErrInvalidPart = errors.New("invalid part")
+
+	// ErrNotSupported is returned by an optional capability that cannot
+	// serve a request. Helpers such as Copy then fall back to a generic
+	// implementation.
+	ErrNotSupported = errors.New("operation not supported")
+
+	// ErrPreconditionFailed is returned when the conditions of a
+	// conditional request are not met.
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrNotModified is returned by a conditional read when the object
+	// matches the version the caller already has.
+	ErrNotModified = errors.New("not modified")
+
+	// ErrVersionNotFound is returned when a version of an object does not exist.
+	ErrVersionNotFound = errors.New("version not found")
+
+	// ErrChecksumMismatch is returned when the contents of an object do not
+	// match the checksums supplied with or stored for them.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrThrottled is returned when a request was rejected because of its
+	// rate. It is temporary: the request may succeed when retried later.
+	ErrThrottled = errors.New("request throttled")
+
+	// ErrUnavailable is returned when the service failed to handle a
+	// request. It is temporary: the request may succeed when retried later.
+	ErrUnavailable = errors.New("service unavailable")
 )
 
+// IsTemporary reports whether err is a transient failure that may succeed
+// when retried: ErrThrottled, ErrUnavailable, or an error in the chain of
+// err with a Temporary method reporting true, such as network timeouts.
+// Errors of canceled or expired contexts are not temporary.
+func IsTemporary(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrThrottled) || errors.Is(err, ErrUnavailable) {
+		return true
+	}
+
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
 // BucketError wraps an error with the bucket name for context.
 type BucketError struct {
 	Bucket string
@@ -42,6 +103,12 @@ func (e *BucketError) Unwrap() error {
 	return e.Err
 }
 
+// Temporary reports whether the underlying error is temporary.
+// See IsTemporary.
+func (e *BucketError) Temporary() bool {
+	return IsTemporary(e.Err)
+}
+
 // ObjectError wraps an error with bucket and object key for context.
 type ObjectError struct {
 	Bucket string
@@ -58,3 +125,9 @@ func (e *ObjectError) Error() string {
 func (e *ObjectError) Unwrap() error {
 	return e.Err
 }
+
+// Temporary reports whether the underlying error is temporary.
+// See IsTemporary.
+func (e *ObjectError) Temporary() bool {
+	return IsTemporary(e.Err)
+}
@@ -1,7 +1,9 @@
 package absos
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -67,10 +69,22 @@ func TestErrorConstants(t *testing.T) {
 	}{
 		{"BucketNotFound", ErrBucketNotFound, "bucket not found"},
 		{"BucketAlreadyExists", ErrBucketAlreadyExists, "bucket already exists"},
+		{"InvalidBucketName", ErrInvalidBucketName, "invalid bucket name"},
 		{"BucketNotEmpty", ErrBucketNotEmpty, "bucket not empty"},
 		{"ObjectNotFound", ErrObjectNotFound, "object not found"},
 		{"InvalidKey", ErrInvalidKey, "invalid object key"},
 		{"PermissionDenied", ErrPermissionDenied, "permission denied"},
+		{"InvalidRange", ErrInvalidRange, "invalid range"},
+		{"UploadAborted", ErrUploadAborted, "upload aborted"},
+		{"UploadNotFound", ErrUploadNotFound, "multipart upload not found"},
+		{"InvalidPart", ErrInvalidPart, "invalid part"},
+		{"NotSupported", ErrNotSupported, "operation not supported"},
+		{"PreconditionFailed", ErrPreconditionFailed, "precondition failed"},
+		{"NotModified", ErrNotModified, "not modified"},
+		{"VersionNotFound", ErrVersionNotFound, "version not found"},
+		{"ChecksumMismatch", ErrChecksumMismatch, "checksum mismatch"},
+		{"Throttled", ErrThrottled, "request throttled"},
+		{"Unavailable", ErrUnavailable, "service unavailable"},
 	}
 
 	for _, tt := range tests {
@@ -81,3 +95,37 @@ func TestErrorConstants(t *testing.T) {
 		})
 	}
 }
+
+// timeoutError mimics the network errors reporting Temporary.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Nil", nil, false},
+		{"Throttled", &ObjectError{Bucket: "b", Key: "k", Err: ErrThrottled}, true},
+		{"Unavailable", &BucketError{Bucket: "b", Err: fmt.Errorf("%w: 503", ErrUnavailable)}, true},
+		{"Network", &ObjectError{Bucket: "b", Key: "k", Err: timeoutError{}}, true},
+		{"NotFound", &ObjectError{Bucket: "b", Key: "k", Err: ErrObjectNotFound}, false},
+		{"Canceled", &BucketError{Bucket: "b", Err: fmt.Errorf("%w: %w", context.Canceled, ErrUnavailable)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemporary(tt.err); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+
+			var temp interface{ Temporary() bool }
+			if errors.As(tt.err, &temp) && temp.Temporary() != tt.expected {
+				t.Errorf("expected Temporary() to report %v", tt.expected)
+			}
+		})
+	}
+}
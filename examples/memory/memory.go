@@ -5,29 +5,53 @@ package memory
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/absfs/absos"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/absfs/absos/internal/listing"
 )
 
 // Store is an in-memory implementation of absos.ObjectStore.
 type Store struct {
-	mu      sync.RWMutex
-	buckets map[string]*Bucket
+	mu       sync.RWMutex
+	buckets  map[string]*Bucket
+	pageSize int
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithPageSize sets the maximum number of entries returned by a single
+// ObjectPage call. The default is 1000.
+func WithPageSize(n int) Option {
+	return func(s *Store) {
+		s.pageSize = n
+	}
 }
 
 // NewStore creates a new in-memory object store.
-func NewStore() *Store {
-	return &Store{
-		buckets: make(map[string]*Bucket),
+func NewStore(opts ...Option) *Store {
+	s := &Store{
+		buckets:  make(map[string]*Bucket),
+		pageSize: listing.DefaultMaxKeys,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // CreateBucket creates a new bucket in memory.
 func (s *Store) CreateBucket(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -36,9 +60,11 @@ func (s *Store) CreateBucket(ctx context.Context, name string) error {
 	}
 
 	s.buckets[name] = &Bucket{
-		name:    name,
-		created: time.Now(),
-		objects: make(map[string]*object),
+		name:     name,
+		created:  time.Now(),
+		pageSize: s.pageSize,
+		objects:  make(map[string]*object),
+		uploads:  make(map[string]*upload),
 	}
 
 	return nil
@@ -46,6 +72,10 @@ func (s *Store) CreateBucket(ctx context.Context, name string) error {
 
 // DeleteBucket deletes a bucket from memory.
 func (s *Store) DeleteBucket(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -54,20 +84,26 @@ func (s *Store) DeleteBucket(ctx context.Context, name string) error {
 		return &absos.BucketError{Bucket: name, Err: absos.ErrBucketNotFound}
 	}
 
-	bucket.mu.RLock()
-	notEmpty := len(bucket.objects) > 0
-	bucket.mu.RUnlock()
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
 
-	if notEmpty {
+	if len(bucket.objects) > 0 || len(bucket.versions) > 0 {
 		return &absos.BucketError{Bucket: name, Err: absos.ErrBucketNotEmpty}
 	}
 
+	// Handles to the bucket held by callers stop working
+	bucket.deleted = true
+
 	delete(s.buckets, name)
 	return nil
 }
 
 // ListBuckets returns all buckets in the store.
 func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -81,10 +117,20 @@ func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
 
 // Bucket is an in-memory implementation of absos.Bucket.
 type Bucket struct {
-	mu      sync.RWMutex
-	name    string
-	created time.Time
-	objects map[string]*object
+	mu       sync.RWMutex
+	name     string
+	created  time.Time
+	pageSize int
+	deleted  bool
+	objects  map[string]*object
+	uploads  map[string]*upload
+
+	// versioning is the versioning state of the bucket. Once versioning
+	// has been enabled, versions holds every version of each key, oldest
+	// first, and objects the latest version unless it is a delete marker.
+	versioning  absos.VersioningStatus
+	versions    map[string][]*object
+	lastVersion int
 }
 
 // Name returns the bucket name.
@@ -102,91 +148,376 @@ func (b *Bucket) Owner() absos.Owner {
 	return nil
 }
 
-// ObjectPage returns a page of objects (simplified pagination).
+// ObjectPage returns a page of objects in lexicographic key order.
+// Keys containing the delimiter after the prefix are collapsed into common
+// prefixes. Continuation tokens remain valid while objects change.
 func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	return b.list(ctx, listing.Options{Prefix: prefix, Delimiter: delimiter, Token: token})
+}
+
+// ObjectPageAfter returns the first page of objects with keys sorting after
+// startAfter, like the StartAfter parameter of S3's ListObjectsV2. The
+// following pages are requested from ObjectPage with the returned token.
+func (b *Bucket) ObjectPageAfter(ctx context.Context, prefix, delimiter, startAfter string) (absos.Page, error) {
+	return b.list(ctx, listing.Options{Prefix: prefix, Delimiter: delimiter, StartAfter: startAfter})
+}
+
+// list returns the page of objects selected by opts.
+func (b *Bucket) list(ctx context.Context, opts listing.Options) (absos.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	// Simplified implementation - returns all objects matching prefix
-	var objects []absos.Object
-	for key, obj := range b.objects {
-		if prefix == "" || len(key) >= len(prefix) && key[:len(prefix)] == prefix {
-			objects = append(objects, obj)
-		}
+	if err := b.check(); err != nil {
+		return nil, err
 	}
 
-	return &page{objects: objects, last: true}, nil
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	opts.MaxKeys = b.pageSize
+	lp, err := listing.List(keys, opts)
+	if err != nil {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	p := &page{
+		objects:  make([]absos.Object, 0, len(lp.Keys)),
+		prefixes: lp.Prefixes,
+		next:     lp.Next,
+		last:     !lp.Truncated,
+	}
+	for _, key := range lp.Keys {
+		p.objects = append(p.objects, b.objects[key])
+	}
+
+	return p, nil
 }
 
 // Head retrieves object metadata.
 func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	return b.HeadIf(ctx, key, absos.Conditions{})
+}
+
+// HeadIf retrieves object metadata if cond holds.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
+	return b.lookup(key, cond)
+}
+
+// check returns absos.ErrBucketNotFound once the bucket has been deleted.
+// The caller must hold the bucket's lock.
+func (b *Bucket) check() error {
+	if b.deleted {
+		return &absos.BucketError{Bucket: b.name, Err: absos.ErrBucketNotFound}
+	}
+	return nil
+}
+
+// lookup returns the object for key if cond holds.
+// The caller must hold the bucket's lock.
+func (b *Bucket) lookup(key string, cond absos.Conditions) (*object, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
 	obj, exists := b.objects[key]
 	if !exists {
 		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrObjectNotFound}
 	}
 
+	if err := cond.CheckRead(obj); err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
 	return obj, nil
 }
 
-// PutBatch is not implemented in this simple example.
-func (b *Bucket) PutBatch(ctx context.Context, iter s3manager.BatchUploadIterator) error {
+// checkWrite evaluates cond against the current object for key.
+// The caller must hold the bucket's write lock.
+func (b *Bucket) checkWrite(key string, cond absos.Conditions) error {
+	if err := b.check(); err != nil {
+		return err
+	}
+
+	var header absos.ObjectHeader
+	if obj, exists := b.objects[key]; exists {
+		header = obj
+	}
+
+	if err := cond.CheckWrite(header); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
 	return nil
 }
 
-// Put stores an object in memory.
-func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker) error {
+// PutBatch stores each object produced by the iterator.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	return absos.PutEach(ctx, b, iter)
+}
+
+// Put stores an object in memory along with the attributes set by opts.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	return b.PutStream(ctx, key, data, opts...)
+}
+
+// PutStream stores an object read from data in memory. The object is only
+// stored once data has been read completely.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	return b.put(ctx, key, data, absos.Conditions{}, opts...)
+}
+
+// PutIf stores an object in memory if cond holds once data has been read.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	return b.put(ctx, key, data, cond, opts...)
+}
+
+// put stores the object read from data if cond holds when it is stored.
+func (b *Bucket) put(ctx context.Context, key string, data io.Reader, cond absos.Conditions, opts ...absos.PutOption) error {
+	content, err := io.ReadAll(data)
+	if err == nil {
+		// The data may have been read after ctx was canceled
+		err = ctx.Err()
+	}
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	options := cloneOptions(absos.NewPutOptions(opts...))
+	etag := md5.Sum(content)
+
+	checksums := absos.ComputeChecksums(content)
+	if err := options.Checksums.Verify(checksums); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	content, err := io.ReadAll(data)
+	if err := b.checkWrite(key, cond); err != nil {
+		return err
+	}
+
+	b.setObject(&object{
+		bucket:    b.name,
+		key:       key,
+		data:      content,
+		etag:      etag[:],
+		checksums: checksums,
+		modTime:   time.Now(),
+		options:   options,
+	})
+
+	return nil
+}
+
+// cloneOptions copies the reference fields of options so that later
+// changes by the caller are not seen.
+func cloneOptions(options absos.PutOptions) absos.PutOptions {
+	if options.Metadata != nil {
+		metadata := make(map[string]string, len(options.Metadata))
+		for k, v := range options.Metadata {
+			metadata[k] = v
+		}
+		options.Metadata = metadata
+	}
+	if options.SSE != nil {
+		sse := *options.SSE
+		options.SSE = &sse
+	}
+	if options.Checksums != nil {
+		checksums := make(absos.Checksums, len(options.Checksums))
+		for a, sum := range options.Checksums {
+			checksums[a] = sum
+		}
+		options.Checksums = checksums
+	}
+	return options
+}
+
+// Copy copies an object from another memory bucket. The copy shares the
+// contents of the source, which are never modified in place.
+// It returns absos.ErrNotSupported if src is not a memory bucket.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	from, ok := src.(*Bucket)
+	if !ok {
+		return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: absos.ErrNotSupported}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: err}
+	}
+
+	from.mu.RLock()
+	obj, err := from.lookup(srcKey, absos.Conditions{})
+	from.mu.RUnlock()
+
 	if err != nil {
 		return err
 	}
 
-	b.objects[key] = &object{
-		bucket:  b.name,
-		key:     key,
-		data:    content,
-		modTime: time.Now(),
+	options := absos.NewCopyOptions(opts...)
+	attributes := obj.options
+	if options.Directive == absos.MetadataReplace {
+		attributes = cloneOptions(options.Replace)
+		if err := attributes.Checksums.Verify(obj.checksums); err != nil {
+			return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: err}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.check(); err != nil {
+		return err
 	}
 
+	b.setObject(&object{
+		bucket:    b.name,
+		key:       dstKey,
+		data:      obj.data,
+		etag:      obj.etag,
+		checksums: obj.checksums,
+		modTime:   time.Now(),
+		options:   attributes,
+	})
+
 	return nil
 }
 
 // Get retrieves an object from memory.
 func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.GetIf(ctx, key, absos.Conditions{})
+}
+
+// GetIf retrieves an object from memory if cond holds.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	obj, exists := b.objects[key]
-	if !exists {
-		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrObjectNotFound}
+	obj, err := b.lookup(key, cond)
+	if err != nil {
+		return nil, err
 	}
 
-	return io.NopCloser(bytes.NewReader(obj.data)), nil
+	return obj.Open(ctx)
+}
+
+// GetRange retrieves part of an object from memory.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	obj, err := b.lookup(key, absos.Conditions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.OpenRange(ctx, offset, length)
 }
 
 // Delete removes an object from memory.
 func (b *Bucket) Delete(ctx context.Context, key string) error {
+	return b.DeleteIf(ctx, key, absos.Conditions{})
+}
+
+// DeleteIf removes an object from memory if cond holds. In a versioned
+// bucket, it hides the object behind a delete marker instead, and like
+// Delete reports a key without a current version as not found.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	if err := b.check(); err != nil {
+		return err
+	}
+
 	if _, exists := b.objects[key]; !exists {
 		return &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrObjectNotFound}
 	}
 
-	delete(b.objects, key)
+	if err := b.checkWrite(key, cond); err != nil {
+		return err
+	}
+
+	b.deleteObject(key)
+	return nil
+}
+
+// deleteLimit is the maximum number of keys accepted by DeleteMany, which
+// is the limit of S3.
+const deleteLimit = 1000
+
+// DeleteLimit returns the maximum number of keys accepted by DeleteMany.
+func (b *Bucket) DeleteLimit() int {
+	return deleteLimit
+}
+
+// DeleteMany removes the objects with the given keys from memory at once.
+// Keys without an object are ignored.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	if len(keys) > deleteLimit {
+		return &absos.BucketError{Bucket: b.name, Err: fmt.Errorf("cannot delete more than %d keys at once", deleteLimit)}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.check(); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if _, exists := b.objects[key]; exists {
+			b.deleteObject(key)
+		}
+	}
+
 	return nil
 }
 
 type object struct {
-	bucket  string
-	key     string
-	data    []byte
-	modTime time.Time
+	bucket    string
+	key       string
+	data      []byte
+	etag      []byte
+	checksums absos.Checksums
+	modTime   time.Time
+	options   absos.PutOptions
+
+	// version is the version ID of the object, and marker reports whether
+	// it is a delete marker.
+	version string
+	marker  bool
 }
 
 func (o *object) Bucket() string                   { return o.bucket }
@@ -194,28 +525,71 @@ func (o *object) Key() string                      { return o.key }
 func (o *object) Size() int64                      { return int64(len(o.data)) }
 func (o *object) ModTime() time.Time               { return o.modTime }
 func (o *object) AccessTime() time.Time            { return o.modTime }
-func (o *object) ETag() []byte                     { return nil }
-func (o *object) StorageClass() string             { return "STANDARD" }
-func (o *object) MimeType() string                 { return "application/octet-stream" }
-func (o *object) Metadata() map[string]string      { return nil }
-func (o *object) Version() string                  { return "" }
+func (o *object) ETag() []byte                     { return o.etag }
+func (o *object) ContentEncoding() string          { return o.options.ContentEncoding }
+func (o *object) CacheControl() string             { return o.options.CacheControl }
+func (o *object) ContentDisposition() string       { return o.options.ContentDisposition }
+func (o *object) Version() string                  { return o.version }
 func (o *object) Redirect() string                 { return "" }
-func (o *object) ServerSideEncryption() *absos.SSE { return nil }
+func (o *object) ServerSideEncryption() *absos.SSE { return o.options.SSE }
+func (o *object) Checksums() absos.Checksums       { return o.checksums }
+
+// Metadata returns a copy of the user metadata of the object, so that
+// callers cannot modify the stored object.
+func (o *object) Metadata() map[string]string {
+	if o.options.Metadata == nil {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(o.options.Metadata))
+	for k, v := range o.options.Metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+func (o *object) StorageClass() string {
+	if o.options.StorageClass == "" {
+		return "STANDARD"
+	}
+	return o.options.StorageClass
+}
+
+func (o *object) MimeType() string {
+	if o.options.ContentType == "" {
+		return "application/octet-stream"
+	}
+	return o.options.ContentType
+}
 
 func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
 	return o, nil
 }
 
+// Open returns a reader of the contents of the object. The checksums of the
+// object are computed from its contents when it is stored, and the contents
+// are never modified afterwards, so they are not verified again.
 func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
 	return io.NopCloser(bytes.NewReader(o.data)), nil
 }
 
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	start, n, err := absos.ResolveRange(o.Size(), offset, length)
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: o.bucket, Key: o.key, Err: err}
+	}
+
+	return io.NopCloser(bytes.NewReader(o.data[start : start+n])), nil
+}
+
 type page struct {
-	objects []absos.Object
-	last    bool
+	objects  []absos.Object
+	prefixes []string
+	next     string
+	last     bool
 }
 
 func (p *page) Objects() []absos.Object { return p.objects }
-func (p *page) Prefixes() []string      { return nil }
-func (p *page) NextPage() string        { return "" }
+func (p *page) Prefixes() []string      { return p.prefixes }
+func (p *page) NextPage() string        { return p.next }
 func (p *page) Last() bool              { return p.last }
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"io"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
 )
 
 func TestStoreCreateBucket(t *testing.T) {
@@ -259,3 +261,317 @@ func TestBucketObjectPage(t *testing.T) {
 		t.Errorf("expected 1 object with prefix, got %d", len(page.Objects()))
 	}
 }
+
+func TestBucketObjectPageAfter(t *testing.T) {
+	store := NewStore(WithPageSize(2))
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	bucket := store.buckets["test-bucket"]
+
+	for _, key := range []string{"a", "b", "c", "d/1", "d/2", "e"} {
+		if err := bucket.Put(ctx, key, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to put object %s: %v", key, err)
+		}
+	}
+
+	page, err := bucket.ObjectPageAfter(ctx, "", "/", "b")
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+
+	// The first page holds "c" and the common prefix "d/"
+	var entries []string
+	for {
+		for _, obj := range page.Objects() {
+			entries = append(entries, obj.Key())
+		}
+		entries = append(entries, page.Prefixes()...)
+		if page.Last() {
+			break
+		}
+
+		page, err = bucket.ObjectPage(ctx, "", "/", page.NextPage())
+		if err != nil {
+			t.Fatalf("failed to list objects: %v", err)
+		}
+	}
+
+	expected := []string{"c", "d/", "e"}
+	if strings.Join(entries, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, entries)
+	}
+}
+
+func TestBucketPutBatch(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	// Create bucket
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	bucket := buckets[0]
+
+	// Upload a batch with one failing object
+	iter := absos.NewBatchIterator(
+		absos.BatchObject{Key: "a.txt", Data: strings.NewReader("a")},
+		absos.BatchObject{Key: "broken.txt", Data: failingReader{}},
+		absos.BatchObject{Key: "b.txt", Data: strings.NewReader("b")},
+	)
+
+	err := bucket.PutBatch(ctx, iter)
+	if err == nil {
+		t.Fatal("expected error for failing object")
+	}
+
+	var batchErr *absos.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected *absos.BatchError, got %T", err)
+	}
+
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "broken.txt" {
+		t.Errorf("expected single failure for broken.txt, got %v", batchErr.Errors)
+	}
+
+	// The remaining objects are still uploaded
+	for _, key := range []string{"a.txt", "b.txt"} {
+		if _, err := bucket.Head(ctx, key); err != nil {
+			t.Errorf("expected %s to be uploaded, got %v", key, err)
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error)                   { return 0, errors.New("read failed") }
+func (failingReader) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+
+func TestBucketPutOptions(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	// Create bucket
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	bucket := buckets[0]
+
+	// Upload with options through a batch
+	metadata := map[string]string{"owner": "alice"}
+	iter := absos.NewBatchIterator(absos.BatchObject{
+		Key:  "data.json",
+		Data: strings.NewReader("{}"),
+		Options: []absos.PutOption{
+			absos.WithContentType("application/json"),
+			absos.WithMetadata(metadata),
+			absos.WithStorageClass("GLACIER"),
+			absos.WithSSE(&absos.SSE{ServerSideEncryption: "aws:kms", KMSKeyId: "key"}),
+		},
+	})
+	if err := bucket.PutBatch(ctx, iter); err != nil {
+		t.Fatalf("failed to put batch: %v", err)
+	}
+
+	// Later changes to the caller's map are not stored
+	metadata["owner"] = "mallory"
+
+	header, err := bucket.Head(ctx, "data.json")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.MimeType() != "application/json" {
+		t.Errorf("expected MIME type application/json, got %s", header.MimeType())
+	}
+
+	if header.Metadata()["owner"] != "alice" {
+		t.Errorf("expected owner alice, got %v", header.Metadata())
+	}
+
+	// Changes to the returned map are not stored either
+	header.Metadata()["owner"] = "mallory"
+	if header.Metadata()["owner"] != "alice" {
+		t.Errorf("expected owner alice, got %v", header.Metadata())
+	}
+
+	if header.StorageClass() != "GLACIER" {
+		t.Errorf("expected storage class GLACIER, got %s", header.StorageClass())
+	}
+
+	if sse := header.ServerSideEncryption(); sse == nil || sse.KMSKeyId != "key" {
+		t.Errorf("unexpected server-side encryption %+v", sse)
+	}
+}
+
+func TestBucketCopy(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	for _, name := range []string{"src-bucket", "dst-bucket"} {
+		if err := store.CreateBucket(ctx, name); err != nil {
+			t.Fatalf("failed to create bucket: %v", err)
+		}
+	}
+
+	src := store.buckets["src-bucket"]
+	dst := store.buckets["dst-bucket"]
+
+	if err := src.Put(ctx, "key", strings.NewReader("shared")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := dst.Copy(ctx, src, "key", "copy"); err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+
+	// The copy shares the contents of the source
+	if &dst.objects["copy"].data[0] != &src.objects["key"].data[0] {
+		t.Error("expected copy to share the source's byte slice")
+	}
+
+	// Copying from a foreign bucket is left to the generic fallback
+	err := dst.Copy(ctx, struct{ absos.Bucket }{src}, "key", "copy")
+	if !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestBucketPutIfNotExists(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	bucket := store.buckets["test-bucket"]
+
+	// Exactly one of the concurrent create-only writers wins
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = bucket.PutIf(ctx, "leader", strings.NewReader("candidate"), absos.Conditions{IfNotExists: true})
+		}(i)
+	}
+	wg.Wait()
+
+	winners := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			winners++
+		case !errors.Is(err, absos.ErrPreconditionFailed):
+			t.Errorf("expected ErrPreconditionFailed, got %v", err)
+		}
+	}
+	if winners != 1 {
+		t.Errorf("expected 1 winner, got %d", winners)
+	}
+}
+
+func TestBucketGetRange(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	// Create bucket
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	bucket, ok := buckets[0].(absos.RangeBucket)
+	if !ok {
+		t.Fatal("expected bucket to implement absos.RangeBucket")
+	}
+
+	if err := bucket.Put(ctx, "digits", strings.NewReader("0123456789")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	// Read a range and a suffix
+	for _, tt := range []struct {
+		offset, length int64
+		expected       string
+	}{
+		{2, 3, "234"},
+		{-2, 0, "89"},
+	} {
+		reader, err := bucket.GetRange(ctx, "digits", tt.offset, tt.length)
+		if err != nil {
+			t.Fatalf("failed to get range: %v", err)
+		}
+
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+
+		if string(content) != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, content)
+		}
+	}
+
+	// Read past the end
+	_, err := bucket.GetRange(ctx, "digits", 10, 1)
+	if !errors.Is(err, absos.ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return NewStore(WithPageSize(2))
+	})
+}
+
+func TestBucketVersionsKeepBucket(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	bucket := store.buckets["test-bucket"]
+
+	if err := bucket.SetVersioning(ctx, absos.VersioningEnabled); err != nil {
+		t.Fatalf("failed to enable versioning: %v", err)
+	}
+	if err := bucket.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	if err := bucket.Delete(ctx, "key"); err != nil {
+		t.Fatalf("failed to delete object: %v", err)
+	}
+
+	// The bucket is not empty until every version is deleted
+	err := store.DeleteBucket(ctx, "test-bucket")
+	if !errors.Is(err, absos.ErrBucketNotEmpty) {
+		t.Errorf("expected ErrBucketNotEmpty, got %v", err)
+	}
+
+	page, err := bucket.ListVersions(ctx, "", "")
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	for _, v := range page.Versions() {
+		if err := bucket.DeleteVersion(ctx, v.Key, v.VersionID); err != nil {
+			t.Fatalf("failed to delete version: %v", err)
+		}
+	}
+
+	if err := store.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Errorf("failed to delete bucket: %v", err)
+	}
+
+	_, err = bucket.ListVersions(ctx, "", "not a token")
+	if !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected ErrBucketNotFound, got %v", err)
+	}
+}
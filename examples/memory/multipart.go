@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// upload is an in-progress multipart upload.
+type upload struct {
+	info    absos.MultipartUpload
+	options absos.PutOptions
+	parts   map[int]*part
+}
+
+type part struct {
+	data []byte
+	info absos.Part
+}
+
+// InitiateMultipart starts a multipart upload for key.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return absos.MultipartUpload{}, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	info := absos.MultipartUpload{
+		Bucket:    b.name,
+		Key:       key,
+		ID:        hex.EncodeToString(id),
+		Initiated: time.Now(),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.check(); err != nil {
+		return absos.MultipartUpload{}, err
+	}
+
+	b.uploads[info.ID] = &upload{
+		info:    info,
+		options: cloneOptions(absos.NewPutOptions(opts...)),
+		parts:   make(map[int]*part),
+	}
+
+	return info, nil
+}
+
+// UploadPart stores a part of a multipart upload in memory.
+func (b *Bucket) UploadPart(ctx context.Context, u absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	if number < 1 || number > absos.MaxParts {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key,
+			Err: fmt.Errorf("%w: part number %d out of range", absos.ErrInvalidPart, number)}
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	etag := md5.Sum(content)
+	p := &part{
+		data: content,
+		info: absos.Part{
+			Number:  number,
+			Size:    int64(len(content)),
+			ETag:    etag[:],
+			ModTime: time.Now(),
+		},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	up, err := b.upload(u)
+	if err != nil {
+		return absos.Part{}, err
+	}
+
+	up.parts[number] = p
+	return p.info, nil
+}
+
+// ListParts returns the uploaded parts ordered by part number.
+func (b *Bucket) ListParts(ctx context.Context, u absos.MultipartUpload) ([]absos.Part, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	up, err := b.upload(u)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]absos.Part, 0, len(up.parts))
+	for _, p := range up.parts {
+		parts = append(parts, p.info)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+
+	return parts, nil
+}
+
+// CompleteMultipart assembles the object from the listed parts. Part sizes
+// are not checked against absos.MinPartSize.
+func (b *Bucket) CompleteMultipart(ctx context.Context, u absos.MultipartUpload, parts []absos.Part) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	up, err := b.upload(u)
+	if err != nil {
+		return err
+	}
+
+	if len(parts) == 0 {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: fmt.Errorf("%w: no parts", absos.ErrInvalidPart)}
+	}
+
+	var data bytes.Buffer
+	for i, p := range parts {
+		if i > 0 && p.Number <= parts[i-1].Number {
+			return &absos.ObjectError{Bucket: b.name, Key: u.Key,
+				Err: fmt.Errorf("%w: part %d is out of order", absos.ErrInvalidPart, p.Number)}
+		}
+
+		stored, ok := up.parts[p.Number]
+		if !ok || (len(p.ETag) > 0 && !bytes.Equal(p.ETag, stored.info.ETag)) {
+			return &absos.ObjectError{Bucket: b.name, Key: u.Key,
+				Err: fmt.Errorf("%w: part %d does not match an uploaded part", absos.ErrInvalidPart, p.Number)}
+		}
+
+		data.Write(stored.data)
+	}
+
+	checksums := absos.ComputeChecksums(data.Bytes())
+	if err := up.options.Checksums.Verify(checksums); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	etag := md5.Sum(data.Bytes())
+	b.setObject(&object{
+		bucket:    b.name,
+		key:       u.Key,
+		data:      data.Bytes(),
+		etag:      etag[:],
+		checksums: checksums,
+		modTime:   time.Now(),
+		options:   up.options,
+	})
+	delete(b.uploads, u.ID)
+
+	return nil
+}
+
+// AbortMultipart discards the upload and its parts.
+func (b *Bucket) AbortMultipart(ctx context.Context, u absos.MultipartUpload) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.upload(u); err != nil {
+		return err
+	}
+
+	delete(b.uploads, u.ID)
+	return nil
+}
+
+// ListMultipartUploads returns the in-progress uploads of keys with the
+// given prefix, ordered by key and initiation time.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	var uploads []absos.MultipartUpload
+	for _, up := range b.uploads {
+		if strings.HasPrefix(up.info.Key, prefix) {
+			uploads = append(uploads, up.info)
+		}
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].Key != uploads[j].Key {
+			return uploads[i].Key < uploads[j].Key
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+
+	return uploads, nil
+}
+
+// upload returns the in-progress upload identified by u.
+// The caller must hold the bucket's lock.
+func (b *Bucket) upload(u absos.MultipartUpload) (*upload, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	up, ok := b.uploads[u.ID]
+	if !ok || up.info.Key != u.Key {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: absos.ErrUploadNotFound}
+	}
+	return up, nil
+}
@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/listing"
+)
+
+// Versioning returns the versioning state of the bucket.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return "", &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if err := b.check(); err != nil {
+		return "", err
+	}
+
+	return b.versioning, nil
+}
+
+// SetVersioning enables or suspends versioning. Objects stored before
+// versioning was first enabled become versions with the ID absos.NullVersion.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	if status != absos.VersioningEnabled && status != absos.VersioningSuspended {
+		return &absos.BucketError{Bucket: b.name, Err: fmt.Errorf("invalid versioning status %q", status)}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.check(); err != nil {
+		return err
+	}
+
+	if b.versioning == absos.VersioningDisabled {
+		b.versions = make(map[string][]*object, len(b.objects))
+		for key, obj := range b.objects {
+			// Objects may be in use by readers, so they are not modified
+			v := *obj
+			v.version = absos.NullVersion
+			b.objects[key] = &v
+			b.versions[key] = []*object{&v}
+		}
+	}
+
+	b.versioning = status
+	return nil
+}
+
+// setObject stores obj as the latest version of its key, which hides the
+// object if obj is a delete marker. The caller must hold the bucket's
+// write lock.
+func (b *Bucket) setObject(obj *object) {
+	switch b.versioning {
+	case absos.VersioningEnabled:
+		b.lastVersion++
+		obj.version = fmt.Sprintf("%016x", b.lastVersion)
+		b.versions[obj.key] = append(b.versions[obj.key], obj)
+	case absos.VersioningSuspended:
+		obj.version = absos.NullVersion
+		b.versions[obj.key] = append(withoutVersion(b.versions[obj.key], absos.NullVersion), obj)
+	}
+
+	if obj.marker {
+		delete(b.objects, obj.key)
+	} else {
+		b.objects[obj.key] = obj
+	}
+}
+
+// deleteObject deletes the object for key, or hides it behind a delete
+// marker if versioning has been enabled. The caller must hold the bucket's
+// write lock.
+func (b *Bucket) deleteObject(key string) {
+	if b.versioning == absos.VersioningDisabled {
+		delete(b.objects, key)
+		return
+	}
+
+	b.setObject(&object{bucket: b.name, key: key, modTime: time.Now(), marker: true})
+}
+
+// withoutVersion returns the versions other than the one with the given ID.
+func withoutVersion(versions []*object, id string) []*object {
+	kept := make([]*object, 0, len(versions))
+	for _, v := range versions {
+		if v.version != id {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// history returns the versions of key, oldest first.
+// The caller must hold the bucket's lock.
+func (b *Bucket) history(key string) []*object {
+	if b.versioning != absos.VersioningDisabled {
+		return b.versions[key]
+	}
+	if obj, exists := b.objects[key]; exists {
+		return []*object{obj}
+	}
+	return nil
+}
+
+// findVersion returns the version of key with the given ID.
+// The caller must hold the bucket's lock.
+func (b *Bucket) findVersion(key, id string) (*object, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range b.history(key) {
+		if versionID(v) == id {
+			return v, nil
+		}
+	}
+
+	return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrVersionNotFound}
+}
+
+// versionID returns the ID listed for v, which is absos.NullVersion for
+// objects stored while versioning was disabled.
+func versionID(v *object) string {
+	if v.version == "" {
+		return absos.NullVersion
+	}
+	return v.version
+}
+
+// readVersion returns the version of key with the given ID, which must not
+// be a delete marker.
+func (b *Bucket) readVersion(ctx context.Context, key, id string) (*object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	v, err := b.findVersion(key, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.marker {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key,
+			Err: fmt.Errorf("%w: version %s is a delete marker", absos.ErrObjectNotFound, id)}
+	}
+
+	return v, nil
+}
+
+// GetVersion retrieves a version of an object from memory.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	v, err := b.readVersion(ctx, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return v.Open(ctx)
+}
+
+// HeadVersion retrieves the metadata of a version of an object.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	v, err := b.readVersion(ctx, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteVersion permanently removes a version or delete marker. The
+// previous version becomes the latest if the latest one is removed.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.findVersion(key, versionID); err != nil {
+		return err
+	}
+
+	if b.versioning == absos.VersioningDisabled {
+		delete(b.objects, key)
+		return nil
+	}
+
+	versions := withoutVersion(b.versions[key], versionID)
+	if len(versions) == 0 {
+		delete(b.versions, key)
+		delete(b.objects, key)
+		return nil
+	}
+
+	b.versions[key] = versions
+	if latest := versions[len(versions)-1]; latest.marker {
+		delete(b.objects, key)
+	} else {
+		b.objects[key] = latest
+	}
+
+	return nil
+}
+
+// ListVersions returns a page of the versions of the objects with the
+// given prefix, in key order and from the newest to the oldest version of
+// each key. Tokens remain valid while versions change, unless the version
+// they continue from is deleted, in which case the listing continues with
+// the next key.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	afterKey, afterVersion, err := decodeVersionToken(token)
+	if err != nil {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	keys := make([]string, 0, len(b.objects))
+	if b.versioning == absos.VersioningDisabled {
+		for key := range b.objects {
+			keys = append(keys, key)
+		}
+	} else {
+		for key := range b.versions {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	p := &versionPage{last: true}
+	for _, key := range keys[sort.SearchStrings(keys, max(prefix, afterKey)):] {
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+
+		history := b.history(key)
+		newest := len(history) - 1
+
+		if key == afterKey {
+			// Continue after the version the token points to
+			i := newest
+			for i >= 0 && versionID(history[i]) != afterVersion {
+				i--
+			}
+			newest = i - 1
+		}
+
+		for i := newest; i >= 0; i-- {
+			if len(p.versions) == b.pageSize {
+				last := p.versions[len(p.versions)-1]
+				p.next = encodeVersionToken(last.Key, last.VersionID)
+				p.last = false
+				return p, nil
+			}
+
+			v := history[i]
+			p.versions = append(p.versions, absos.ObjectVersion{
+				Key:          key,
+				VersionID:    versionID(v),
+				IsLatest:     i == len(history)-1,
+				DeleteMarker: v.marker,
+				Size:         v.Size(),
+				ModTime:      v.modTime,
+				ETag:         v.etag,
+			})
+		}
+	}
+
+	return p, nil
+}
+
+func encodeVersionToken(key, version string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + "\x00" + version))
+}
+
+func decodeVersionToken(token string) (key, version string, err error) {
+	if token == "" {
+		return "", "", nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", listing.ErrInvalidToken
+	}
+
+	key, version, ok := strings.Cut(string(data), "\x00")
+	if !ok {
+		return "", "", listing.ErrInvalidToken
+	}
+
+	return key, version, nil
+}
+
+type versionPage struct {
+	versions []absos.ObjectVersion
+	next     string
+	last     bool
+}
+
+func (p *versionPage) Versions() []absos.ObjectVersion { return p.versions }
+func (p *versionPage) NextPage() string                { return p.next }
+func (p *versionPage) Last() bool                      { return p.last }
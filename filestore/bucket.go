@@ -0,0 +1,620 @@
+package filestore
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/listing"
+)
+
+// Bucket is a filesystem implementation of absos.Bucket.
+type Bucket struct {
+	store   *Store
+	name    string
+	created time.Time
+}
+
+// Name returns the bucket name.
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+// CreationTime returns when the bucket was created.
+func (b *Bucket) CreationTime() time.Time {
+	if b.created.IsZero() {
+		return b.creationTime()
+	}
+	return b.created
+}
+
+// Owner returns nil for this implementation.
+func (b *Bucket) Owner() absos.Owner {
+	return nil
+}
+
+// ObjectPage returns a page of objects in lexicographic key order.
+// Keys containing the delimiter after the prefix are collapsed into common
+// prefixes. Continuation tokens remain valid while objects change.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	if err := b.begin(ctx); err != nil {
+		return nil, err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	opts := listing.Options{
+		Prefix:    prefix,
+		Delimiter: delimiter,
+		Token:     token,
+		MaxKeys:   b.store.pageSize,
+	}
+
+	keys, err := walkKeys(b.dir(), opts)
+	if err != nil {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	lp, err := listing.List(keys, opts)
+	if err != nil {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	p := &page{
+		objects:  make([]absos.Object, 0, len(lp.Keys)),
+		prefixes: lp.Prefixes,
+		next:     lp.Next,
+		last:     !lp.Truncated,
+	}
+
+	for _, key := range lp.Keys {
+		obj, err := b.stat(key)
+		if errors.Is(err, absos.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.objects = append(p.objects, obj)
+	}
+
+	return p, nil
+}
+
+// Head retrieves object metadata from the file and its sidecar.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	return b.HeadIf(ctx, key, absos.Conditions{})
+}
+
+// HeadIf retrieves object metadata if cond holds.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	if err := b.beginObject(ctx, key); err != nil {
+		return nil, err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return b.statIf(key, cond)
+}
+
+// statIf is like stat but also evaluates cond for a read of the object.
+// The caller must hold the bucket's lock.
+func (b *Bucket) statIf(key string, cond absos.Conditions) (*object, error) {
+	obj, err := b.stat(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cond.CheckRead(obj); err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return obj, nil
+}
+
+// checkWrite evaluates cond against the current object for key.
+// The caller must hold the bucket's write lock.
+func (b *Bucket) checkWrite(key string, cond absos.Conditions) error {
+	var header absos.ObjectHeader
+
+	obj, err := b.stat(key)
+	switch {
+	case err == nil:
+		header = obj
+	case !errors.Is(err, absos.ErrObjectNotFound):
+		return err
+	}
+
+	if err := cond.CheckWrite(header); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return nil
+}
+
+// PutBatch stores each object produced by the iterator.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	return absos.PutEach(ctx, b, iter)
+}
+
+// Put writes the object to a temporary file and renames it into place.
+// The attributes set by opts are persisted in the object's sidecar.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	return b.PutStream(ctx, key, data, opts...)
+}
+
+// PutStream is like Put but reads the object from a plain reader. Nothing
+// is renamed into place unless data is read completely.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	return b.put(ctx, key, data, absos.Conditions{}, opts...)
+}
+
+// PutIf is like Put but only renames the object into place if cond holds
+// once data has been written.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	return b.put(ctx, key, data, cond, opts...)
+}
+
+// put writes the object to a temporary file and commits it if cond holds.
+func (b *Bucket) put(ctx context.Context, key string, data io.Reader, cond absos.Conditions, opts ...absos.PutOption) error {
+	if err := b.beginObject(ctx, key); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(b.reserved("tmp"), "put-*")
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	hasher := absos.NewChecksumHasher(absos.ChecksumAlgorithms...)
+	_, err = io.Copy(io.MultiWriter(tmp, hash, hasher), &ctxReader{ctx: ctx, r: data})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	options := absos.NewPutOptions(opts...)
+	meta := newSidecar(key, options)
+	meta.ETag = hex.EncodeToString(hash.Sum(nil))
+	meta.Checksums = hasher.Sum()
+
+	if err := options.Checksums.Verify(meta.Checksums); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	lock := b.store.lock(b.name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := b.checkWrite(key, cond); err != nil {
+		return err
+	}
+
+	if err := b.commit(key, tmp.Name(), meta); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return nil
+}
+
+// Copy copies an object from another filesystem bucket, which may belong
+// to a different Store. It returns absos.ErrNotSupported for other buckets.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	from, ok := src.(*Bucket)
+	if !ok {
+		return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: absos.ErrNotSupported}
+	}
+
+	if err := b.beginObject(ctx, dstKey); err != nil {
+		return err
+	}
+
+	tmp, meta, err := from.snapshot(ctx, srcKey, b.reserved("tmp"))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if options := absos.NewCopyOptions(opts...); options.Directive == absos.MetadataReplace {
+		if err := options.Replace.Checksums.Verify(meta.Checksums); err != nil {
+			return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: err}
+		}
+
+		etag, checksums := meta.ETag, meta.Checksums
+		meta = newSidecar(dstKey, options.Replace)
+		meta.ETag, meta.Checksums = etag, checksums
+	}
+
+	lock := b.store.lock(b.name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := b.commit(dstKey, tmp, meta); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: err}
+	}
+
+	return nil
+}
+
+// snapshot copies the object file for key into a temporary file in dir
+// and returns its name along with the object's sidecar.
+func (b *Bucket) snapshot(ctx context.Context, key, dir string) (string, sidecar, error) {
+	if err := b.beginObject(ctx, key); err != nil {
+		return "", sidecar{}, err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	obj, err := b.stat(key)
+	if err != nil {
+		return "", sidecar{}, err
+	}
+
+	f, err := os.Open(b.objectPath(key))
+	if err != nil {
+		return "", sidecar{}, b.fileError(key, err)
+	}
+	defer f.Close()
+
+	tmp, err := os.CreateTemp(dir, "copy-*")
+	if err != nil {
+		return "", sidecar{}, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	_, err = io.Copy(tmp, &ctxReader{ctx: ctx, r: f})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", sidecar{}, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return tmp.Name(), obj.meta, nil
+}
+
+// Get opens the object file for reading. The reader verifies the checksums
+// recorded when the object was stored and reports absos.ErrChecksumMismatch
+// at the end of the contents if the file has changed.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.GetIf(ctx, key, absos.Conditions{})
+}
+
+// GetIf is like Get but only opens the object if cond holds.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	if err := b.beginObject(ctx, key); err != nil {
+		return nil, err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	obj, err := b.statIf(key, cond)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := b.open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return absos.VerifyReader(f, obj.meta.Checksums), nil
+}
+
+// open opens the object file for key.
+func (b *Bucket) open(ctx context.Context, key string) (*os.File, error) {
+	if err := b.beginObject(ctx, key); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(b.objectPath(key))
+	if err != nil {
+		return nil, b.fileError(key, err)
+	}
+
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrObjectNotFound}
+	}
+
+	return f, nil
+}
+
+// GetRange opens the object file at the start of the range.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := b.open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	start, n, err := absos.ResolveRange(info.Size(), offset, length)
+	if err == nil {
+		_, err = f.Seek(start, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return &fileRange{Reader: io.LimitReader(f, n), Closer: f}, nil
+}
+
+// Delete removes the object file, its sidecar and any directories left empty.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	return b.DeleteIf(ctx, key, absos.Conditions{})
+}
+
+// DeleteIf is like Delete but only removes the object if cond holds.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	if err := b.beginObject(ctx, key); err != nil {
+		return err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	obj, err := b.stat(key)
+	if err != nil {
+		return err
+	}
+
+	if err := cond.CheckWrite(obj); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	if err := os.Remove(b.objectPath(key)); err != nil {
+		return b.fileError(key, err)
+	}
+
+	for _, p := range []string{b.metaPath(key), b.nextMetaPath(key)} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+		}
+	}
+
+	b.prune(b.dir(), key)
+	return nil
+}
+
+// begin validates the context and the existence of the bucket.
+func (b *Bucket) begin(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: b.name, Err: err}
+	}
+	return b.check()
+}
+
+// beginObject validates the context, bucket and key of an object operation.
+func (b *Bucket) beginObject(ctx context.Context, key string) error {
+	if err := b.begin(ctx); err != nil {
+		return err
+	}
+
+	if !validKey(key) {
+		return &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrInvalidKey}
+	}
+
+	return nil
+}
+
+// check returns absos.ErrBucketNotFound if the bucket directory is missing.
+func (b *Bucket) check() error {
+	if !validBucketName(b.name) {
+		return &absos.BucketError{Bucket: b.name, Err: absos.ErrBucketNotFound}
+	}
+
+	info, err := os.Stat(b.dir())
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return &absos.BucketError{Bucket: b.name, Err: absos.ErrBucketNotFound}
+	}
+	if err != nil {
+		return &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	// Directories created outside this package are adopted as buckets.
+	if _, err := os.Stat(b.reserved("tmp")); errors.Is(err, fs.ErrNotExist) {
+		if err := initBucket(b.dir()); err != nil {
+			return &absos.BucketError{Bucket: b.name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// commit moves the temporary file into place and writes its sidecar.
+// The caller must hold the bucket's write lock.
+func (b *Bucket) commit(key, tmp string, meta sidecar) error {
+	dst := b.objectPath(key)
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		return fmt.Errorf("%w: key is a prefix of other objects", absos.ErrInvalidKey)
+	}
+
+	for _, p := range []string{dst, b.metaPath(key)} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			if errors.Is(err, syscall.ENOTDIR) || errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("%w: an object exists at a prefix of the key", absos.ErrInvalidKey)
+			}
+			return err
+		}
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return err
+	}
+	meta.Size, meta.ModTime = info.Size(), info.ModTime().UnixNano()
+
+	// The sidecar is staged before the contents are renamed into place,
+	// which commits the object: if renaming the sidecar over the previous
+	// one then fails, stat uses the staged sidecar, which describes the new
+	// contents. Renames keep the modification time of files.
+	staged, err := stageSidecar(b.reserved("tmp"), meta)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged)
+
+	next := b.nextMetaPath(key)
+	if err := os.Rename(staged, next); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(next)
+		return err
+	}
+	return os.Rename(next, b.metaPath(key))
+}
+
+// stat builds the object for key from its file and sidecar.
+// The caller must hold the bucket's lock.
+func (b *Bucket) stat(key string) (*object, error) {
+	info, err := os.Stat(b.objectPath(key))
+	if err != nil {
+		return nil, b.fileError(key, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrObjectNotFound}
+	}
+
+	// A staged sidecar describing the file was left by a commit that
+	// failed after moving the contents into place
+	meta, err := readSidecar(b.nextMetaPath(key))
+	if err == nil && !meta.describes(info) {
+		meta, err = readSidecar(b.metaPath(key))
+	}
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return &object{bucket: b, key: key, info: info, meta: meta}, nil
+}
+
+// prune removes the directories of key below root that are left empty.
+func (b *Bucket) prune(root, key string) {
+	for dir := path.Dir(key); dir != "."; dir = path.Dir(dir) {
+		if os.Remove(filepath.Join(root, filepath.FromSlash(dir))) != nil {
+			return
+		}
+	}
+}
+
+// fileError translates a filesystem error for key into an *absos.ObjectError.
+func (b *Bucket) fileError(key string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, syscall.ENOTDIR):
+		err = absos.ErrObjectNotFound
+	case errors.Is(err, fs.ErrPermission):
+		err = fmt.Errorf("%w: %w", absos.ErrPermissionDenied, err)
+	}
+	return &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+}
+
+// creationTime reads the creation time recorded for the bucket, falling back
+// to the modification time of directories created outside this package.
+func (b *Bucket) creationTime() time.Time {
+	var info bucketInfo
+	data, err := os.ReadFile(b.reserved("bucket.json"))
+	if err == nil && json.Unmarshal(data, &info) == nil {
+		return info.Created
+	}
+
+	if fi, err := os.Stat(b.dir()); err == nil {
+		return fi.ModTime()
+	}
+	return time.Time{}
+}
+
+func (b *Bucket) dir() string {
+	return filepath.Join(b.store.root, b.name)
+}
+
+func (b *Bucket) reserved(name string) string {
+	return filepath.Join(b.dir(), reservedDir, name)
+}
+
+func (b *Bucket) objectPath(key string) string {
+	return filepath.Join(b.dir(), filepath.FromSlash(key))
+}
+
+// metaPath returns the path of the sidecar of key. Sidecars are named after
+// the SHA-256 hash of their key, so that the sidecars of keys such as "a"
+// and "a.json/b" cannot collide, and spread over 256 directories.
+func (b *Bucket) metaPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(b.reserved("meta"), name[:2], name+".json")
+}
+
+// nextMetaPath returns the path of the staged sidecar of key, written before
+// its contents are committed.
+func (b *Bucket) nextMetaPath(key string) string {
+	return b.metaPath(key) + ".next"
+}
+
+// fileRange reads a range of an open file.
+type fileRange struct {
+	io.Reader
+	io.Closer
+}
+
+// ctxReader stops reading once its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+type page struct {
+	objects  []absos.Object
+	prefixes []string
+	next     string
+	last     bool
+}
+
+func (p *page) Objects() []absos.Object { return p.objects }
+func (p *page) Prefixes() []string      { return p.prefixes }
+func (p *page) NextPage() string        { return p.next }
+func (p *page) Last() bool              { return p.last }
@@ -0,0 +1,506 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/internal/listing"
+)
+
+func newTestBucket(t *testing.T, opts ...Option) (*Store, *Bucket) {
+	t.Helper()
+
+	store, err := New(t.TempDir(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if err := store.CreateBucket(context.Background(), "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	return store, store.Bucket("test-bucket")
+}
+
+func TestStoreBuckets(t *testing.T) {
+	store, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, name := range []string{"bucket2", "bucket1"} {
+		if err := store.CreateBucket(ctx, name); err != nil {
+			t.Fatalf("failed to create bucket %s: %v", name, err)
+		}
+	}
+
+	if err := store.CreateBucket(ctx, "bucket1"); !errors.Is(err, absos.ErrBucketAlreadyExists) {
+		t.Errorf("expected ErrBucketAlreadyExists, got %v", err)
+	}
+
+	for _, name := range []string{"", ".hidden", "a/b"} {
+		if err := store.CreateBucket(ctx, name); !errors.Is(err, absos.ErrInvalidBucketName) {
+			t.Errorf("expected ErrInvalidBucketName for %q, got %v", name, err)
+		}
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(buckets) != 2 || buckets[0].Name() != "bucket1" || buckets[1].Name() != "bucket2" {
+		t.Fatalf("expected [bucket1 bucket2], got %v", buckets)
+	}
+
+	if buckets[0].CreationTime().IsZero() {
+		t.Error("expected creation time to be set")
+	}
+
+	if err := store.DeleteBucket(ctx, "bucket1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := store.DeleteBucket(ctx, "bucket1"); !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected ErrBucketNotFound, got %v", err)
+	}
+}
+
+func TestStoreDeleteNonEmptyBucket(t *testing.T) {
+	store, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := bucket.Put(ctx, "dir/test-key", strings.NewReader("test data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := store.DeleteBucket(ctx, "test-bucket"); !errors.Is(err, absos.ErrBucketNotEmpty) {
+		t.Errorf("expected ErrBucketNotEmpty, got %v", err)
+	}
+
+	// Deleting the last object leaves the bucket empty again
+	if err := bucket.Delete(ctx, "dir/test-key"); err != nil {
+		t.Fatalf("failed to delete object: %v", err)
+	}
+
+	if err := store.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestBucketPutGetHead(t *testing.T) {
+	store, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	testData := "Hello, World!"
+	if err := bucket.Put(ctx, "docs/hello.txt", strings.NewReader(testData)); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	// The object is a plain file under the bucket directory
+	content, err := os.ReadFile(filepath.Join(store.root, "test-bucket", "docs", "hello.txt"))
+	if err != nil || string(content) != testData {
+		t.Fatalf("expected file with %q, got %q (%v)", testData, content, err)
+	}
+
+	reader, err := bucket.Get(ctx, "docs/hello.txt")
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	defer reader.Close()
+
+	content, err = io.ReadAll(reader)
+	if err != nil || string(content) != testData {
+		t.Errorf("expected %q, got %q (%v)", testData, content, err)
+	}
+
+	header, err := bucket.Head(ctx, "docs/hello.txt")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.Size() != int64(len(testData)) {
+		t.Errorf("expected size %d, got %d", len(testData), header.Size())
+	}
+
+	if !strings.HasPrefix(header.MimeType(), "text/plain") {
+		t.Errorf("expected text/plain mime type, got %q", header.MimeType())
+	}
+
+	sum := md5.Sum([]byte(testData))
+	if string(header.ETag()) != string(sum[:]) {
+		t.Errorf("expected ETag %x, got %x", sum, header.ETag())
+	}
+}
+
+func TestBucketPersistence(t *testing.T) {
+	store, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("test data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	// A new store over the same root sees the object and its sidecar
+	reopened, err := New(store.root)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+
+	header, err := reopened.Bucket("test-bucket").Head(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if len(header.ETag()) != md5.Size {
+		t.Errorf("expected persisted ETag, got %x", header.ETag())
+	}
+}
+
+func TestBucketChecksumMismatch(t *testing.T) {
+	_, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("test data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	// Contents changed behind the store's back fail verification on EOF
+	if err := os.WriteFile(bucket.objectPath("test-key"), []byte("bad! data"), 0o644); err != nil {
+		t.Fatalf("failed to corrupt object: %v", err)
+	}
+
+	r, err := bucket.Get(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := io.ReadAll(r); !errors.Is(err, absos.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestBucketInterruptedCommit(t *testing.T) {
+	_, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("old data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	meta := bucket.metaPath("test-key")
+	old, err := os.ReadFile(meta)
+	if err != nil {
+		t.Fatalf("failed to read sidecar: %v", err)
+	}
+
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("new data!"), absos.WithContentType("text/plain")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	// Leave the sidecars as a commit failing after moving the new contents
+	// into place does
+	if err := os.Rename(meta, bucket.nextMetaPath("test-key")); err != nil {
+		t.Fatalf("failed to stage sidecar: %v", err)
+	}
+	if err := os.WriteFile(meta, old, 0o644); err != nil {
+		t.Fatalf("failed to restore sidecar: %v", err)
+	}
+
+	header, err := bucket.Head(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+	sum := md5.Sum([]byte("new data!"))
+	if !bytes.Equal(header.ETag(), sum[:]) || header.MimeType() != "text/plain" {
+		t.Errorf("expected the metadata of the new contents, got %x and %q", header.ETag(), header.MimeType())
+	}
+
+	// The staged sidecar of a commit failing before that is ignored
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("newest")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	if err := os.WriteFile(bucket.nextMetaPath("test-key"), old, 0o644); err != nil {
+		t.Fatalf("failed to stage sidecar: %v", err)
+	}
+
+	header, err = bucket.Head(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+	sum = md5.Sum([]byte("newest"))
+	if !bytes.Equal(header.ETag(), sum[:]) {
+		t.Errorf("expected the metadata of the newest contents, got %x", header.ETag())
+	}
+}
+
+func TestMultipartPersistence(t *testing.T) {
+	store, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	upload, err := bucket.InitiateMultipart(ctx, "big", absos.WithMetadata(map[string]string{"job": "42"}))
+	if err != nil {
+		t.Fatalf("failed to initiate upload: %v", err)
+	}
+
+	if _, err := bucket.UploadPart(ctx, upload, 1, strings.NewReader("part one, ")); err != nil {
+		t.Fatalf("failed to upload part: %v", err)
+	}
+
+	// After a restart the upload is found and can be completed
+	reopened, err := New(store.root)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	bucket = reopened.Bucket("test-bucket")
+
+	uploads, err := bucket.ListMultipartUploads(ctx, "")
+	if err != nil || len(uploads) != 1 || uploads[0].ID != upload.ID {
+		t.Fatalf("expected upload %s to be listed, got %v (%v)", upload.ID, uploads, err)
+	}
+
+	if _, err := bucket.UploadPart(ctx, uploads[0], 2, strings.NewReader("part two")); err != nil {
+		t.Fatalf("failed to upload part: %v", err)
+	}
+
+	parts, err := bucket.ListParts(ctx, uploads[0])
+	if err != nil {
+		t.Fatalf("failed to list parts: %v", err)
+	}
+
+	if err := bucket.CompleteMultipart(ctx, uploads[0], parts); err != nil {
+		t.Fatalf("failed to complete upload: %v", err)
+	}
+
+	header, err := bucket.Head(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.Size() != int64(len("part one, part two")) || header.Metadata()["job"] != "42" {
+		t.Errorf("unexpected object size %d and metadata %v", header.Size(), header.Metadata())
+	}
+
+	sum := md5.Sum([]byte("part one, part two"))
+	if string(header.ETag()) != string(sum[:]) {
+		t.Errorf("expected ETag %x, got %x", sum, header.ETag())
+	}
+
+	// The upload directory is removed
+	if _, err := os.Stat(bucket.uploadPath(upload.ID)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected upload directory to be removed, got %v", err)
+	}
+}
+
+func TestMultipartInvalidID(t *testing.T) {
+	_, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"", "..", "../../etc", "0123"} {
+		upload := absos.MultipartUpload{Bucket: "test-bucket", Key: "key", ID: id}
+		if _, err := bucket.ListParts(ctx, upload); !errors.Is(err, absos.ErrUploadNotFound) {
+			t.Errorf("expected ErrUploadNotFound for ID %q, got %v", id, err)
+		}
+	}
+}
+
+func TestBucketInvalidKeys(t *testing.T) {
+	_, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/abs", "a//b", "../escape", "a/./b", "dir/", ".absos/meta", `back\slash`} {
+		err := bucket.Put(ctx, key, strings.NewReader("data"))
+		if !errors.Is(err, absos.ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+
+	// A key cannot be both an object and a prefix of other objects
+	if err := bucket.Put(ctx, "a", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := bucket.Put(ctx, "a/b", strings.NewReader("data")); !errors.Is(err, absos.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for a/b, got %v", err)
+	}
+
+	if err := bucket.Put(ctx, "c/d", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := bucket.Put(ctx, "c", strings.NewReader("data")); !errors.Is(err, absos.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for c, got %v", err)
+	}
+
+	// Listing never escapes the bucket
+	page, err := bucket.ObjectPage(ctx, "../", "", "")
+	if err != nil || len(page.Objects()) != 0 {
+		t.Errorf("expected empty listing, got %v (%v)", page, err)
+	}
+}
+
+func TestBucketNotFoundErrors(t *testing.T) {
+	store, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if _, err := bucket.Get(ctx, "missing"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound from Get, got %v", err)
+	}
+
+	if _, err := bucket.Head(ctx, "missing"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound from Head, got %v", err)
+	}
+
+	err := bucket.Delete(ctx, "missing")
+	var objErr *absos.ObjectError
+	if !errors.As(err, &objErr) || !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected *absos.ObjectError with ErrObjectNotFound, got %v", err)
+	}
+
+	err = store.Bucket("missing-bucket").Put(ctx, "key", strings.NewReader("data"))
+	if !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected ErrBucketNotFound, got %v", err)
+	}
+}
+
+func TestBucketObjectPage(t *testing.T) {
+	_, bucket := newTestBucket(t, WithPageSize(2))
+	ctx := context.Background()
+
+	keys := []string{"a.txt", "dir-file.txt", "dir/b.txt", "dir/sub/c.txt", "e.txt"}
+	for _, key := range keys {
+		if err := bucket.Put(ctx, key, strings.NewReader(key)); err != nil {
+			t.Fatalf("failed to put object %s: %v", key, err)
+		}
+	}
+
+	var objects, prefixes []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > len(keys) {
+			t.Fatal("pagination did not terminate")
+		}
+
+		page, err := bucket.ObjectPage(ctx, "", "/", token)
+		if err != nil {
+			t.Fatalf("failed to list objects: %v", err)
+		}
+
+		for _, obj := range page.Objects() {
+			objects = append(objects, obj.Key())
+		}
+		prefixes = append(prefixes, page.Prefixes()...)
+
+		if page.Last() {
+			break
+		}
+		token = page.NextPage()
+	}
+
+	if strings.Join(objects, ",") != "a.txt,dir-file.txt,e.txt" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	if strings.Join(prefixes, ",") != "dir/" {
+		t.Errorf("unexpected prefixes %v", prefixes)
+	}
+
+	page, err := bucket.ObjectPage(ctx, "dir/", "", "")
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+
+	if len(page.Objects()) != 2 || page.Objects()[1].Key() != "dir/sub/c.txt" {
+		t.Errorf("unexpected objects under dir/: %v", page.Objects())
+	}
+}
+
+func TestBucketObjectPageWalk(t *testing.T) {
+	_, bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	keys := []string{"a", "a-b", "a.txt", "b/1", "b/2", "b/c/3", "b0", "c/d-e/f", "c/d-g", "c/h"}
+	for _, key := range keys {
+		if err := bucket.Put(ctx, key, strings.NewReader(key)); err != nil {
+			t.Fatalf("failed to put object %s: %v", key, err)
+		}
+	}
+
+	// Collapsed directories are represented by a single key
+	walked, err := walkKeys(bucket.dir(), listing.Options{Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("failed to walk: %v", err)
+	}
+	if strings.Join(walked, ",") != "a,a-b,a.txt,b/1,b0,c/d-e/f" {
+		t.Errorf("unexpected keys %v", walked)
+	}
+
+	// Pages walk the same entries as a listing of all keys
+	for _, prefix := range []string{"", "a", "b/", "c/", "c/d", "x/"} {
+		for _, delimiter := range []string{"", "/", "-"} {
+			for maxKeys := 1; maxKeys <= len(keys)+1; maxKeys++ {
+				opts := listing.Options{Prefix: prefix, Delimiter: delimiter, MaxKeys: maxKeys}
+				for pages := 0; ; pages++ {
+					if pages > len(keys) {
+						t.Fatal("pagination did not terminate")
+					}
+
+					expected, err := listing.List(keys, opts)
+					if err != nil {
+						t.Fatalf("failed to list: %v", err)
+					}
+
+					walked, err := walkKeys(bucket.dir(), opts)
+					if err != nil {
+						t.Fatalf("failed to walk: %v", err)
+					}
+					got, err := listing.List(walked, opts)
+					if err != nil {
+						t.Fatalf("failed to list: %v", err)
+					}
+
+					if !reflect.DeepEqual(got, expected) {
+						t.Fatalf("%+v: expected %+v, got %+v", opts, expected, got)
+					}
+					if !got.Truncated {
+						break
+					}
+					opts.Token = got.Next
+				}
+			}
+		}
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	_, bucket := newTestBucket(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := bucket.Put(ctx, "key", strings.NewReader("data")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		store, err := New(t.TempDir(), WithPageSize(2))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		return store
+	})
+}
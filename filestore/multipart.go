@@ -0,0 +1,398 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// Multipart uploads are kept in <bucket>/.absos/uploads/<id>. The directory
+// holds upload.json and one file per part named <number>-<md5>, so that the
+// ETag of a part is committed atomically with its data.
+
+// uploadInfo is persisted as upload.json in the directory of an upload.
+type uploadInfo struct {
+	Key       string    `json:"key"`
+	Initiated time.Time `json:"initiated"`
+	Meta      sidecar   `json:"meta"`
+
+	// Checksums are the expected checksums of the completed object.
+	Checksums absos.Checksums `json:"checksums,omitempty"`
+}
+
+// InitiateMultipart creates the directory of a new multipart upload.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	if err := b.beginObject(ctx, key); err != nil {
+		return absos.MultipartUpload{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return absos.MultipartUpload{}, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	options := absos.NewPutOptions(opts...)
+	info := uploadInfo{
+		Key:       key,
+		Initiated: time.Now().UTC(),
+		Meta:      newSidecar(key, options),
+		Checksums: options.Checksums,
+	}
+
+	upload := absos.MultipartUpload{
+		Bucket:    b.name,
+		Key:       key,
+		ID:        hex.EncodeToString(id),
+		Initiated: info.Initiated,
+	}
+
+	data, err := json.Marshal(info)
+	if err == nil {
+		err = os.MkdirAll(b.uploadPath(upload.ID), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(b.uploadPath(upload.ID), "upload.json"), data, 0o644)
+	}
+	if err != nil {
+		_ = os.RemoveAll(b.uploadPath(upload.ID))
+		return absos.MultipartUpload{}, &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	return upload, nil
+}
+
+// UploadPart writes the part to a temporary file and renames it into the
+// directory of the upload, replacing any part with the same number.
+func (b *Bucket) UploadPart(ctx context.Context, u absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	if err := b.beginObject(ctx, u.Key); err != nil {
+		return absos.Part{}, err
+	}
+
+	if number < 1 || number > absos.MaxParts {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key,
+			Err: fmt.Errorf("%w: part number %d out of range", absos.ErrInvalidPart, number)}
+	}
+
+	if _, err := b.readUpload(u); err != nil {
+		return absos.Part{}, err
+	}
+
+	tmp, err := os.CreateTemp(b.reserved("tmp"), "part-*")
+	if err != nil {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), &ctxReader{ctx: ctx, r: data})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	part := absos.Part{Number: number, Size: size, ETag: hash.Sum(nil), ModTime: time.Now()}
+
+	lock := b.store.lock(b.name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The upload may have been completed or aborted in the meantime
+	parts, err := b.readParts(u)
+	if err != nil {
+		return absos.Part{}, err
+	}
+
+	if err := os.Rename(tmp.Name(), b.partPath(u.ID, part)); err != nil {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	if old, ok := parts[number]; ok && !bytes.Equal(old.ETag, part.ETag) {
+		_ = os.Remove(b.partPath(u.ID, old))
+	}
+
+	return part, nil
+}
+
+// ListParts returns the parts found in the directory of the upload.
+func (b *Bucket) ListParts(ctx context.Context, u absos.MultipartUpload) ([]absos.Part, error) {
+	if err := b.beginObject(ctx, u.Key); err != nil {
+		return nil, err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	found, err := b.readParts(u)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]absos.Part, 0, len(found))
+	for _, p := range found {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+
+	return parts, nil
+}
+
+// CompleteMultipart concatenates the parts into a temporary file, renames
+// it into place and removes the upload. Part sizes are not checked against
+// absos.MinPartSize.
+func (b *Bucket) CompleteMultipart(ctx context.Context, u absos.MultipartUpload, parts []absos.Part) error {
+	if err := b.beginObject(ctx, u.Key); err != nil {
+		return err
+	}
+
+	info, err := b.readUpload(u)
+	if err != nil {
+		return err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	found, err := b.readParts(u)
+	lock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if len(parts) == 0 {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: fmt.Errorf("%w: no parts", absos.ErrInvalidPart)}
+	}
+
+	files := make([]string, len(parts))
+	for i, p := range parts {
+		if i > 0 && p.Number <= parts[i-1].Number {
+			return &absos.ObjectError{Bucket: b.name, Key: u.Key,
+				Err: fmt.Errorf("%w: part %d is out of order", absos.ErrInvalidPart, p.Number)}
+		}
+
+		stored, ok := found[p.Number]
+		if !ok || (len(p.ETag) > 0 && !bytes.Equal(p.ETag, stored.ETag)) {
+			return &absos.ObjectError{Bucket: b.name, Key: u.Key,
+				Err: fmt.Errorf("%w: part %d does not match an uploaded part", absos.ErrInvalidPart, p.Number)}
+		}
+		files[i] = b.partPath(u.ID, stored)
+	}
+
+	tmp, err := os.CreateTemp(b.reserved("tmp"), "complete-*")
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	hasher := absos.NewChecksumHasher(absos.ChecksumAlgorithms...)
+	err = concat(ctx, io.MultiWriter(tmp, hash, hasher), files)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	meta := info.Meta
+	meta.ETag = hex.EncodeToString(hash.Sum(nil))
+	meta.Checksums = hasher.Sum()
+
+	if err := info.Checksums.Verify(meta.Checksums); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := b.readUpload(u); err != nil {
+		return err
+	}
+
+	if err := b.commit(u.Key, tmp.Name(), meta); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	_ = os.RemoveAll(b.uploadPath(u.ID))
+	return nil
+}
+
+// AbortMultipart removes the directory of the upload.
+func (b *Bucket) AbortMultipart(ctx context.Context, u absos.MultipartUpload) error {
+	if err := b.beginObject(ctx, u.Key); err != nil {
+		return err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := b.readUpload(u); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(b.uploadPath(u.ID)); err != nil {
+		return &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	return nil
+}
+
+// ListMultipartUploads returns the uploads found in the reserved directory.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	if err := b.begin(ctx); err != nil {
+		return nil, err
+	}
+
+	lock := b.store.lock(b.name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	entries, err := os.ReadDir(b.reserved("uploads"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, &absos.BucketError{Bucket: b.name, Err: err}
+	}
+
+	var uploads []absos.MultipartUpload
+	for _, e := range entries {
+		u := absos.MultipartUpload{Bucket: b.name, ID: e.Name()}
+
+		info, err := b.readUpload(u)
+		if err != nil || !strings.HasPrefix(info.Key, prefix) {
+			continue
+		}
+
+		u.Key, u.Initiated = info.Key, info.Initiated
+		uploads = append(uploads, u)
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].Key != uploads[j].Key {
+			return uploads[i].Key < uploads[j].Key
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+
+	return uploads, nil
+}
+
+// readUpload reads the upload.json of u. It returns absos.ErrUploadNotFound
+// if the upload does not exist or belongs to another key.
+func (b *Bucket) readUpload(u absos.MultipartUpload) (uploadInfo, error) {
+	var info uploadInfo
+
+	if !validUploadID(u.ID) {
+		return info, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: absos.ErrUploadNotFound}
+	}
+
+	data, err := os.ReadFile(filepath.Join(b.uploadPath(u.ID), "upload.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return info, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: absos.ErrUploadNotFound}
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &info)
+	}
+	if err != nil {
+		return info, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	if u.Key != "" && info.Key != u.Key {
+		return info, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: absos.ErrUploadNotFound}
+	}
+
+	return info, nil
+}
+
+// readParts returns the parts of u by number.
+// The caller must hold the bucket's lock.
+func (b *Bucket) readParts(u absos.MultipartUpload) (map[int]absos.Part, error) {
+	if _, err := b.readUpload(u); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(b.uploadPath(u.ID))
+	if err != nil {
+		return nil, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	parts := make(map[int]absos.Part)
+	for _, e := range entries {
+		num, etag, ok := strings.Cut(e.Name(), "-")
+		if !ok {
+			continue
+		}
+
+		number, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+
+		sum, err := hex.DecodeString(etag)
+		if err != nil {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+		}
+
+		parts[number] = absos.Part{Number: number, Size: info.Size(), ETag: sum, ModTime: info.ModTime()}
+	}
+
+	return parts, nil
+}
+
+// concat copies the contents of files to w in order.
+func concat(ctx context.Context, w io.Writer, files []string) error {
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, &ctxReader{ctx: ctx, r: f})
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validUploadID reports whether id has the form of the IDs generated by
+// InitiateMultipart, so that it can safely be used in a path.
+func validUploadID(id string) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == 16
+}
+
+func (b *Bucket) uploadPath(id string) string {
+	return filepath.Join(b.reserved("uploads"), id)
+}
+
+func (b *Bucket) partPath(id string, p absos.Part) string {
+	return filepath.Join(b.uploadPath(id), fmt.Sprintf("%05d-%s", p.Number, hex.EncodeToString(p.ETag)))
+}
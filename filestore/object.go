@@ -0,0 +1,158 @@
+package filestore
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// sidecar holds the object attributes persisted next to the object file.
+type sidecar struct {
+	MimeType           string            `json:"mime_type,omitempty"`
+	ContentEncoding    string            `json:"content_encoding,omitempty"`
+	CacheControl       string            `json:"cache_control,omitempty"`
+	ContentDisposition string            `json:"content_disposition,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	StorageClass       string            `json:"storage_class,omitempty"`
+	SSE                *absos.SSE        `json:"sse,omitempty"`
+	ETag               string            `json:"etag,omitempty"`
+	Checksums          absos.Checksums   `json:"checksums,omitempty"`
+
+	// Size and ModTime identify the object file the sidecar was written
+	// for, in nanoseconds since the epoch, so that a staged sidecar can be
+	// matched with the contents it describes.
+	Size    int64 `json:"size,omitempty"`
+	ModTime int64 `json:"mod_time,omitempty"`
+}
+
+// describes reports whether meta was written for the object file of info.
+func (meta sidecar) describes(info fs.FileInfo) bool {
+	return meta.ModTime != 0 && meta.ModTime == info.ModTime().UnixNano() && meta.Size == info.Size()
+}
+
+// newSidecar returns the sidecar recording options for key. The MIME type
+// defaults to the one registered for the extension of key.
+func newSidecar(key string, options absos.PutOptions) sidecar {
+	meta := sidecar{
+		MimeType:           options.ContentType,
+		ContentEncoding:    options.ContentEncoding,
+		CacheControl:       options.CacheControl,
+		ContentDisposition: options.ContentDisposition,
+		Metadata:           options.Metadata,
+		StorageClass:       options.StorageClass,
+		SSE:                options.SSE,
+	}
+	if meta.MimeType == "" {
+		meta.MimeType = mime.TypeByExtension(path.Ext(key))
+	}
+	return meta
+}
+
+// readSidecar reads the sidecar at path. Objects written outside this
+// package have no sidecar, which is not an error.
+func readSidecar(path string) (sidecar, error) {
+	var meta sidecar
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+// stageSidecar writes meta to a temporary file in tmpDir, to be renamed to
+// the staged sidecar of an object, and returns its path.
+func stageSidecar(tmpDir string, meta sidecar) (string, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "meta-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// object implements both absos.Object and absos.ObjectHeader.
+type object struct {
+	bucket *Bucket
+	key    string
+	info   fs.FileInfo
+	meta   sidecar
+}
+
+func (o *object) Bucket() string                   { return o.bucket.name }
+func (o *object) Key() string                      { return o.key }
+func (o *object) Size() int64                      { return o.info.Size() }
+func (o *object) ModTime() time.Time               { return o.info.ModTime() }
+func (o *object) AccessTime() time.Time            { return o.info.ModTime() }
+func (o *object) ContentEncoding() string          { return o.meta.ContentEncoding }
+func (o *object) CacheControl() string             { return o.meta.CacheControl }
+func (o *object) ContentDisposition() string       { return o.meta.ContentDisposition }
+func (o *object) Metadata() map[string]string      { return o.meta.Metadata }
+func (o *object) Version() string                  { return "" }
+func (o *object) Redirect() string                 { return "" }
+func (o *object) ServerSideEncryption() *absos.SSE { return o.meta.SSE }
+func (o *object) Checksums() absos.Checksums       { return o.meta.Checksums }
+
+func (o *object) StorageClass() string {
+	if o.meta.StorageClass == "" {
+		return "STANDARD"
+	}
+	return o.meta.StorageClass
+}
+
+func (o *object) ETag() []byte {
+	etag, err := hex.DecodeString(o.meta.ETag)
+	if err != nil || len(etag) == 0 {
+		return nil
+	}
+	return etag
+}
+
+func (o *object) MimeType() string {
+	if o.meta.MimeType != "" {
+		return o.meta.MimeType
+	}
+	if t := mime.TypeByExtension(path.Ext(o.key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
+	return o.bucket.Head(ctx, o.key)
+}
+
+func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
+	return o.bucket.Get(ctx, o.key)
+}
+
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return o.bucket.GetRange(ctx, o.key, offset, length)
+}
@@ -0,0 +1,374 @@
+// Package filestore implements the absos interfaces on the local filesystem.
+//
+// Buckets are directories under a root directory and objects are files
+// within them, so a key such as "photos/2024/cat.jpg" is stored at
+// <root>/<bucket>/photos/2024/cat.jpg. Object attributes that files cannot
+// carry, such as the MIME type, user metadata and ETag, are persisted in
+// sidecar files under the reserved <root>/<bucket>/.absos directory.
+//
+// Objects are written to a temporary file and renamed into place, so readers
+// never observe partially written objects. Because keys map onto paths, a
+// key cannot also be a "directory" of other keys: "a" and "a/b" cannot both
+// exist, and keys with empty, "." or ".." segments are rejected with
+// absos.ErrInvalidKey.
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/listing"
+)
+
+// reservedDir is the per-bucket directory holding sidecar and temporary files.
+const reservedDir = ".absos"
+
+// Store is a filesystem implementation of absos.ObjectStore.
+type Store struct {
+	root     string
+	pageSize int
+
+	mu    sync.Mutex
+	locks map[string]*sync.RWMutex
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithPageSize sets the maximum number of entries returned by a single
+// ObjectPage call. The default is 1000.
+func WithPageSize(n int) Option {
+	return func(s *Store) {
+		s.pageSize = n
+	}
+}
+
+// New creates a store rooted at the given directory, creating it if necessary.
+func New(root string, opts ...Option) (*Store, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		root:     root,
+		pageSize: listing.DefaultMaxKeys,
+		locks:    make(map[string]*sync.RWMutex),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Bucket returns a handle to the named bucket without checking that it exists.
+// Operations on a missing bucket fail with absos.ErrBucketNotFound.
+func (s *Store) Bucket(name string) *Bucket {
+	return &Bucket{store: s, name: name}
+}
+
+// CreateBucket creates the bucket directory.
+func (s *Store) CreateBucket(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
+	if !validBucketName(name) {
+		return &absos.BucketError{Bucket: name, Err: absos.ErrInvalidBucketName}
+	}
+
+	dir := filepath.Join(s.root, name)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return &absos.BucketError{Bucket: name, Err: absos.ErrBucketAlreadyExists}
+		}
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
+	if err := initBucket(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
+	return nil
+}
+
+// DeleteBucket removes an empty bucket directory.
+func (s *Store) DeleteBucket(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
+	b := s.Bucket(name)
+	if err := b.check(); err != nil {
+		return err
+	}
+
+	lock := s.lock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entries, err := os.ReadDir(b.dir())
+	if err != nil {
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
+	for _, e := range entries {
+		if e.Name() != reservedDir {
+			return &absos.BucketError{Bucket: name, Err: absos.ErrBucketNotEmpty}
+		}
+	}
+
+	if err := os.RemoveAll(b.dir()); err != nil {
+		return &absos.BucketError{Bucket: name, Err: err}
+	}
+
+	return nil
+}
+
+// ListBuckets returns every directory under the root, sorted by name.
+func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]absos.Bucket, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || !validBucketName(e.Name()) {
+			continue
+		}
+
+		b := s.Bucket(e.Name())
+		b.created = b.creationTime()
+		buckets = append(buckets, b)
+	}
+
+	return buckets, nil
+}
+
+// lock returns the lock serializing writes to the named bucket.
+func (s *Store) lock(bucket string) *sync.RWMutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.locks[bucket]
+	if !ok {
+		l = new(sync.RWMutex)
+		s.locks[bucket] = l
+	}
+
+	return l
+}
+
+// bucketInfo is persisted in the reserved directory of every bucket.
+type bucketInfo struct {
+	Created time.Time `json:"created"`
+}
+
+func initBucket(dir string) error {
+	for _, sub := range []string{"meta", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, reservedDir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(bucketInfo{Created: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, reservedDir, "bucket.json"), data, 0o644)
+}
+
+// validBucketName reports whether name can be used as a bucket directory.
+func validBucketName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\:`)
+}
+
+// validKey reports whether key can be mapped onto a path within a bucket.
+func validKey(key string) bool {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+
+	segments := strings.Split(key, "/")
+	if segments[0] == reservedDir {
+		return false
+	}
+
+	for _, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." || len(seg) > 255 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkKeys returns, in ascending order, the keys of the objects under dir
+// making up the page selected by opts, and the keys of the entry following
+// it so that listing.List can tell whether the page is truncated. Only the
+// directory containing the prefix is walked, directories sorting before the
+// start of the page are skipped, and directories collapsed into a common
+// prefix by the delimiter are represented by a single key.
+func walkKeys(dir string, opts listing.Options) ([]string, error) {
+	after, err := listing.After(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	base := ""
+	if i := strings.LastIndex(opts.Prefix, "/"); i >= 0 {
+		base = opts.Prefix[:i+1]
+	}
+
+	// No valid key lies below a directory that is not itself a valid key.
+	if base != "" && !validKey(strings.TrimSuffix(base, "/")) {
+		return nil, nil
+	}
+
+	w := &walker{dir: dir, opts: opts, after: after, limit: opts.Limit() + 1}
+	if err := w.walk(base); err != nil && err != errPageFull {
+		return nil, err
+	}
+	return w.keys, nil
+}
+
+// errPageFull stops a walker once it found enough entries.
+var errPageFull = errors.New("page full")
+
+// walker collects the keys of a page of a listing, in ascending order.
+type walker struct {
+	dir   string
+	opts  listing.Options
+	after string
+	limit int
+
+	keys  []string
+	last  string
+	count int
+}
+
+// walk visits the directory holding the keys starting with rel, which is
+// empty or ends with a slash. Its entries are visited in the order of their
+// keys, which for a directory is its name followed by a slash.
+func (w *walker) walk(rel string) error {
+	entries, err := os.ReadDir(filepath.Join(w.dir, filepath.FromSlash(rel)))
+	if err != nil {
+		// The directory of the prefix may not exist or be an object.
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+			return nil
+		}
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		switch {
+		case e.IsDir() && (rel != "" || e.Name() != reservedDir):
+			names = append(names, e.Name()+"/")
+		case e.Type().IsRegular():
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	prefix, delimiter := w.opts.Prefix, w.opts.Delimiter
+	for _, name := range names {
+		key := rel + name
+		if !strings.HasSuffix(key, "/") {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if entry, _ := listing.Collapse(key, prefix, delimiter); entry > w.after {
+				if err := w.add(key, entry); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// Every key under the directory starts with key.
+		if !strings.HasPrefix(key, prefix) && !strings.HasPrefix(prefix, key) {
+			continue
+		}
+		if key < w.after && !strings.HasPrefix(w.after, key) {
+			continue
+		}
+
+		if strings.HasPrefix(key, prefix) {
+			if entry, ok := listing.Collapse(key, prefix, delimiter); ok {
+				if entry <= w.after || entry == w.last {
+					continue
+				}
+				found, err := w.first(key)
+				if err != nil {
+					return err
+				}
+				if found != "" {
+					if err := w.add(found, entry); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+
+		if err := w.walk(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add appends key, which belongs to entry of the listing, and stops the walk
+// once limit entries were found.
+func (w *walker) add(key, entry string) error {
+	w.keys = append(w.keys, key)
+	if entry != w.last {
+		w.last = entry
+		w.count++
+	}
+	if w.count == w.limit {
+		return errPageFull
+	}
+	return nil
+}
+
+// first returns the key of any object under the directory holding the keys
+// starting with rel, or an empty string if there is none.
+func (w *walker) first(rel string) (string, error) {
+	var key string
+	root := filepath.Join(w.dir, filepath.FromSlash(rel))
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		r, err := filepath.Rel(w.dir, path)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(r)
+		return fs.SkipAll
+	})
+	return key, err
+}
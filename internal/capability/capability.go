@@ -0,0 +1,32 @@
+// Package capability gives bucket wrappers the optional interfaces of the
+// buckets they wrap. The wrappers implement every optional interface, so
+// that they can be stacked; the helpers here let each operation fail with
+// absos.ErrNotSupported when the wrapped bucket lacks it.
+package capability
+
+import "github.com/absfs/absos"
+
+// As returns b as a T, such as an absos.MultipartBucket. If b does not
+// implement T, it fails with absos.ErrNotSupported, reported for key or, if
+// key is empty, for the whole bucket.
+func As[T any](b absos.Bucket, key string) (T, error) {
+	if t, ok := b.(T); ok {
+		return t, nil
+	}
+
+	var zero T
+	if key == "" {
+		return zero, &absos.BucketError{Bucket: b.Name(), Err: absos.ErrNotSupported}
+	}
+	return zero, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: absos.ErrNotSupported}
+}
+
+// DeleteLimit returns the DeleteLimit of b, or 1 if b deletes objects one by
+// one, so that absos.DeleteMany still deletes them concurrently through a
+// wrapper.
+func DeleteLimit(b absos.Bucket) int {
+	if bb, ok := b.(absos.BulkDeleteBucket); ok {
+		return bb.DeleteLimit()
+	}
+	return 1
+}
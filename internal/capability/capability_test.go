@@ -0,0 +1,69 @@
+package capability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+)
+
+func TestAs(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	if err := store.CreateBucket(ctx, "test"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+	b := struct{ absos.Bucket }{buckets[0]}
+
+	_, err = As[absos.MultipartBucket](b, "key")
+	var objErr *absos.ObjectError
+	if !errors.Is(err, absos.ErrNotSupported) || !errors.As(err, &objErr) || objErr.Key != "key" {
+		t.Errorf("expected ErrNotSupported for the key, got %v", err)
+	}
+
+	_, err = As[absos.VersionedBucket](b, "")
+	var bucketErr *absos.BucketError
+	if !errors.Is(err, absos.ErrNotSupported) || !errors.As(err, &bucketErr) {
+		t.Errorf("expected ErrNotSupported for the bucket, got %v", err)
+	}
+
+	if _, err := As[absos.Bucket](b, "key"); err != nil {
+		t.Errorf("expected the bucket, got %v", err)
+	}
+}
+
+func TestDeleteLimit(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	if err := store.CreateBucket(ctx, "test"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	if n := DeleteLimit(struct{ absos.Bucket }{buckets[0]}); n != 1 {
+		t.Errorf("expected 1 key per batch, got %d", n)
+	}
+	if n := DeleteLimit(bulk{buckets[0]}); n != 1000 {
+		t.Errorf("expected 1000 keys per batch, got %d", n)
+	}
+}
+
+// bulk is a bucket deleting up to 1000 objects at once.
+type bulk struct {
+	absos.Bucket
+}
+
+func (bulk) DeleteLimit() int { return 1000 }
+
+func (b bulk) DeleteMany(ctx context.Context, keys []string) error {
+	return absos.DeleteMany(ctx, b.Bucket, keys)
+}
@@ -0,0 +1,149 @@
+// Package listing implements S3-style listing over a sorted set of keys:
+// prefix filtering, collapsing of keys into common prefixes by delimiter,
+// page size limits and opaque continuation tokens.
+package listing
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxKeys is the page size used when Options.MaxKeys is not positive.
+const DefaultMaxKeys = 1000
+
+// ErrInvalidToken is returned for continuation tokens not produced by List.
+var ErrInvalidToken = errors.New("invalid continuation token")
+
+// Options controls a single List call.
+type Options struct {
+	// Prefix restricts the listing to keys beginning with it.
+	Prefix string
+
+	// Delimiter collapses keys containing it after the prefix into a
+	// common prefix ending at its first occurrence.
+	Delimiter string
+
+	// Token is the continuation token returned by the previous page.
+	Token string
+
+	// StartAfter restricts the listing to keys and prefixes sorting after it.
+	StartAfter string
+
+	// MaxKeys limits the number of keys and prefixes in the page.
+	MaxKeys int
+}
+
+// Page is a single page of listing results, in lexicographic order.
+type Page struct {
+	Keys      []string
+	Prefixes  []string
+	Next      string
+	Truncated bool
+}
+
+// List returns the page of keys selected by opts. The keys must be sorted in
+// ascending order. Tokens encode the last key or prefix of the page, so they
+// remain valid while keys are added or removed between calls.
+func List(keys []string, opts Options) (Page, error) {
+	after, err := After(opts)
+	if err != nil {
+		return Page{}, err
+	}
+
+	maxKeys := opts.Limit()
+
+	start := opts.Prefix
+	if after > start {
+		start = after
+	}
+
+	var (
+		p     Page
+		last  string
+		count int
+	)
+
+	for i := sort.SearchStrings(keys, start); i < len(keys); i++ {
+		key := keys[i]
+		if !strings.HasPrefix(key, opts.Prefix) {
+			break
+		}
+
+		entry, isPrefix := Collapse(key, opts.Prefix, opts.Delimiter)
+		if entry <= after || (isPrefix && entry == last) {
+			continue
+		}
+
+		if count == maxKeys {
+			p.Truncated = true
+			p.Next = encodeToken(last)
+			break
+		}
+
+		if isPrefix {
+			p.Prefixes = append(p.Prefixes, entry)
+		} else {
+			p.Keys = append(p.Keys, entry)
+		}
+		last = entry
+		count++
+	}
+
+	return p, nil
+}
+
+// After returns the key or prefix after which the page selected by opts
+// starts, or an empty string for the first page.
+func After(opts Options) (string, error) {
+	after, err := decodeToken(opts.Token)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.StartAfter > after {
+		after = opts.StartAfter
+	}
+	return after, nil
+}
+
+// Limit returns the maximum number of keys and prefixes in the page.
+func (o Options) Limit() int {
+	if o.MaxKeys <= 0 {
+		return DefaultMaxKeys
+	}
+	return o.MaxKeys
+}
+
+// Collapse returns the common prefix key belongs to, or the key itself if it
+// does not contain the delimiter after the prefix.
+func Collapse(key, prefix, delimiter string) (string, bool) {
+	if delimiter == "" {
+		return key, false
+	}
+
+	i := strings.Index(key[len(prefix):], delimiter)
+	if i < 0 {
+		return key, false
+	}
+
+	return key[:len(prefix)+i+len(delimiter)], true
+}
+
+func encodeToken(after string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(after))
+}
+
+func decodeToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(after) == 0 {
+		return "", ErrInvalidToken
+	}
+
+	return string(after), nil
+}
@@ -0,0 +1,114 @@
+package listing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var keys = []string{
+	"a.txt",
+	"dir-file.txt",
+	"dir/a.txt",
+	"dir/b.txt",
+	"dir/sub/c.txt",
+	"z.txt",
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		keys     []string
+		prefixes []string
+	}{
+		{"All", Options{}, keys, nil},
+		{"Prefix", Options{Prefix: "dir/"}, []string{"dir/a.txt", "dir/b.txt", "dir/sub/c.txt"}, nil},
+		{"Delimiter", Options{Delimiter: "/"}, []string{"a.txt", "dir-file.txt", "z.txt"}, []string{"dir/"}},
+		{"PrefixDelimiter", Options{Prefix: "dir/", Delimiter: "/"}, []string{"dir/a.txt", "dir/b.txt"}, []string{"dir/sub/"}},
+		{"PartialPrefix", Options{Prefix: "dir", Delimiter: "/"}, []string{"dir-file.txt"}, []string{"dir/"}},
+		{"NoMatch", Options{Prefix: "missing/"}, nil, nil},
+		{"StartAfter", Options{StartAfter: "dir/b.txt"}, []string{"dir/sub/c.txt", "z.txt"}, nil},
+		{"StartAfterPrefix", Options{Prefix: "dir/", StartAfter: "a"}, []string{"dir/a.txt", "dir/b.txt", "dir/sub/c.txt"}, nil},
+		{"StartAfterDelimiter", Options{Delimiter: "/", StartAfter: "dir-file.txt"}, []string{"z.txt"}, []string{"dir/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := List(keys, tt.opts)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !reflect.DeepEqual(p.Keys, tt.keys) {
+				t.Errorf("expected keys %v, got %v", tt.keys, p.Keys)
+			}
+
+			if !reflect.DeepEqual(p.Prefixes, tt.prefixes) {
+				t.Errorf("expected prefixes %v, got %v", tt.prefixes, p.Prefixes)
+			}
+
+			if p.Truncated || p.Next != "" {
+				t.Errorf("expected single page, got next %q", p.Next)
+			}
+		})
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	opts := Options{Delimiter: "/", MaxKeys: 1}
+
+	var entries []string
+	for pages := 0; ; pages++ {
+		if pages > len(keys) {
+			t.Fatal("pagination did not terminate")
+		}
+
+		p, err := List(keys, opts)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if n := len(p.Keys) + len(p.Prefixes); n != 1 {
+			t.Fatalf("expected 1 entry per page, got %d", n)
+		}
+		entries = append(entries, p.Keys...)
+		entries = append(entries, p.Prefixes...)
+
+		if !p.Truncated {
+			break
+		}
+		opts.Token = p.Next
+	}
+
+	expected := []string{"a.txt", "dir-file.txt", "dir/", "z.txt"}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v, got %v", expected, entries)
+	}
+}
+
+func TestListTokenStable(t *testing.T) {
+	p, err := List(keys, Options{MaxKeys: 2})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Removing the last listed key does not invalidate the token
+	changed := []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"}
+	p, err = List(changed, Options{MaxKeys: 2, Token: p.Next})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []string{"dir/b.txt", "dir/sub/c.txt"}
+	if !reflect.DeepEqual(p.Keys, expected) {
+		t.Errorf("expected %v, got %v", expected, p.Keys)
+	}
+}
+
+func TestListInvalidToken(t *testing.T) {
+	_, err := List(keys, Options{Token: "not a token!"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package measure measures the data that bucket wrappers pass to and read
+// from the buckets they wrap.
+package measure
+
+import "io"
+
+// Remaining returns the number of bytes between the offset of r and its end,
+// leaving the offset unchanged.
+func Remaining(r io.ReadSeeker) (int64, error) {
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end - offset, nil
+}
+
+// Reader counts the bytes read from a reader. When it is first closed, it
+// reports them with the first read error other than io.EOF, or otherwise the
+// error of Close.
+type Reader struct {
+	r      io.ReadCloser
+	done   func(n int64, err error)
+	n      int64
+	err    error
+	closed bool
+}
+
+// NewReader returns a Reader reading from r and calling done once closed.
+func NewReader(r io.ReadCloser, done func(n int64, err error)) *Reader {
+	return &Reader{r: r, done: done}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *Reader) Close() error {
+	err := r.r.Close()
+	if !r.closed {
+		r.closed = true
+		if r.err != nil {
+			r.done(r.n, r.err)
+		} else {
+			r.done(r.n, err)
+		}
+	}
+	return err
+}
+
+// Counter counts the bytes read from a reader.
+type Counter struct {
+	R io.Reader
+	N int64
+}
+
+func (c *Counter) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.N += int64(n)
+	return n, err
+}
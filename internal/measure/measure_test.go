@@ -0,0 +1,76 @@
+package measure
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRemaining(t *testing.T) {
+	r := strings.NewReader("0123456789")
+	if _, err := r.Seek(4, io.SeekStart); err != nil {
+		t.Fatalf("failed to seek: %v", err)
+	}
+
+	n, err := Remaining(r)
+	if err != nil || n != 6 {
+		t.Errorf("expected 6 bytes, got %d (%v)", n, err)
+	}
+	if offset, _ := r.Seek(0, io.SeekCurrent); offset != 4 {
+		t.Errorf("expected the offset to be kept, got %d", offset)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	c := &Counter{R: strings.NewReader("data")}
+	if _, err := io.ReadAll(c); err != nil || c.N != 4 {
+		t.Errorf("expected 4 bytes, got %d (%v)", c.N, err)
+	}
+}
+
+// failingReader fails after reading its data.
+type failingReader struct {
+	io.Reader
+	err error
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		err = r.err
+	}
+	return n, err
+}
+
+func TestReader(t *testing.T) {
+	readErr := errors.New("read failed")
+
+	tests := map[string]struct {
+		r   io.Reader
+		err error
+	}{
+		"EOF":       {strings.NewReader("data"), nil},
+		"ReadError": {failingReader{strings.NewReader("data"), readErr}, readErr},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			r := NewReader(io.NopCloser(tt.r), func(n int64, err error) {
+				calls++
+				if n != 4 || err != tt.err {
+					t.Errorf("expected 4 bytes and %v, got %d and %v", tt.err, n, err)
+				}
+			})
+
+			_, _ = io.ReadAll(r)
+			r.Close()
+			r.Close()
+
+			if calls != 1 {
+				t.Errorf("expected one call, got %d", calls)
+			}
+		})
+	}
+}
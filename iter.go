@@ -0,0 +1,97 @@
+//go:build go1.23
+
+package absos
+
+import (
+	"context"
+	"iter"
+)
+
+// Objects returns an iterator over the objects in b whose keys begin with
+// prefix, in lexicographic order, listing the following page while the
+// caller processes the current one. The iteration stops after yielding the
+// first error, including the error of a canceled ctx.
+func Objects(ctx context.Context, b Bucket, prefix string) iter.Seq2[Object, error] {
+	return func(yield func(Object, error) bool) {
+		for page, err := range pages(ctx, b, prefix, "") {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, obj := range page.Objects() {
+				if err := ctx.Err(); err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(obj, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Prefixes returns an iterator over the common prefixes formed by delimiter
+// among the keys in b that begin with prefix, such as the "directories" of
+// a level of the hierarchy. Errors are reported as by Objects.
+func Prefixes(ctx context.Context, b Bucket, prefix, delimiter string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for page, err := range pages(ctx, b, prefix, delimiter) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			for _, p := range page.Prefixes() {
+				if err := ctx.Err(); err != nil {
+					yield("", err)
+					return
+				}
+				if !yield(p, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Buckets returns an iterator over the buckets in store.
+// Errors are reported as by Objects.
+func Buckets(ctx context.Context, store ObjectStore) iter.Seq2[Bucket, error] {
+	return func(yield func(Bucket, error) bool) {
+		buckets, err := store.ListBuckets(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, b := range buckets {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
+
+// pages returns an iterator over the pages of a listing.
+func pages(ctx context.Context, b Bucket, prefix, delimiter string) iter.Seq2[Page, error] {
+	return func(yield func(Page, error) bool) {
+		p := newPager(ctx, b, prefix, delimiter)
+		defer p.close()
+
+		for {
+			page, err := p.next()
+			if page == nil && err == nil {
+				return
+			}
+			if !yield(page, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+//go:build go1.23
+
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+)
+
+func TestObjects(t *testing.T) {
+	b := newListBucket(t, walkKeys...)
+
+	var keys []string
+	for obj, err := range absos.Objects(context.Background(), b, "b/") {
+		if err != nil {
+			t.Fatalf("failed to list objects: %v", err)
+		}
+		keys = append(keys, obj.Key())
+	}
+
+	expected := []string{"b/1", "b/2", "b/c/3"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+
+	// Breaking out of the loop stops the listing
+	count := 0
+	for _, err := range absos.Objects(context.Background(), b, "") {
+		if err != nil {
+			t.Fatalf("failed to list objects: %v", err)
+		}
+		if count++; count == 3 {
+			break
+		}
+	}
+}
+
+func TestObjectsCanceled(t *testing.T) {
+	b := newListBucket(t, walkKeys...)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var errs []error
+	for obj, err := range absos.Objects(ctx, b, "") {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if obj.Key() == "b/1" {
+			cancel()
+		}
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected a single context.Canceled error, got %v", errs)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	b := newListBucket(t, walkKeys...)
+
+	var prefixes []string
+	for p, err := range absos.Prefixes(context.Background(), b, "", "/") {
+		if err != nil {
+			t.Fatalf("failed to list prefixes: %v", err)
+		}
+		prefixes = append(prefixes, p)
+	}
+
+	expected := []string{"b/", "e/"}
+	if strings.Join(prefixes, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, prefixes)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	for _, name := range []string{"one", "two"} {
+		if err := store.CreateBucket(ctx, name); err != nil {
+			t.Fatalf("failed to create bucket: %v", err)
+		}
+	}
+
+	count := 0
+	for _, err := range absos.Buckets(ctx, store) {
+		if err != nil {
+			t.Fatalf("failed to list buckets: %v", err)
+		}
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 buckets, got %d", count)
+	}
+}
@@ -0,0 +1,217 @@
+package absos
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MinPartSize is the minimum size S3 accepts for every part of a multipart
+// upload except the last. Other providers may accept smaller parts.
+const MinPartSize = 5 << 20
+
+// MaxParts is the maximum number of parts of a multipart upload.
+const MaxParts = 10000
+
+// MultipartUpload identifies an in-progress multipart upload. It can be
+// serialized, for example with encoding/json, so that a job interrupted by a
+// crash can resume the upload where it left off.
+type MultipartUpload struct {
+	// Bucket is the name of the bucket the object is uploaded to.
+	Bucket string `json:"bucket"`
+
+	// Key is the key the object is stored under once the upload completes.
+	Key string `json:"key"`
+
+	// ID is the provider-assigned identifier of the upload.
+	ID string `json:"id"`
+
+	// Initiated is the time the upload was initiated.
+	Initiated time.Time `json:"initiated"`
+}
+
+// Part describes an uploaded part of a multipart upload.
+type Part struct {
+	// Number is the part number, from 1 to MaxParts.
+	Number int `json:"number"`
+
+	// Size is the size of the part in bytes.
+	Size int64 `json:"size"`
+
+	// ETag is the entity tag of the part (usually the MD5 hash of its contents).
+	ETag []byte `json:"etag"`
+
+	// ModTime is the time the part was uploaded.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// MultipartBucket is implemented by buckets that support uploading an object
+// in independently uploaded parts. The object becomes visible only when the
+// upload is completed.
+//
+// Operations on uploads that do not exist, or that were completed or
+// aborted, return ErrUploadNotFound.
+type MultipartBucket interface {
+	Bucket
+
+	// InitiateMultipart starts a multipart upload for key. The options are
+	// applied to the object when the upload completes.
+	InitiateMultipart(ctx context.Context, key string, opts ...PutOption) (MultipartUpload, error)
+
+	// UploadPart uploads part number of the upload, replacing any part
+	// previously uploaded with the same number.
+	UploadPart(ctx context.Context, upload MultipartUpload, number int, data io.ReadSeeker) (Part, error)
+
+	// ListParts returns the parts uploaded so far, ordered by part number.
+	ListParts(ctx context.Context, upload MultipartUpload) ([]Part, error)
+
+	// CompleteMultipart assembles the object from the given parts, which
+	// must be in ascending order of part number and carry the ETags returned
+	// by UploadPart. Uploaded parts that are not listed are discarded.
+	// ErrInvalidPart is returned for parts that do not match.
+	CompleteMultipart(ctx context.Context, upload MultipartUpload, parts []Part) error
+
+	// AbortMultipart discards the upload and its parts.
+	AbortMultipart(ctx context.Context, upload MultipartUpload) error
+
+	// ListMultipartUploads returns the in-progress uploads of keys with the
+	// given prefix, ordered by key and initiation time.
+	ListMultipartUploads(ctx context.Context, prefix string) ([]MultipartUpload, error)
+}
+
+// UploadParts uploads the size bytes of r as parts of partSize bytes and
+// completes the upload. Parts that were already uploaded with the expected
+// size, and an ETag that is either not an MD5 hash or the MD5 hash of the
+// part's data, are not uploaded again, so calling UploadParts again with the
+// same upload and partSize resumes an interrupted upload. A partSize of zero
+// or less selects MinPartSize.
+func UploadParts(ctx context.Context, b MultipartBucket, upload MultipartUpload, r io.ReaderAt, size, partSize int64) error {
+	if partSize <= 0 {
+		partSize = MinPartSize
+	}
+
+	count := (size + partSize - 1) / partSize
+	if count == 0 {
+		count = 1
+	}
+	if count > MaxParts {
+		return &ObjectError{Bucket: b.Name(), Key: upload.Key,
+			Err: fmt.Errorf("%w: %d parts of %d bytes exceed the limit of %d parts", ErrInvalidPart, count, partSize, MaxParts)}
+	}
+
+	uploaded, err := b.ListParts(ctx, upload)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[int]Part, len(uploaded))
+	for _, p := range uploaded {
+		existing[p.Number] = p
+	}
+
+	parts := make([]Part, 0, count)
+	for i := int64(0); i < count; i++ {
+		number := int(i) + 1
+		offset := i * partSize
+		section := io.NewSectionReader(r, offset, min(partSize, size-offset))
+
+		if p, ok := existing[number]; ok && p.Size == section.Size() {
+			if matches, err := partMatches(p, section); err != nil {
+				return &ObjectError{Bucket: b.Name(), Key: upload.Key, Err: err}
+			} else if matches {
+				parts = append(parts, p)
+				continue
+			}
+		}
+
+		p, err := b.UploadPart(ctx, upload, number, section)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, p)
+	}
+
+	return b.CompleteMultipart(ctx, upload, parts)
+}
+
+// partMatches reports whether the uploaded part p holds the data of section.
+// ETags that are not MD5 hashes cannot be verified and are trusted.
+func partMatches(p Part, section *io.SectionReader) (bool, error) {
+	if len(p.ETag) != md5.Size {
+		return true, nil
+	}
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, section); err != nil {
+		return false, err
+	}
+	if _, err := section.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(hash.Sum(nil), p.ETag), nil
+}
+
+// streamPartSize returns the size of part number of a stream uploaded by
+// uploadMultipart: MinPartSize for the first thousand parts, doubling every
+// thousand parts, so that MaxParts parts hold streams of nearly 5 TiB, the
+// largest object S3 accepts.
+func streamPartSize(number int) int64 {
+	return MinPartSize << ((number - 1) / (MaxParts / 10))
+}
+
+// uploadMultipart uploads data as a multipart upload of parts growing as
+// described for streamPartSize, or with a single Put if it fits into one
+// part. The upload is aborted if any part fails, or with ErrInvalidPart if
+// data does not fit into MaxParts parts.
+func uploadMultipart(ctx context.Context, b MultipartBucket, key string, data io.Reader, opts ...PutOption) error {
+	buf := make([]byte, streamPartSize(1))
+
+	n, err := io.ReadFull(data, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return b.Put(ctx, key, bytes.NewReader(buf[:n]), opts...)
+	}
+	if err != nil {
+		return &ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	upload, err := b.InitiateMultipart(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	var parts []Part
+	for number := 1; n > 0; number++ {
+		if number > MaxParts {
+			_ = b.AbortMultipart(context.WithoutCancel(ctx), upload)
+			return &ObjectError{Bucket: b.Name(), Key: key,
+				Err: fmt.Errorf("%w: stream exceeds the limit of %d parts", ErrInvalidPart, MaxParts)}
+		}
+
+		p, err := b.UploadPart(ctx, upload, number, bytes.NewReader(buf[:n]))
+		if err != nil {
+			_ = b.AbortMultipart(context.WithoutCancel(ctx), upload)
+			return err
+		}
+		parts = append(parts, p)
+
+		if size := streamPartSize(number + 1); size > int64(len(buf)) {
+			buf = make([]byte, size)
+		}
+		n, err = io.ReadFull(data, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			_ = b.AbortMultipart(context.WithoutCancel(ctx), upload)
+			return &ObjectError{Bucket: b.Name(), Key: key, Err: err}
+		}
+	}
+
+	if err := b.CompleteMultipart(ctx, upload, parts); err != nil {
+		_ = b.AbortMultipart(context.WithoutCancel(ctx), upload)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package absos
+
+import "testing"
+
+func TestStreamPartSize(t *testing.T) {
+	tests := []struct {
+		number int
+		size   int64
+	}{
+		{1, MinPartSize},
+		{1000, MinPartSize},
+		{1001, 2 * MinPartSize},
+		{MaxParts, 512 * MinPartSize},
+	}
+
+	for _, tt := range tests {
+		if size := streamPartSize(tt.number); size != tt.size {
+			t.Errorf("part %d: expected %d bytes, got %d", tt.number, tt.size, size)
+		}
+	}
+
+	// MaxParts parts hold nearly 5 TiB
+	var total int64
+	for number := 1; number <= MaxParts; number++ {
+		total += streamPartSize(number)
+	}
+	if total < 4<<40 || total > 5<<40 {
+		t.Errorf("expected MaxParts parts to hold nearly 5 TiB, got %d bytes", total)
+	}
+}
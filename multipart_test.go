@@ -0,0 +1,136 @@
+package absos_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/absfs/absos"
+)
+
+// multipartOnly hides every capability of the wrapped bucket but multipart uploads.
+type multipartOnly struct {
+	absos.MultipartBucket
+}
+
+// flakyBucket counts uploaded parts and fails part failPart once.
+type flakyBucket struct {
+	absos.MultipartBucket
+	failPart int
+	uploads  atomic.Int64
+}
+
+var errFlaky = errors.New("connection reset")
+
+func (b *flakyBucket) UploadPart(ctx context.Context, upload absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	if number == b.failPart {
+		b.failPart = 0
+		return absos.Part{}, errFlaky
+	}
+	b.uploads.Add(1)
+	return b.MultipartBucket.UploadPart(ctx, upload, number, data)
+}
+
+func newMultipartBucket(t *testing.T) absos.MultipartBucket {
+	t.Helper()
+
+	mb, ok := newStreamBucket(t).(absos.MultipartBucket)
+	if !ok {
+		t.Fatal("expected memory bucket to implement absos.MultipartBucket")
+	}
+	return mb
+}
+
+func TestUploadPartsResume(t *testing.T) {
+	ctx := context.Background()
+	bucket := &flakyBucket{MultipartBucket: newMultipartBucket(t), failPart: 3}
+
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	upload, err := bucket.InitiateMultipart(ctx, "data")
+	if err != nil {
+		t.Fatalf("failed to initiate upload: %v", err)
+	}
+
+	// The first attempt stops at part 3
+	err = absos.UploadParts(ctx, bucket, upload, bytes.NewReader(data), int64(len(data)), 30)
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("expected error %v, got %v", errFlaky, err)
+	}
+
+	// Replace part 1 with wrong data, as if it was only partially written
+	if _, err := bucket.MultipartBucket.UploadPart(ctx, upload, 1, bytes.NewReader(make([]byte, 30))); err != nil {
+		t.Fatalf("failed to upload part: %v", err)
+	}
+
+	// The second attempt uploads the mismatched part 1 and the missing parts 3 and 4
+	bucket.uploads.Store(0)
+	if err := absos.UploadParts(ctx, bucket, upload, bytes.NewReader(data), int64(len(data)), 30); err != nil {
+		t.Fatalf("failed to resume upload: %v", err)
+	}
+
+	if n := bucket.uploads.Load(); n != 3 {
+		t.Errorf("expected 3 parts to be uploaded on resume, got %d", n)
+	}
+
+	if content := readObject(t, bucket, "data"); !bytes.Equal(content, data) {
+		t.Errorf("expected %q, got %q", data, content)
+	}
+}
+
+func TestUploadPartsEmpty(t *testing.T) {
+	ctx := context.Background()
+	bucket := newMultipartBucket(t)
+
+	upload, err := bucket.InitiateMultipart(ctx, "empty")
+	if err != nil {
+		t.Fatalf("failed to initiate upload: %v", err)
+	}
+
+	if err := absos.UploadParts(ctx, bucket, upload, bytes.NewReader(nil), 0, 0); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	if content := readObject(t, bucket, "empty"); len(content) != 0 {
+		t.Errorf("expected empty object, got %q", content)
+	}
+}
+
+func TestUploadPartsTooMany(t *testing.T) {
+	ctx := context.Background()
+	bucket := newMultipartBucket(t)
+
+	upload := absos.MultipartUpload{Bucket: bucket.Name(), Key: "key", ID: "id"}
+	err := absos.UploadParts(ctx, bucket, upload, bytes.NewReader(nil), absos.MaxParts+1, 1)
+	if !errors.Is(err, absos.ErrInvalidPart) {
+		t.Errorf("expected ErrInvalidPart, got %v", err)
+	}
+}
+
+func TestUploadMultipart(t *testing.T) {
+	ctx := context.Background()
+	bucket := multipartOnly{newMultipartBucket(t)}
+
+	// Two full parts and a partial one
+	data := bytes.Repeat([]byte("x"), 2*absos.MinPartSize+10)
+	if err := absos.Upload(ctx, bucket, "big", io.MultiReader(bytes.NewReader(data))); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	if content := readObject(t, bucket, "big"); !bytes.Equal(content, data) {
+		t.Errorf("expected %d bytes, got %d", len(data), len(content))
+	}
+
+	// A failing stream aborts the upload
+	err := absos.Upload(ctx, bucket, "broken", errorAfter(bytes.NewReader(data), errFlaky))
+	if !errors.Is(err, errFlaky) {
+		t.Errorf("expected error %v, got %v", errFlaky, err)
+	}
+
+	uploads, err := bucket.ListMultipartUploads(ctx, "")
+	if err != nil || len(uploads) != 0 {
+		t.Errorf("expected no uploads left, got %v (%v)", uploads, err)
+	}
+}
@@ -61,6 +61,15 @@ type ObjectHeader interface {
 	// MimeType returns the MIME type (Content-Type) of the object.
 	MimeType() string
 
+	// ContentEncoding returns the Content-Encoding of the object.
+	ContentEncoding() string
+
+	// CacheControl returns the Cache-Control header of the object.
+	CacheControl() string
+
+	// ContentDisposition returns the Content-Disposition header of the object.
+	ContentDisposition() string
+
 	// Metadata returns custom user-defined metadata associated with the object.
 	Metadata() map[string]string
 
@@ -75,6 +84,10 @@ type ObjectHeader interface {
 
 	// StorageClass returns the storage class of the object (e.g., STANDARD, GLACIER).
 	StorageClass() string
+
+	// Checksums returns the checksums of the object's contents known to the
+	// provider, which may be none.
+	Checksums() Checksums
 }
 
 // SSE represents server-side encryption configuration for an object.
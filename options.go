@@ -0,0 +1,102 @@
+package absos
+
+// PutOptions holds the optional attributes stored with an object by Put.
+// Zero values leave the choice to the provider.
+type PutOptions struct {
+	// ContentType is the MIME type of the object.
+	ContentType string
+
+	// ContentEncoding is the Content-Encoding of the object, such as gzip.
+	ContentEncoding string
+
+	// CacheControl is the Cache-Control header served with the object.
+	CacheControl string
+
+	// ContentDisposition is the Content-Disposition header served with the object.
+	ContentDisposition string
+
+	// Metadata is custom user-defined metadata stored with the object.
+	// Providers may normalize the case of the keys; lowercase keys are portable.
+	Metadata map[string]string
+
+	// StorageClass is the storage class of the object (e.g., STANDARD, GLACIER).
+	StorageClass string
+
+	// SSE configures server-side encryption of the object.
+	SSE *SSE
+
+	// Checksums are the expected checksums of the contents. Providers
+	// verify them and reject the object with ErrChecksumMismatch if they
+	// do not match.
+	Checksums Checksums
+}
+
+// PutOption configures the attributes of an object stored by Put.
+type PutOption func(*PutOptions)
+
+// NewPutOptions returns the PutOptions resulting from applying opts in order.
+func NewPutOptions(opts ...PutOption) PutOptions {
+	var o PutOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithContentType sets the MIME type of the object.
+func WithContentType(contentType string) PutOption {
+	return func(o *PutOptions) { o.ContentType = contentType }
+}
+
+// WithContentEncoding sets the Content-Encoding of the object.
+func WithContentEncoding(encoding string) PutOption {
+	return func(o *PutOptions) { o.ContentEncoding = encoding }
+}
+
+// WithCacheControl sets the Cache-Control header of the object.
+func WithCacheControl(cacheControl string) PutOption {
+	return func(o *PutOptions) { o.CacheControl = cacheControl }
+}
+
+// WithContentDisposition sets the Content-Disposition header of the object.
+func WithContentDisposition(disposition string) PutOption {
+	return func(o *PutOptions) { o.ContentDisposition = disposition }
+}
+
+// WithMetadata adds custom user-defined metadata to the object.
+// Repeated calls merge their entries.
+func WithMetadata(metadata map[string]string) PutOption {
+	return func(o *PutOptions) {
+		if o.Metadata == nil {
+			o.Metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			o.Metadata[k] = v
+		}
+	}
+}
+
+// WithStorageClass sets the storage class of the object.
+func WithStorageClass(class string) PutOption {
+	return func(o *PutOptions) { o.StorageClass = class }
+}
+
+// WithSSE configures server-side encryption of the object.
+func WithSSE(sse *SSE) PutOption {
+	return func(o *PutOptions) { o.SSE = sse }
+}
+
+// WithChecksum sets the expected checksum of the contents for algorithm.
+func WithChecksum(algorithm ChecksumAlgorithm, sum []byte) PutOption {
+	return func(o *PutOptions) {
+		if o.Checksums == nil {
+			o.Checksums = make(Checksums)
+		}
+		o.Checksums[algorithm] = sum
+	}
+}
+
+// withOptions replaces all options with o.
+func withOptions(o PutOptions) PutOption {
+	return func(p *PutOptions) { *p = o }
+}
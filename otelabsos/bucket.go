@@ -0,0 +1,179 @@
+package otelabsos
+
+import (
+	"context"
+	"io"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/measure"
+)
+
+// Store is an instrumented absos.ObjectStore. The buckets returned by
+// ListBuckets are instrumented too.
+type Store struct {
+	store absos.ObjectStore
+	in    *instruments
+}
+
+var _ absos.ObjectStore = (*Store)(nil)
+
+// New returns a Store instrumenting store.
+func New(store absos.ObjectStore, opts ...Option) *Store {
+	return &Store{store: store, in: newInstruments(opts)}
+}
+
+// CreateBucket creates a bucket.
+func (s *Store) CreateBucket(ctx context.Context, bucket string) error {
+	ctx, op := s.in.start(ctx, "CreateBucket", bucket)
+	err := s.store.CreateBucket(ctx, bucket)
+	op.end(err)
+	return err
+}
+
+// DeleteBucket deletes a bucket.
+func (s *Store) DeleteBucket(ctx context.Context, bucket string) error {
+	ctx, op := s.in.start(ctx, "DeleteBucket", bucket)
+	err := s.store.DeleteBucket(ctx, bucket)
+	op.end(err)
+	return err
+}
+
+// ListBuckets lists the buckets of the store.
+func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	ctx, op := s.in.start(ctx, "ListBuckets", "")
+	buckets, err := s.store.ListBuckets(ctx)
+	op.end(err)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, b := range buckets {
+		buckets[i] = &Bucket{Bucket: b, in: s.in}
+	}
+	return buckets, nil
+}
+
+// Bucket is an instrumented absos.Bucket. Conditional requests, native range
+// reads, streamed and multipart uploads, copies, bulk deletes, presigning and
+// versioning are instrumented as operations of their own, so that wrapping a
+// bucket does not hide its capabilities. Objects returned in pages are
+// instrumented too.
+//
+// Bucket implements every optional interface, so type assertions on it do
+// not tell what the wrapped bucket supports; assert on the Bucket field
+// instead. In particular, conditional reads are atomic only if the wrapped
+// bucket is an absos.ConditionalBucket.
+type Bucket struct {
+	absos.Bucket
+	in *instruments
+}
+
+// NewBucket returns a Bucket instrumenting b.
+func NewBucket(b absos.Bucket, opts ...Option) *Bucket {
+	return &Bucket{Bucket: b, in: newInstruments(opts)}
+}
+
+// ObjectPage lists a page of objects.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	ctx, op := b.in.start(ctx, "ObjectPage", b.Name(), prefixKey.String(prefix))
+	page, err := b.Bucket.ObjectPage(ctx, prefix, delimiter, token)
+	op.end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedPage{Page: page, in: b.in}, nil
+}
+
+// Head retrieves the metadata of an object.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	ctx, op := b.in.start(ctx, "Head", b.Name(), keyKey.String(key))
+	header, err := b.Bucket.Head(ctx, key)
+	op.end(err)
+	return header, err
+}
+
+// PutBatch uploads the objects of iter.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	ctx, op := b.in.start(ctx, "PutBatch", b.Name())
+	err := b.Bucket.PutBatch(ctx, iter)
+	op.end(err)
+	return err
+}
+
+// Put uploads an object. The bytes written are the remaining length of
+// data, determined by seeking.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	ctx, op := b.in.start(ctx, "Put", b.Name(), keyKey.String(key))
+
+	size, sizeErr := measure.Remaining(data)
+	err := sizeErr
+	if err == nil {
+		err = b.Bucket.Put(ctx, key, data, opts...)
+	}
+	if err == nil {
+		op.transferred(size, "write")
+	}
+
+	op.end(err)
+	if sizeErr != nil {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: sizeErr}
+	}
+	return err
+}
+
+// Get opens an object. Its span ends when the reader is closed.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, op := b.in.start(ctx, "Get", b.Name(), keyKey.String(key))
+	r, err := b.Bucket.Get(ctx, key)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// Delete removes an object.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	ctx, op := b.in.start(ctx, "Delete", b.Name(), keyKey.String(key))
+	err := b.Bucket.Delete(ctx, key)
+	op.end(err)
+	return err
+}
+
+// instrumentedPage instruments the objects of a page.
+type instrumentedPage struct {
+	absos.Page
+	in *instruments
+}
+
+func (p *instrumentedPage) Objects() []absos.Object {
+	objects := p.Page.Objects()
+	wrapped := make([]absos.Object, len(objects))
+	for i, obj := range objects {
+		wrapped[i] = &object{Object: obj, in: p.in}
+	}
+	return wrapped
+}
+
+// object is an instrumented object of a page.
+type object struct {
+	absos.Object
+	in *instruments
+}
+
+func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
+	ctx, op := o.in.start(ctx, "Head", o.Bucket(), keyKey.String(o.Key()))
+	header, err := o.Object.Head(ctx)
+	op.end(err)
+	return header, err
+}
+
+func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
+	ctx, op := o.in.start(ctx, "Open", o.Bucket(), keyKey.String(o.Key()))
+	r, err := o.Object.Open(ctx)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
@@ -0,0 +1,313 @@
+package otelabsos
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/capability"
+	"github.com/absfs/absos/internal/measure"
+)
+
+var (
+	_ absos.RangeBucket       = (*Bucket)(nil)
+	_ absos.ConditionalBucket = (*Bucket)(nil)
+	_ absos.StreamBucket      = (*Bucket)(nil)
+	_ absos.CopyBucket        = (*Bucket)(nil)
+	_ absos.MultipartBucket   = (*Bucket)(nil)
+	_ absos.VersionedBucket   = (*Bucket)(nil)
+	_ absos.BulkDeleteBucket  = (*Bucket)(nil)
+	_ absos.Presigner         = (*Bucket)(nil)
+	_ absos.RangeObject       = (*object)(nil)
+)
+
+// GetRange opens part of an object, reading the whole object if the wrapped
+// bucket cannot read ranges. Its span ends when the reader is closed.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	ctx, op := b.in.start(ctx, "GetRange", b.Name(), keyKey.String(key), offsetKey.Int64(offset), lengthKey.Int64(length))
+	r, err := absos.GetRange(ctx, b.Bucket, key, offset, length)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// PutIf uploads an object if cond holds. It fails with
+// absos.ErrNotSupported if the wrapped bucket does not support conditional
+// writes.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	ctx, op := b.in.start(ctx, "PutIf", b.Name(), keyKey.String(key))
+
+	size, err := measure.Remaining(data)
+	if err != nil {
+		err = &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	} else if err = absos.PutIf(ctx, b.Bucket, key, data, cond, opts...); err == nil {
+		op.transferred(size, "write")
+	}
+
+	op.end(err)
+	return err
+}
+
+// GetIf opens an object if cond holds. Its span ends when the reader is
+// closed.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	ctx, op := b.in.start(ctx, "GetIf", b.Name(), keyKey.String(key))
+	r, err := absos.GetIf(ctx, b.Bucket, key, cond)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// HeadIf retrieves the metadata of an object if cond holds.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	ctx, op := b.in.start(ctx, "HeadIf", b.Name(), keyKey.String(key))
+	header, err := absos.HeadIf(ctx, b.Bucket, key, cond)
+	op.end(err)
+	return header, err
+}
+
+// DeleteIf removes an object if cond holds. It fails with
+// absos.ErrNotSupported if the wrapped bucket does not support conditional
+// writes.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	ctx, op := b.in.start(ctx, "DeleteIf", b.Name(), keyKey.String(key))
+	err := absos.DeleteIf(ctx, b.Bucket, key, cond)
+	op.end(err)
+	return err
+}
+
+// PutStream uploads an object read from data until io.EOF with
+// absos.Upload. The bytes written are those read from data.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	ctx, op := b.in.start(ctx, "PutStream", b.Name(), keyKey.String(key))
+	c := &measure.Counter{R: data}
+	err := absos.Upload(ctx, b.Bucket, key, c, opts...)
+	if err == nil {
+		op.transferred(c.N, "write")
+	}
+	op.end(err)
+	return err
+}
+
+// Copy copies an object within the wrapped bucket's provider. It fails with
+// absos.ErrNotSupported if the wrapped bucket cannot copy from src, so that
+// absos.Copy falls back to reading and uploading the object.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	if s, ok := src.(*Bucket); ok {
+		src = s.Bucket
+	}
+
+	ctx, op := b.in.start(ctx, "Copy", b.Name(), keyKey.String(dstKey),
+		sourceBucketKey.String(src.Name()), sourceKeyKey.String(srcKey))
+
+	cb, err := capability.As[absos.CopyBucket](b.Bucket, dstKey)
+	if err == nil {
+		err = cb.Copy(ctx, src, srcKey, dstKey, opts...)
+	}
+
+	op.end(err)
+	return err
+}
+
+// DeleteLimit returns the number of keys the wrapped bucket deletes at
+// once, or 1 if it deletes them one by one.
+func (b *Bucket) DeleteLimit() int {
+	return capability.DeleteLimit(b.Bucket)
+}
+
+// DeleteMany deletes the objects with the given keys. Its span records the
+// number of keys.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	ctx, op := b.in.start(ctx, "DeleteMany", b.Name(), keysKey.Int(len(keys)))
+	err := absos.DeleteMany(ctx, b.Bucket, keys)
+	op.end(err)
+	return err
+}
+
+// Presign returns a URL for method on an object. It fails with
+// absos.ErrNotSupported if the wrapped bucket cannot presign URLs. Requests
+// made with the URL are not instrumented.
+func (b *Bucket) Presign(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	ctx, op := b.in.start(ctx, "Presign", b.Name(), keyKey.String(key), methodKey.String(method))
+	p, err := capability.As[absos.Presigner](b.Bucket, key)
+	var url string
+	if err == nil {
+		url, err = p.Presign(ctx, method, key, expires)
+	}
+	op.end(err)
+	return url, err
+}
+
+// InitiateMultipart starts a multipart upload. The multipart operations fail
+// with absos.ErrNotSupported if the wrapped bucket does not support them.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	ctx, op := b.in.start(ctx, "InitiateMultipart", b.Name(), keyKey.String(key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, key)
+	var upload absos.MultipartUpload
+	if err == nil {
+		upload, err = mb.InitiateMultipart(ctx, key, opts...)
+	}
+	op.end(err)
+	return upload, err
+}
+
+// UploadPart uploads a part of a multipart upload. The bytes written are the
+// remaining length of data, determined by seeking.
+func (b *Bucket) UploadPart(ctx context.Context, upload absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	ctx, op := b.in.start(ctx, "UploadPart", b.Name(), keyKey.String(upload.Key), partKey.Int(number))
+
+	var part absos.Part
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err == nil {
+		var size int64
+		if size, err = measure.Remaining(data); err != nil {
+			err = &absos.ObjectError{Bucket: b.Name(), Key: upload.Key, Err: err}
+		} else if part, err = mb.UploadPart(ctx, upload, number, data); err == nil {
+			op.transferred(size, "write")
+		}
+	}
+
+	op.end(err)
+	return part, err
+}
+
+// ListParts lists the parts of a multipart upload.
+func (b *Bucket) ListParts(ctx context.Context, upload absos.MultipartUpload) ([]absos.Part, error) {
+	ctx, op := b.in.start(ctx, "ListParts", b.Name(), keyKey.String(upload.Key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	var parts []absos.Part
+	if err == nil {
+		parts, err = mb.ListParts(ctx, upload)
+	}
+	op.end(err)
+	return parts, err
+}
+
+// CompleteMultipart assembles the parts of a multipart upload.
+func (b *Bucket) CompleteMultipart(ctx context.Context, upload absos.MultipartUpload, parts []absos.Part) error {
+	ctx, op := b.in.start(ctx, "CompleteMultipart", b.Name(), keyKey.String(upload.Key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err == nil {
+		err = mb.CompleteMultipart(ctx, upload, parts)
+	}
+	op.end(err)
+	return err
+}
+
+// AbortMultipart aborts a multipart upload.
+func (b *Bucket) AbortMultipart(ctx context.Context, upload absos.MultipartUpload) error {
+	ctx, op := b.in.start(ctx, "AbortMultipart", b.Name(), keyKey.String(upload.Key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err == nil {
+		err = mb.AbortMultipart(ctx, upload)
+	}
+	op.end(err)
+	return err
+}
+
+// ListMultipartUploads lists the multipart uploads in progress.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	ctx, op := b.in.start(ctx, "ListMultipartUploads", b.Name(), prefixKey.String(prefix))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, "")
+	var uploads []absos.MultipartUpload
+	if err == nil {
+		uploads, err = mb.ListMultipartUploads(ctx, prefix)
+	}
+	op.end(err)
+	return uploads, err
+}
+
+// Versioning returns the versioning state of the bucket. The versioning
+// operations fail with absos.ErrNotSupported if the wrapped bucket does not
+// support them.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	ctx, op := b.in.start(ctx, "Versioning", b.Name())
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	var status absos.VersioningStatus
+	if err == nil {
+		status, err = vb.Versioning(ctx)
+	}
+	op.end(err)
+	return status, err
+}
+
+// SetVersioning enables or suspends versioning.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	ctx, op := b.in.start(ctx, "SetVersioning", b.Name())
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err == nil {
+		err = vb.SetVersioning(ctx, status)
+	}
+	op.end(err)
+	return err
+}
+
+// ListVersions lists a page of versions.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	ctx, op := b.in.start(ctx, "ListVersions", b.Name(), prefixKey.String(prefix))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	var page absos.VersionPage
+	if err == nil {
+		page, err = vb.ListVersions(ctx, prefix, token)
+	}
+	op.end(err)
+	return page, err
+}
+
+// GetVersion opens a version of an object. Its span ends when the reader is
+// closed.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	ctx, op := b.in.start(ctx, "GetVersion", b.Name(), keyKey.String(key), versionKey.String(versionID))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+
+	r, err := vb.GetVersion(ctx, key, versionID)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// HeadVersion retrieves the metadata of a version of an object.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	ctx, op := b.in.start(ctx, "HeadVersion", b.Name(), keyKey.String(key), versionKey.String(versionID))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	var header absos.ObjectHeader
+	if err == nil {
+		header, err = vb.HeadVersion(ctx, key, versionID)
+	}
+	op.end(err)
+	return header, err
+}
+
+// DeleteVersion permanently removes a version or delete marker.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	ctx, op := b.in.start(ctx, "DeleteVersion", b.Name(), keyKey.String(key), versionKey.String(versionID))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err == nil {
+		err = vb.DeleteVersion(ctx, key, versionID)
+	}
+	op.end(err)
+	return err
+}
+
+// OpenRange opens part of the object, reading the whole object if it cannot
+// read ranges. Its span ends when the reader is closed.
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	ctx, op := o.in.start(ctx, "OpenRange", o.Bucket(), keyKey.String(o.Key()), offsetKey.Int64(offset), lengthKey.Int64(length))
+	r, err := absos.OpenRange(ctx, o.Object, offset, length)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
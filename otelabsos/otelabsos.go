@@ -0,0 +1,242 @@
+// Package otelabsos instruments object stores and buckets with OpenTelemetry,
+// whatever their backend:
+//
+//	store := otelabsos.New(s3.New(client))
+//
+// Every operation is traced with a span carrying the bucket, the key, the
+// number of bytes transferred and, for failures, an error class derived from
+// the absos sentinel errors. The spans of operations returning a reader, such
+// as Get, GetRange and Open, end when the reader is closed, so that they
+// cover the transfer.
+//
+// The following metrics are recorded, with the operation, the bucket and the
+// error class as attributes:
+//
+//   - absos.operation.duration: a histogram of the duration of operations
+//   - absos.io: a counter of the bytes read and written, by direction
+//   - absos.errors: a counter of failed operations
+//
+// The global providers of the otel package are used unless others are set
+// with WithTracerProvider and WithMeterProvider.
+package otelabsos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/measure"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer and meter of this package.
+const instrumentationName = "github.com/absfs/absos/otelabsos"
+
+// Attribute keys of spans and metrics.
+const (
+	operationKey    = attribute.Key("absos.operation")
+	bucketKey       = attribute.Key("absos.bucket")
+	keyKey          = attribute.Key("absos.key")
+	prefixKey       = attribute.Key("absos.prefix")
+	bytesKey        = attribute.Key("absos.bytes")
+	directionKey    = attribute.Key("absos.io.direction")
+	errorKey        = attribute.Key("error.type")
+	offsetKey       = attribute.Key("absos.range.offset")
+	lengthKey       = attribute.Key("absos.range.length")
+	sourceKeyKey    = attribute.Key("absos.source.key")
+	sourceBucketKey = attribute.Key("absos.source.bucket")
+	partKey         = attribute.Key("absos.part")
+	versionKey      = attribute.Key("absos.version")
+	keysKey         = attribute.Key("absos.keys")
+	methodKey       = attribute.Key("http.request.method")
+)
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the provider of the tracer creating spans.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.tracerProvider = tp
+		}
+	}
+}
+
+// WithMeterProvider sets the provider of the meter recording metrics.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		if mp != nil {
+			c.meterProvider = mp
+		}
+	}
+}
+
+// instruments are shared by a Store and its buckets.
+type instruments struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	bytes    metric.Int64Counter
+	errors   metric.Int64Counter
+}
+
+func newInstruments(opts []Option) *instruments {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	in := &instruments{tracer: c.tracerProvider.Tracer(instrumentationName)}
+
+	// Failing instruments are reported to the global handler and replaced
+	// by no-ops, so that instrumentation never breaks the store.
+	var err error
+	in.duration, err = meter.Float64Histogram("absos.operation.duration",
+		metric.WithDescription("Duration of object store operations."),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	in.bytes, err = meter.Int64Counter("absos.io",
+		metric.WithDescription("Bytes read from and written to object stores."),
+		metric.WithUnit("By"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	in.errors, err = meter.Int64Counter("absos.errors",
+		metric.WithDescription("Failed object store operations."),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return in
+}
+
+// operation is an instrumented operation in progress.
+type operation struct {
+	in     *instruments
+	span   trace.Span
+	attrs  []attribute.KeyValue
+	start  time.Time
+	ctx    context.Context
+	closed bool
+}
+
+// start begins the span of the operation name on bucket, which is empty for
+// operations on the store. The attributes recorded on the span only, such as
+// keys, would give metrics too many distinct values.
+func (in *instruments) start(ctx context.Context, name, bucket string, spanAttrs ...attribute.KeyValue) (context.Context, *operation) {
+	attrs := []attribute.KeyValue{operationKey.String(name)}
+	if bucket != "" {
+		attrs = append(attrs, bucketKey.String(bucket))
+	}
+
+	ctx, span := in.tracer.Start(ctx, "absos."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(spanAttrs...))
+
+	return ctx, &operation{in: in, span: span, attrs: attrs, start: time.Now(), ctx: ctx}
+}
+
+// transferred records n bytes moved in direction ("read" or "write").
+func (o *operation) transferred(n int64, direction string) {
+	o.span.SetAttributes(bytesKey.Int64(n))
+	if n > 0 && o.in.bytes != nil {
+		o.in.bytes.Add(o.ctx, n, metric.WithAttributes(append(o.attrs, directionKey.String(direction))...))
+	}
+}
+
+// end records the outcome of the operation and ends its span.
+func (o *operation) end(err error) {
+	if o.closed {
+		return
+	}
+	o.closed = true
+
+	attrs := o.attrs
+	if err != nil {
+		class := ErrorClass(err)
+		attrs = append(attrs, errorKey.String(class))
+
+		o.span.SetAttributes(errorKey.String(class))
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+
+		if o.in.errors != nil {
+			o.in.errors.Add(o.ctx, 1, metric.WithAttributes(attrs...))
+		}
+	}
+
+	if o.in.duration != nil {
+		o.in.duration.Record(o.ctx, time.Since(o.start).Seconds(), metric.WithAttributes(attrs...))
+	}
+	o.span.End()
+}
+
+// classes maps the absos sentinel errors to the error classes of spans and
+// metrics. Context errors come first, as they wrap other errors.
+var classes = []struct {
+	err   error
+	class string
+}{
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+	{absos.ErrBucketNotFound, "bucket_not_found"},
+	{absos.ErrObjectNotFound, "object_not_found"},
+	{absos.ErrVersionNotFound, "version_not_found"},
+	{absos.ErrUploadNotFound, "upload_not_found"},
+	{absos.ErrBucketAlreadyExists, "bucket_already_exists"},
+	{absos.ErrBucketNotEmpty, "bucket_not_empty"},
+	{absos.ErrInvalidBucketName, "invalid_bucket_name"},
+	{absos.ErrInvalidKey, "invalid_key"},
+	{absos.ErrInvalidRange, "invalid_range"},
+	{absos.ErrInvalidPart, "invalid_part"},
+	{absos.ErrPermissionDenied, "permission_denied"},
+	{absos.ErrPreconditionFailed, "precondition_failed"},
+	{absos.ErrNotModified, "not_modified"},
+	{absos.ErrChecksumMismatch, "checksum_mismatch"},
+	{absos.ErrThrottled, "throttled"},
+	{absos.ErrUnavailable, "unavailable"},
+	{absos.ErrUploadAborted, "upload_aborted"},
+	{absos.ErrNotSupported, "not_supported"},
+}
+
+// ErrorClass returns the class recorded for err: the name of the absos
+// sentinel error it wraps in snake case, such as "object_not_found", or
+// "other" if it wraps none.
+func ErrorClass(err error) string {
+	for _, c := range classes {
+		if errors.Is(err, c.err) {
+			return c.class
+		}
+	}
+	return "other"
+}
+
+// reader returns r counting the bytes read from an object, which ends the
+// operation that opened it when closed.
+func (o *operation) reader(r io.ReadCloser) io.ReadCloser {
+	return measure.NewReader(r, func(n int64, err error) {
+		o.transferred(n, "read")
+		o.end(err)
+	})
+}
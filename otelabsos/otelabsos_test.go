@@ -0,0 +1,270 @@
+package otelabsos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestStore returns an instrumented memory store with a bucket, and the
+// recorders of its spans and metrics.
+func newTestStore(t *testing.T) (*Store, absos.Bucket, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	spans := tracetest.NewSpanRecorder()
+	metrics := sdkmetric.NewManualReader()
+
+	store := New(memory.NewStore(),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics))))
+
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	return store, buckets[0], spans, metrics
+}
+
+// spanAttrs returns the attributes of the ended span with the given name.
+func spanAttrs(t *testing.T, spans *tracetest.SpanRecorder, name string) (map[attribute.Key]attribute.Value, sdktrace.ReadOnlySpan) {
+	t.Helper()
+
+	for _, s := range spans.Ended() {
+		if s.Name() == name {
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range s.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			return attrs, s
+		}
+	}
+
+	t.Fatalf("no span %q", name)
+	return nil, nil
+}
+
+// sum returns the value of the counter name for the attributes matching attrs.
+func sum(t *testing.T, metrics *sdkmetric.ManualReader, name string, attrs ...attribute.KeyValue) int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := metrics.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				matches := true
+				for _, kv := range attrs {
+					if v, ok := dp.Attributes.Value(kv.Key); !ok || v != kv.Value {
+						matches = false
+					}
+				}
+				if matches {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestPutGet(t *testing.T) {
+	_, b, spans, metrics := newTestStore(t)
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "key", strings.NewReader("hello")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	attrs, _ := spanAttrs(t, spans, "absos.Put")
+	if attrs[bucketKey].AsString() != "test-bucket" || attrs[keyKey].AsString() != "key" || attrs[bytesKey].AsInt64() != 5 {
+		t.Errorf("unexpected Put span attributes %v", attrs)
+	}
+
+	r, err := b.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	// The span of Get ends with the reader
+	for _, s := range spans.Ended() {
+		if s.Name() == "absos.Get" {
+			t.Fatal("expected the Get span to end when the reader is closed")
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	attrs, _ = spanAttrs(t, spans, "absos.Get")
+	if attrs[bytesKey].AsInt64() != 5 {
+		t.Errorf("expected 5 bytes read, got %v", attrs[bytesKey])
+	}
+
+	if n := sum(t, metrics, "absos.io", directionKey.String("write")); n != 5 {
+		t.Errorf("expected 5 bytes written, got %d", n)
+	}
+	if n := sum(t, metrics, "absos.io", directionKey.String("read")); n != 5 {
+		t.Errorf("expected 5 bytes read, got %d", n)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, b, spans, metrics := newTestStore(t)
+
+	_, err := b.Head(context.Background(), "missing")
+	if !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	attrs, span := spanAttrs(t, spans, "absos.Head")
+	if attrs[errorKey].AsString() != "object_not_found" {
+		t.Errorf("expected error class object_not_found, got %v", attrs[errorKey])
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", span.Status())
+	}
+
+	if n := sum(t, metrics, "absos.errors", operationKey.String("Head"), errorKey.String("object_not_found")); n != 1 {
+		t.Errorf("expected 1 error, got %d", n)
+	}
+}
+
+func TestObjectPage(t *testing.T) {
+	_, b, spans, metrics := newTestStore(t)
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "dir/key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	page, err := b.ObjectPage(ctx, "dir/", "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	r, err := page.Objects()[0].Open(ctx)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if attrs, _ := spanAttrs(t, spans, "absos.ObjectPage"); attrs[prefixKey].AsString() != "dir/" {
+		t.Errorf("expected prefix dir/, got %v", attrs[prefixKey])
+	}
+	if attrs, _ := spanAttrs(t, spans, "absos.Open"); attrs[keyKey].AsString() != "dir/key" || attrs[bytesKey].AsInt64() != 4 {
+		t.Errorf("unexpected Open span attributes %v", attrs)
+	}
+	if n := sum(t, metrics, "absos.io", operationKey.String("Open")); n != 4 {
+		t.Errorf("expected 4 bytes read by Open, got %d", n)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	_, b, spans, metrics := newTestStore(t)
+	ctx := context.Background()
+
+	cond := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("hello"), cond); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("again"), cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if n := sum(t, metrics, "absos.errors", operationKey.String("PutIf"), errorKey.String("precondition_failed")); n != 1 {
+		t.Errorf("expected 1 failed PutIf, got %d", n)
+	}
+
+	r, err := absos.GetRange(ctx, b, "key", 1, 3)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "ell" {
+		t.Errorf("expected ell, got %q", data)
+	}
+	if attrs, _ := spanAttrs(t, spans, "absos.GetRange"); attrs[offsetKey].AsInt64() != 1 || attrs[bytesKey].AsInt64() != 3 {
+		t.Errorf("unexpected GetRange span attributes %v", attrs)
+	}
+
+	if err := absos.Upload(ctx, b, "stream", strings.NewReader("streamed")); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if n := sum(t, metrics, "absos.io", operationKey.String("PutStream")); n != 8 {
+		t.Errorf("expected 8 bytes written by PutStream, got %d", n)
+	}
+
+	if err := absos.DeleteMany(ctx, b, []string{"key", "stream"}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if attrs, _ := spanAttrs(t, spans, "absos.DeleteMany"); attrs[keysKey].AsInt64() != 2 {
+		t.Errorf("unexpected DeleteMany span attributes %v", attrs)
+	}
+
+	// The memory bucket cannot presign URLs
+	if _, err := b.(*Bucket).Presign(ctx, "GET", "key", time.Minute); !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+	if n := sum(t, metrics, "absos.errors", operationKey.String("Presign"), errorKey.String("not_supported")); n != 1 {
+		t.Errorf("expected 1 failed Presign, got %d", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return New(memory.NewStore())
+	})
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{&absos.ObjectError{Err: absos.ErrObjectNotFound}, "object_not_found"},
+		{&absos.BucketError{Err: absos.ErrThrottled}, "throttled"},
+		{fmt.Errorf("%w: %w", context.Canceled, absos.ErrUnavailable), "canceled"},
+		{errors.New("boom"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.expected {
+			t.Errorf("ErrorClass(%v): expected %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
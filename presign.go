@@ -0,0 +1,43 @@
+package absos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Presigner is implemented by buckets and signers that can create URLs
+// granting temporary access to an object, which can be handed to browsers
+// and other services without sharing credentials.
+//
+// The s3 package signs URLs with SigV4. Stores without native support can
+// use the presign package, which signs URLs with HMAC and serves them with
+// an http.Handler.
+type Presigner interface {
+	// Presign returns a URL allowing its bearer to perform method on the
+	// object with the specified key until expires has elapsed. The method
+	// is http.MethodGet, http.MethodPut or http.MethodHead; other methods
+	// return ErrNotSupported.
+	Presign(ctx context.Context, method, key string, expires time.Duration) (string, error)
+}
+
+// errInvalidExpiry is returned for presigned URLs that would never be valid.
+var errInvalidExpiry = errors.New("presigned URL expiry must be positive")
+
+// CheckPresign validates the arguments of Presigner.Presign, returning
+// ErrNotSupported for methods other than GET, PUT and HEAD.
+func CheckPresign(method string, expires time.Duration) error {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodHead:
+	default:
+		return fmt.Errorf("%w: presigned %s requests", ErrNotSupported, method)
+	}
+
+	if expires <= 0 {
+		return errInvalidExpiry
+	}
+
+	return nil
+}
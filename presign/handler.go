@@ -0,0 +1,181 @@
+package presign
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// Handler serves the requests of URLs created by a Signer with the same
+// secret: GET and HEAD read an object and PUT uploads one. Requests whose
+// signature does not match or whose URL has expired are rejected with
+// 403 Forbidden.
+type Handler struct {
+	store  absos.ObjectStore
+	secret []byte
+	now    func() time.Time
+}
+
+// NewHandler returns a Handler serving the objects of store.
+func NewHandler(store absos.ObjectStore, secret []byte) *Handler {
+	return &Handler{store: store, secret: secret, now: time.Now}
+}
+
+// ServeHTTP verifies a presigned request and performs it.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	if err := h.verify(r, bucket, key); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	b, err := h.bucket(r.Context(), bucket)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		h.put(w, r, b, key)
+		return
+	}
+	h.get(w, r, b, key)
+}
+
+// verify checks the signature and expiry time of a request.
+func (h *Handler) verify(r *http.Request, bucket, key string) error {
+	q := r.URL.Query()
+
+	expiry, err := strconv.ParseInt(q.Get(expiresParam), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(q.Get(signatureParam))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(signature, sign(h.secret, r.Method, bucket, key, expiry)) {
+		return ErrInvalidSignature
+	}
+
+	if h.now().Unix() > expiry {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+// bucket looks up the named bucket in the store.
+func (h *Handler) bucket(ctx context.Context, name string) (absos.Bucket, error) {
+	buckets, err := h.store.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range buckets {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+
+	return nil, &absos.BucketError{Bucket: name, Err: absos.ErrBucketNotFound}
+}
+
+// getAttempts is the number of times get reads an object replaced between
+// its Head and its Get before it gives up.
+const getAttempts = 3
+
+// get serves GET and HEAD requests. The body is read from the object
+// described by the header: if the object is replaced in between, the request
+// starts over, and fails with 503 Service Unavailable once it ran out of
+// attempts.
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	var (
+		header absos.ObjectHeader
+		body   io.ReadCloser
+		err    error
+	)
+	for attempt := 0; attempt < getAttempts; attempt++ {
+		if header, err = b.Head(r.Context(), key); err != nil || r.Method == http.MethodHead {
+			break
+		}
+
+		if etag := header.ETag(); len(etag) > 0 {
+			body, err = absos.GetIf(r.Context(), b, key, absos.Conditions{IfMatch: etag})
+		} else {
+			body, err = b.Get(r.Context(), key)
+		}
+		if !errors.Is(err, absos.ErrPreconditionFailed) {
+			break
+		}
+	}
+
+	if errors.Is(err, absos.ErrPreconditionFailed) {
+		err = &absos.ObjectError{Bucket: b.Name(), Key: key, Err: absos.ErrUnavailable}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(header.Size(), 10))
+	w.Header().Set("Last-Modified", header.ModTime().UTC().Format(http.TimeFormat))
+	if mimeType := header.MimeType(); mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+	if etag := header.ETag(); etag != nil {
+		w.Header().Set("ETag", `"`+hex.EncodeToString(etag)+`"`)
+	}
+
+	if body == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	defer body.Close()
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
+
+// put serves PUT requests, keeping the Content-Type of the request.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	var opts []absos.PutOption
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		opts = append(opts, absos.WithContentType(contentType))
+	}
+
+	if err := absos.Upload(r.Context(), b, key, r.Body, opts...); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeError writes err with the matching HTTP status.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, absos.ErrBucketNotFound), errors.Is(err, absos.ErrObjectNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, absos.ErrInvalidKey), errors.Is(err, absos.ErrInvalidBucketName):
+		status = http.StatusBadRequest
+	case errors.Is(err, absos.ErrPermissionDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, absos.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,269 @@
+package presign
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+	"github.com/absfs/absos/filestore"
+)
+
+var secret = []byte("test-secret")
+
+// newTestSigner starts a Handler for store below /files and returns a
+// Signer for a new bucket of store.
+func newTestSigner(t *testing.T, store absos.ObjectStore) (*Signer, absos.Bucket) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle("/files/", http.StripPrefix("/files", NewHandler(store, secret)))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	signer, err := New(buckets[0], srv.URL+"/files/", secret)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	return signer, buckets[0]
+}
+
+// do performs a request for a presigned URL.
+func do(t *testing.T, method, url string, body io.Reader) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	return resp, string(data)
+}
+
+func TestPresign(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create filestore: %v", err)
+	}
+
+	stores := []struct {
+		name  string
+		store absos.ObjectStore
+	}{
+		{"Memory", memory.NewStore()},
+		{"Filestore", fs},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			signer, b := newTestSigner(t, tt.store)
+			key := "dir/a b+c.txt"
+
+			put, err := signer.Presign(ctx, http.MethodPut, key, time.Hour)
+			if err != nil {
+				t.Fatalf("failed to presign: %v", err)
+			}
+
+			if resp, body := do(t, http.MethodPut, put, strings.NewReader("data")); resp.StatusCode != http.StatusOK {
+				t.Fatalf("PUT: expected 200, got %d: %s", resp.StatusCode, body)
+			}
+
+			header, err := b.Head(ctx, key)
+			if err != nil {
+				t.Fatalf("failed to head uploaded object: %v", err)
+			}
+			if header.Size() != 4 || header.MimeType() != "text/plain" {
+				t.Errorf("expected 4 bytes of text/plain, got %d bytes of %s", header.Size(), header.MimeType())
+			}
+
+			get, err := signer.Presign(ctx, http.MethodGet, key, time.Hour)
+			if err != nil {
+				t.Fatalf("failed to presign: %v", err)
+			}
+
+			resp, body := do(t, http.MethodGet, get, nil)
+			if resp.StatusCode != http.StatusOK || body != "data" {
+				t.Errorf("GET: expected 200 and %q, got %d and %q", "data", resp.StatusCode, body)
+			}
+			if resp.Header.Get("Content-Type") != "text/plain" {
+				t.Errorf("GET: expected Content-Type text/plain, got %q", resp.Header.Get("Content-Type"))
+			}
+
+			head, err := signer.Presign(ctx, http.MethodHead, key, time.Hour)
+			if err != nil {
+				t.Fatalf("failed to presign: %v", err)
+			}
+
+			if resp, _ := do(t, http.MethodHead, head, nil); resp.StatusCode != http.StatusOK || resp.ContentLength != 4 {
+				t.Errorf("HEAD: expected 200 and length 4, got %d and %d", resp.StatusCode, resp.ContentLength)
+			}
+
+			// A URL only grants the method it was signed for
+			if resp, _ := do(t, http.MethodPut, get, strings.NewReader("other")); resp.StatusCode != http.StatusForbidden {
+				t.Errorf("PUT with GET URL: expected 403, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPresignRejected(t *testing.T) {
+	ctx := context.Background()
+	signer, b := newTestSigner(t, memory.NewStore())
+
+	if err := b.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	valid, err := signer.Presign(ctx, http.MethodGet, "key", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expired, err := signer.Presign(ctx, http.MethodGet, "key", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+
+	other, err := New(b, strings.SplitAfter(valid, "/files/")[0], []byte("other-secret"))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	foreign, err := other.Presign(ctx, http.MethodGet, "key", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+
+	missing, err := signer.Presign(ctx, http.MethodGet, "missing", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{"Expired", expired, http.StatusForbidden, ErrExpired.Error()},
+		{"OtherSecret", foreign, http.StatusForbidden, ErrInvalidSignature.Error()},
+		{"OtherKey", strings.Replace(valid, "/key?", "/kez?", 1), http.StatusForbidden, ErrInvalidSignature.Error()},
+		{"Unsigned", strings.Split(valid, "?")[0], http.StatusForbidden, ErrInvalidSignature.Error()},
+		{"Missing", missing, http.StatusNotFound, absos.ErrObjectNotFound.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := do(t, http.MethodGet, tt.url, nil)
+			if resp.StatusCode != tt.status || !strings.Contains(body, tt.body) {
+				t.Errorf("expected %d and %q, got %d and %q", tt.status, tt.body, resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestPresignInvalid(t *testing.T) {
+	ctx := context.Background()
+	signer, _ := newTestSigner(t, memory.NewStore())
+
+	if _, err := signer.Presign(ctx, http.MethodDelete, "key", time.Hour); !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for DELETE, got %v", err)
+	}
+
+	if _, err := signer.Presign(ctx, http.MethodGet, "key", 0); err == nil {
+		t.Error("expected an error for a zero expiry")
+	}
+}
+
+// racingStore replaces an object after each of the first replaces Heads of
+// it, like a writer racing with the reads of the handler.
+type racingStore struct {
+	absos.ObjectStore
+	replaces atomic.Int64
+}
+
+func (s *racingStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := s.ObjectStore.ListBuckets(ctx)
+	for i, b := range buckets {
+		buckets[i] = &racingBucket{Bucket: b, s: s}
+	}
+	return buckets, err
+}
+
+type racingBucket struct {
+	absos.Bucket
+	s *racingStore
+}
+
+func (b *racingBucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	header, err := b.Bucket.Head(ctx, key)
+	if n := b.s.replaces.Add(-1); err == nil && n >= 0 {
+		err = b.Bucket.Put(ctx, key, strings.NewReader(fmt.Sprintf("replaced %d", n)))
+	}
+	return header, err
+}
+
+func TestPresignGetReplaced(t *testing.T) {
+	ctx := context.Background()
+	store := &racingStore{ObjectStore: memory.NewStore()}
+	signer, b := newTestSigner(t, store)
+
+	if err := b.Put(ctx, "key", strings.NewReader("original")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	get, err := signer.Presign(ctx, http.MethodGet, "key", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+
+	// The object replaced after the first Head is served with its own header
+	store.replaces.Store(1)
+	resp, body := do(t, http.MethodGet, get, nil)
+	sum := md5.Sum([]byte(body))
+	if resp.StatusCode != http.StatusOK || body != "replaced 0" {
+		t.Errorf("expected the replaced object, got %d: %q", resp.StatusCode, body)
+	}
+	if etag := resp.Header.Get("ETag"); etag != fmt.Sprintf("%q", hex.EncodeToString(sum[:])) {
+		t.Errorf("expected the ETag of the body, got %s", etag)
+	}
+
+	// An object replaced on every attempt is unavailable
+	store.replaces.Store(100)
+	if resp, _ := do(t, http.MethodGet, get, nil); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,30 @@
+package absos
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCheckPresign(t *testing.T) {
+	tests := []struct {
+		method  string
+		expires time.Duration
+		err     error
+	}{
+		{http.MethodGet, time.Hour, nil},
+		{http.MethodPut, time.Second, nil},
+		{http.MethodHead, time.Minute, nil},
+		{http.MethodDelete, time.Hour, ErrNotSupported},
+		{http.MethodPost, time.Hour, ErrNotSupported},
+		{http.MethodGet, 0, errInvalidExpiry},
+		{http.MethodGet, -time.Hour, errInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		if err := CheckPresign(tt.method, tt.expires); !errors.Is(err, tt.err) {
+			t.Errorf("CheckPresign(%s, %v): expected %v, got %v", tt.method, tt.expires, tt.err, err)
+		}
+	}
+}
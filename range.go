@@ -0,0 +1,140 @@
+package absos
+
+import (
+	"context"
+	"io"
+)
+
+// RangeBucket is implemented by buckets that can read part of an object
+// without transferring the rest of it.
+type RangeBucket interface {
+	Bucket
+
+	// GetRange returns a reader for part of the object with the specified key.
+	// The range is interpreted as described for ResolveRange.
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// RangeObject is implemented by objects that can be opened at an offset.
+type RangeObject interface {
+	Object
+
+	// OpenRange opens part of the object for reading.
+	// The range is interpreted as described for ResolveRange.
+	OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+}
+
+// ResolveRange returns the first byte and the number of bytes selected by a
+// range within an object of the given size.
+//
+// A non-negative offset selects length bytes starting at offset; a negative
+// length, or one extending past the end of the object, selects everything up
+// to the end. A negative offset selects the last -offset bytes (a suffix
+// range) and length is ignored; suffixes longer than the object select the
+// whole object.
+//
+// ResolveRange returns ErrInvalidRange if the range selects no bytes: when
+// offset is at or past the end of the object, when length is zero, or when
+// the object is empty.
+func ResolveRange(size, offset, length int64) (start, n int64, err error) {
+	if offset < 0 {
+		n = -offset
+		if n > size {
+			n = size
+		}
+		if n == 0 {
+			return 0, 0, ErrInvalidRange
+		}
+		return size - n, n, nil
+	}
+
+	if offset >= size || length == 0 {
+		return 0, 0, ErrInvalidRange
+	}
+
+	n = size - offset
+	if length > 0 && length < n {
+		n = length
+	}
+
+	return offset, n, nil
+}
+
+// GetRange reads part of the object with the specified key from b.
+// It uses RangeBucket if b implements it; otherwise it reads the whole object
+// with Get and discards the bytes outside the range.
+func GetRange(ctx context.Context, b Bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	if rb, ok := b.(RangeBucket); ok {
+		return rb.GetRange(ctx, key, offset, length)
+	}
+
+	header, err := b.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return readRange(b.Name(), key, header.Size(), offset, length, func() (io.ReadCloser, error) {
+		return b.Get(ctx, key)
+	})
+}
+
+// GetRangeIf reads part of the object with the specified key from b if
+// cond holds. No bucket interface combines range reads with conditions, so
+// the range is opened with GetRange before cond is checked with HeadIf: a
+// range opened from an object replaced in between fails like HeadIf, for
+// instance with ErrPreconditionFailed for IfMatch.
+func GetRangeIf(ctx context.Context, b Bucket, key string, offset, length int64, cond Conditions) (io.ReadCloser, error) {
+	r, err := GetRange(ctx, b, key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := HeadIf(ctx, b, key, cond); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// OpenRange opens part of obj for reading.
+// It uses RangeObject if obj implements it; otherwise it opens the whole
+// object and discards the bytes outside the range.
+func OpenRange(ctx context.Context, obj Object, offset, length int64) (io.ReadCloser, error) {
+	if ro, ok := obj.(RangeObject); ok {
+		return ro.OpenRange(ctx, offset, length)
+	}
+
+	return readRange(obj.Bucket(), obj.Key(), obj.Size(), offset, length, func() (io.ReadCloser, error) {
+		return obj.Open(ctx)
+	})
+}
+
+// readRange implements a range read of an object of the given size on top
+// of the full read returned by open.
+func readRange(bucket, key string, size, offset, length int64, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	start, n, err := ResolveRange(size, offset, length)
+	if err != nil {
+		return nil, &ObjectError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.CopyN(io.Discard, rc, start); err != nil {
+		rc.Close()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, &ObjectError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return &limitedReadCloser{Reader: io.LimitReader(rc, n), Closer: rc}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,130 @@
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+)
+
+func TestResolveRange(t *testing.T) {
+	tests := []struct {
+		name                 string
+		size, offset, length int64
+		start, n             int64
+		err                  error
+	}{
+		{"Prefix", 10, 0, 4, 0, 4, nil},
+		{"Middle", 10, 3, 4, 3, 4, nil},
+		{"ToEnd", 10, 3, -1, 3, 7, nil},
+		{"PastEnd", 10, 8, 10, 8, 2, nil},
+		{"Suffix", 10, -3, 0, 7, 3, nil},
+		{"LongSuffix", 10, -20, 0, 0, 10, nil},
+		{"OffsetAtEnd", 10, 10, 1, 0, 0, absos.ErrInvalidRange},
+		{"OffsetPastEnd", 10, 11, -1, 0, 0, absos.ErrInvalidRange},
+		{"ZeroLength", 10, 0, 0, 0, 0, absos.ErrInvalidRange},
+		{"EmptyObject", 0, 0, -1, 0, 0, absos.ErrInvalidRange},
+		{"EmptySuffix", 0, -1, 0, 0, 0, absos.ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, n, err := absos.ResolveRange(tt.size, tt.offset, tt.length)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if start != tt.start || n != tt.n {
+				t.Errorf("expected start %d and length %d, got %d and %d", tt.start, tt.n, start, n)
+			}
+		})
+	}
+}
+
+// plainBucket hides the optional capabilities of the wrapped bucket.
+type plainBucket struct {
+	absos.Bucket
+}
+
+func TestGetRangeFallback(t *testing.T) {
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	bucket := plainBucket{buckets[0]}
+
+	if _, ok := absos.Bucket(bucket).(absos.RangeBucket); ok {
+		t.Fatal("expected bucket without range support")
+	}
+
+	if err := bucket.Put(ctx, "digits", strings.NewReader("0123456789")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	reader, err := absos.GetRange(ctx, bucket, "digits", 2, 5)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	defer reader.Close()
+
+	content, _ := io.ReadAll(reader)
+	if string(content) != "23456" {
+		t.Errorf("expected %q, got %q", "23456", content)
+	}
+
+	_, err = absos.GetRange(ctx, bucket, "digits", 10, 1)
+	var objErr *absos.ObjectError
+	if !errors.Is(err, absos.ErrInvalidRange) || !errors.As(err, &objErr) || objErr.Key != "digits" {
+		t.Errorf("expected ErrInvalidRange for digits, got %v", err)
+	}
+
+	if _, err := absos.GetRange(ctx, bucket, "missing", 0, 1); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestGetRangeIf(t *testing.T) {
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	bucket := buckets[0]
+
+	if err := bucket.Put(ctx, "digits", strings.NewReader("0123456789")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	header, err := bucket.Head(ctx, "digits")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+	cond := absos.Conditions{IfMatch: header.ETag()}
+
+	reader, err := absos.GetRangeIf(ctx, bucket, "digits", 2, 5, cond)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "23456" {
+		t.Errorf("expected %q, got %q", "23456", content)
+	}
+
+	if err := bucket.Put(ctx, "digits", strings.NewReader("9876543210")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	if _, err := absos.GetRangeIf(ctx, bucket, "digits", 2, 5, cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+// Package readerat provides random access to objects through io.ReaderAt
+// and io.ReadSeeker, so they can be handed to archive/zip, debug/elf, image
+// decoders and similar readers without downloading them first.
+//
+// Objects are read in fixed-size blocks with range reads. Recently used
+// blocks are kept in an LRU cache, and sequential access triggers read-ahead
+// of the following blocks in the background.
+package readerat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/absfs/absos"
+)
+
+// Default configuration values.
+const (
+	DefaultBlockSize   = 1 << 20
+	DefaultReadAhead   = 1
+	DefaultCacheBlocks = 32
+)
+
+var errNegativeOffset = errors.New("readerat: negative offset")
+
+// Option configures a Reader.
+type Option func(*Reader)
+
+// WithBlockSize sets the number of bytes fetched by each range read.
+func WithBlockSize(n int64) Option {
+	return func(r *Reader) {
+		if n > 0 {
+			r.blockSize = n
+		}
+	}
+}
+
+// WithReadAhead sets the number of blocks fetched ahead of sequential reads.
+// Zero disables read-ahead.
+func WithReadAhead(blocks int) Option {
+	return func(r *Reader) {
+		if blocks >= 0 {
+			r.readAhead = blocks
+		}
+	}
+}
+
+// WithCacheBlocks sets the number of blocks kept in the LRU cache.
+func WithCacheBlocks(blocks int) Option {
+	return func(r *Reader) {
+		if blocks > 0 {
+			r.cacheBlocks = blocks
+		}
+	}
+}
+
+// fetchFunc reads length bytes of the object starting at offset.
+type fetchFunc func(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+
+// Reader reads an object through a block cache. It implements io.ReaderAt,
+// io.ReadSeeker and io.Closer. ReadAt may be called concurrently; Read and
+// Seek share an offset and, like those of an *os.File, should not be.
+//
+// All reads use the context the Reader was created with. Close cancels
+// pending read-ahead and releases the cache.
+type Reader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	fetch  fetchFunc
+	size   int64
+
+	blockSize   int64
+	readAhead   int
+	cacheBlocks int
+
+	mu      sync.Mutex
+	cache   map[int64]*block
+	pending map[int64]*call
+	clock   uint64
+	last    int64
+	offset  int64
+}
+
+type block struct {
+	data []byte
+	used uint64
+}
+
+// call is an in-flight fetch of a block.
+type call struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// New returns a Reader for the object with the specified key in b.
+// The object's size and ETag are determined with Head, and blocks are only
+// read from the object with that ETag: once it is replaced, reads fail with
+// absos.ErrPreconditionFailed instead of mixing the contents of both.
+func New(ctx context.Context, b absos.Bucket, key string, opts ...Option) (*Reader, error) {
+	header, err := b.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	etag := header.ETag()
+	return newReader(ctx, header.Size(), func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		if etag == nil {
+			return absos.GetRange(ctx, b, key, offset, length)
+		}
+		return absos.GetRangeIf(ctx, b, key, offset, length, absos.Conditions{IfMatch: etag})
+	}, opts), nil
+}
+
+// NewObject returns a Reader for obj, using the size reported by obj.
+func NewObject(ctx context.Context, obj absos.Object, opts ...Option) *Reader {
+	return newReader(ctx, obj.Size(), func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		return absos.OpenRange(ctx, obj, offset, length)
+	}, opts)
+}
+
+func newReader(ctx context.Context, size int64, fetch fetchFunc, opts []Option) *Reader {
+	r := &Reader{
+		fetch:       fetch,
+		size:        size,
+		blockSize:   DefaultBlockSize,
+		readAhead:   DefaultReadAhead,
+		cacheBlocks: DefaultCacheBlocks,
+		cache:       make(map[int64]*block),
+		pending:     make(map[int64]*call),
+		last:        -1,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	return r
+}
+
+// Size returns the size of the object in bytes.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < r.size {
+		i := off / r.blockSize
+		data, err := r.block(i)
+		if err != nil {
+			return n, err
+		}
+
+		c := copy(p[n:], data[off-i*r.blockSize:])
+		n += c
+		off += int64(c)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	off := r.offset
+	r.mu.Unlock()
+
+	n, err := r.ReadAt(p, off)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+
+	r.mu.Lock()
+	r.offset = off + int64(n)
+	r.mu.Unlock()
+
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("readerat: invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+// Close cancels pending read-ahead and releases the cached blocks.
+func (r *Reader) Close() error {
+	r.cancel()
+
+	r.mu.Lock()
+	r.cache = make(map[int64]*block)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// block returns the contents of block i, fetching it if it is not cached.
+func (r *Reader) block(i int64) ([]byte, error) {
+	r.mu.Lock()
+
+	sequential := i == r.last+1
+	r.last = i
+
+	if sequential {
+		for j := i + 1; j <= i+int64(r.readAhead); j++ {
+			r.startLocked(j)
+		}
+	}
+
+	if b, ok := r.cache[i]; ok {
+		r.clock++
+		b.used = r.clock
+		r.mu.Unlock()
+		return b.data, nil
+	}
+
+	c := r.startLocked(i)
+	r.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.data, c.err
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+}
+
+// startLocked starts fetching block i unless it is cached, already being
+// fetched or past the end of the object. The caller must hold r.mu.
+func (r *Reader) startLocked(i int64) *call {
+	if c, ok := r.pending[i]; ok {
+		return c
+	}
+
+	off := i * r.blockSize
+	if off >= r.size {
+		return nil
+	}
+	if _, ok := r.cache[i]; ok {
+		return nil
+	}
+
+	length := r.blockSize
+	if off+length > r.size {
+		length = r.size - off
+	}
+
+	c := &call{done: make(chan struct{})}
+	r.pending[i] = c
+
+	go func() {
+		c.data, c.err = r.read(off, length)
+
+		r.mu.Lock()
+		delete(r.pending, i)
+		if c.err == nil {
+			r.storeLocked(i, c.data)
+		}
+		r.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	return c
+}
+
+// read fetches length bytes starting at off.
+func (r *Reader) read(off, length int64) ([]byte, error) {
+	rc, err := r.fetch(r.ctx, off, length)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(rc, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// storeLocked caches block i, evicting the least recently used block if the
+// cache is full. The caller must hold r.mu.
+func (r *Reader) storeLocked(i int64, data []byte) {
+	if len(r.cache) >= r.cacheBlocks {
+		var (
+			oldest int64
+			lowest uint64
+			found  bool
+		)
+		for j, b := range r.cache {
+			if !found || b.used < lowest {
+				oldest, lowest, found = j, b.used, true
+			}
+		}
+		delete(r.cache, oldest)
+	}
+
+	r.clock++
+	r.cache[i] = &block{data: data, used: r.clock}
+}
@@ -0,0 +1,308 @@
+package readerat
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// countingBucket counts the range reads issued against the wrapped bucket.
+type countingBucket struct {
+	absos.RangeBucket
+	reads atomic.Int64
+}
+
+func (b *countingBucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	b.reads.Add(1)
+	return b.RangeBucket.GetRange(ctx, key, offset, length)
+}
+
+func newTestBucket(t *testing.T, key string, data []byte) *countingBucket {
+	t.Helper()
+
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	if err := buckets[0].Put(ctx, key, bytes.NewReader(data)); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	rb, ok := buckets[0].(absos.RangeBucket)
+	if !ok {
+		t.Fatal("expected bucket to implement absos.RangeBucket")
+	}
+
+	return &countingBucket{RangeBucket: rb}
+}
+
+func TestReadAt(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	bucket := newTestBucket(t, "data", data)
+
+	r, err := New(context.Background(), bucket, "data", WithBlockSize(4), WithReadAhead(0))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if r.Size() != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), r.Size())
+	}
+
+	tests := []struct {
+		off      int64
+		n        int
+		expected string
+		err      error
+	}{
+		{0, 4, "0123", nil},
+		{2, 7, "2345678", nil},
+		{30, 6, "uvwxyz", nil},
+		{30, 10, "uvwxyz", io.EOF},
+		{36, 1, "", io.EOF},
+	}
+
+	for _, tt := range tests {
+		p := make([]byte, tt.n)
+		n, err := r.ReadAt(p, tt.off)
+		if err != tt.err {
+			t.Errorf("ReadAt(%d, %d): expected error %v, got %v", tt.off, tt.n, tt.err, err)
+		}
+		if string(p[:n]) != tt.expected {
+			t.Errorf("ReadAt(%d, %d): expected %q, got %q", tt.off, tt.n, tt.expected, p[:n])
+		}
+	}
+
+	if _, err := r.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Error("expected error for negative offset")
+	}
+}
+
+func TestCache(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 100)
+	bucket := newTestBucket(t, "data", data)
+
+	r, err := New(context.Background(), bucket, "data", WithBlockSize(10), WithReadAhead(0), WithCacheBlocks(2))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	p := make([]byte, 5)
+	for _, off := range []int64{0, 5, 10, 0, 15} {
+		if _, err := r.ReadAt(p, off); err != nil {
+			t.Fatalf("ReadAt(%d): %v", off, err)
+		}
+	}
+
+	// Blocks 0 and 1 were each fetched once
+	if n := bucket.reads.Load(); n != 2 {
+		t.Errorf("expected 2 range reads, got %d", n)
+	}
+
+	// Block 2 evicts block 0, the least recently used, while block 1 stays cached
+	for _, off := range []int64{20, 10, 0} {
+		if _, err := r.ReadAt(p, off); err != nil {
+			t.Fatalf("ReadAt(%d): %v", off, err)
+		}
+	}
+
+	if n := bucket.reads.Load(); n != 4 {
+		t.Errorf("expected 4 range reads, got %d", n)
+	}
+}
+
+func TestReadAhead(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	bucket := newTestBucket(t, "data", data)
+
+	r, err := New(context.Background(), bucket, "data", WithBlockSize(10), WithReadAhead(2))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	if !bytes.Equal(content, data) {
+		t.Errorf("expected %d bytes of data, got %q", len(data), content)
+	}
+
+	// Read-ahead never fetches a block twice
+	if n := bucket.reads.Load(); n != 10 {
+		t.Errorf("expected 10 range reads, got %d", n)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	bucket := newTestBucket(t, "data", []byte("0123456789"))
+
+	r, err := New(context.Background(), bucket, "data", WithBlockSize(3))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	tests := []struct {
+		offset   int64
+		whence   int
+		pos      int64
+		expected string
+	}{
+		{4, io.SeekStart, 4, "456789"},
+		{-3, io.SeekEnd, 7, "789"},
+		{-5, io.SeekCurrent, 5, "56789"},
+	}
+
+	for _, tt := range tests {
+		pos, err := r.Seek(tt.offset, tt.whence)
+		if err != nil || pos != tt.pos {
+			t.Errorf("Seek(%d, %d): expected %d, got %d (%v)", tt.offset, tt.whence, tt.pos, pos, err)
+		}
+
+		content, err := io.ReadAll(r)
+		if err != nil || string(content) != tt.expected {
+			t.Errorf("after Seek(%d, %d): expected %q, got %q (%v)", tt.offset, tt.whence, tt.expected, content, err)
+		}
+	}
+
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Error("expected error for negative position")
+	}
+}
+
+func TestConcurrentReadAt(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	bucket := newTestBucket(t, "data", data)
+
+	r, err := New(context.Background(), bucket, "data", WithBlockSize(64), WithCacheBlocks(4))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			p := make([]byte, 37)
+			for off := int64(w); off < int64(len(data)-len(p)); off += 53 {
+				if _, err := r.ReadAt(p, off); err != nil {
+					t.Errorf("ReadAt(%d): %v", off, err)
+					return
+				}
+				if !bytes.Equal(p, data[off:off+int64(len(p))]) {
+					t.Errorf("ReadAt(%d): unexpected data %q", off, p)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, _ := zw.Create(name)
+		_, _ = io.WriteString(w, strings.Repeat(name, 1000))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+
+	bucket := newTestBucket(t, "archive.zip", buf.Bytes())
+	ctx := context.Background()
+
+	page, err := bucket.ObjectPage(ctx, "archive.zip", "", "")
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+
+	r := NewObject(ctx, page.Objects()[0], WithBlockSize(512))
+	defer r.Close()
+
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+
+	f, err := zr.Open("b.txt")
+	if err != nil {
+		t.Fatalf("failed to open b.txt: %v", err)
+	}
+	defer f.Close()
+
+	content, _ := io.ReadAll(f)
+	if string(content) != strings.Repeat("b.txt", 1000) {
+		t.Errorf("unexpected content of b.txt: %d bytes", len(content))
+	}
+}
+
+func TestMissingObject(t *testing.T) {
+	bucket := newTestBucket(t, "data", []byte("data"))
+
+	if _, err := New(context.Background(), bucket, "missing"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestReplacedObject(t *testing.T) {
+	bucket := newTestBucket(t, "data", []byte("0123456789"))
+	ctx := context.Background()
+
+	r, err := New(ctx, bucket, "data", WithBlockSize(4), WithReadAhead(0))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	p := make([]byte, 4)
+	if _, err := r.ReadAt(p, 0); err != nil || string(p) != "0123" {
+		t.Fatalf("expected 0123, got %q: %v", p, err)
+	}
+
+	if err := bucket.Put(ctx, "data", strings.NewReader("abcdefghij")); err != nil {
+		t.Fatalf("failed to replace object: %v", err)
+	}
+	if _, err := r.ReadAt(p, 4); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	bucket := newTestBucket(t, "data", []byte("0123456789"))
+
+	r, err := New(context.Background(), bucket, "data")
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if _, err := r.ReadAt(make([]byte, 1), 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled after Close, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/capability"
+)
+
+var (
+	_ absos.RangeBucket       = (*Bucket)(nil)
+	_ absos.ConditionalBucket = (*Bucket)(nil)
+	_ absos.StreamBucket      = (*Bucket)(nil)
+	_ absos.CopyBucket        = (*Bucket)(nil)
+	_ absos.MultipartBucket   = (*Bucket)(nil)
+	_ absos.VersionedBucket   = (*Bucket)(nil)
+	_ absos.BulkDeleteBucket  = (*Bucket)(nil)
+	_ absos.Presigner         = (*Bucket)(nil)
+)
+
+// GetRange opens part of an object, retrying temporary failures.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return do(ctx, b.policy, func() (io.ReadCloser, error) {
+		return absos.GetRange(ctx, b.Bucket, key, offset, length)
+	})
+}
+
+// PutIf uploads an object if cond holds, without retrying: a retry of a
+// write that succeeded would fail with absos.ErrPreconditionFailed.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	return absos.PutIf(ctx, b.Bucket, key, data, cond, opts...)
+}
+
+// GetIf opens an object if cond holds, retrying temporary failures.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	return do(ctx, b.policy, func() (io.ReadCloser, error) {
+		return absos.GetIf(ctx, b.Bucket, key, cond)
+	})
+}
+
+// HeadIf retrieves the metadata of an object if cond holds, retrying
+// temporary failures.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	return do(ctx, b.policy, func() (absos.ObjectHeader, error) {
+		return absos.HeadIf(ctx, b.Bucket, key, cond)
+	})
+}
+
+// DeleteIf removes an object if cond holds, without retrying.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	return absos.DeleteIf(ctx, b.Bucket, key, cond)
+}
+
+// PutStream uploads an object read from data until io.EOF. A stream cannot
+// be rewound, so native streamed uploads of the wrapped bucket are not
+// retried; otherwise the data is uploaded in retried parts, or buffered and
+// uploaded with the retrying Put.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	if sb, ok := b.Bucket.(absos.StreamBucket); ok {
+		return sb.PutStream(ctx, key, data, opts...)
+	}
+	if _, ok := b.Bucket.(absos.MultipartBucket); ok {
+		return absos.Upload(ctx, struct{ absos.MultipartBucket }{b}, key, data, opts...)
+	}
+	return absos.Upload(ctx, struct{ absos.Bucket }{b}, key, data, opts...)
+}
+
+// Copy copies an object, retrying temporary failures. It fails with
+// absos.ErrNotSupported if the wrapped bucket cannot copy from src.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	if s, ok := src.(*Bucket); ok {
+		src = s.Bucket
+	}
+
+	cb, err := capability.As[absos.CopyBucket](b.Bucket, dstKey)
+	if err != nil {
+		return err
+	}
+	return run(ctx, b.policy, func() error {
+		return cb.Copy(ctx, src, srcKey, dstKey, opts...)
+	})
+}
+
+// DeleteLimit returns the number of keys the wrapped bucket deletes at
+// once, or 1 if it deletes them one by one.
+func (b *Bucket) DeleteLimit() int {
+	return capability.DeleteLimit(b.Bucket)
+}
+
+// DeleteMany deletes the objects with the given keys, retrying the keys
+// that failed with a temporary error. Missing objects are not failures, so a
+// retry finding an object deleted by an attempt reported as failed succeeds.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	var failed []*absos.ObjectError
+	err := run(ctx, b.policy, func() error {
+		err := absos.DeleteMany(ctx, b.Bucket, keys)
+		var batchErr *absos.BatchError
+		if !errors.As(err, &batchErr) {
+			return err
+		}
+
+		// Only the keys that failed temporarily are deleted again
+		var retried []*absos.ObjectError
+		keys = nil
+		for _, objErr := range batchErr.Errors {
+			if b.policy.retryable(objErr) {
+				retried = append(retried, objErr)
+				keys = append(keys, objErr.Key)
+			} else {
+				failed = append(failed, objErr)
+			}
+		}
+
+		if len(retried) == 0 {
+			return nil
+		}
+		return &absos.BatchError{Bucket: batchErr.Bucket, Errors: retried}
+	})
+
+	var batchErr *absos.BatchError
+	if errors.As(err, &batchErr) {
+		failed = append(failed, batchErr.Errors...)
+	} else if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return &absos.BatchError{Bucket: b.Name(), Errors: failed}
+	}
+	return nil
+}
+
+// Presign returns a URL for method on an object from the wrapped bucket.
+// Signing does not contact the provider, so it is not retried.
+func (b *Bucket) Presign(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	p, err := capability.As[absos.Presigner](b.Bucket, key)
+	if err != nil {
+		return "", err
+	}
+	return p.Presign(ctx, method, key, expires)
+}
+
+// InitiateMultipart starts a multipart upload without retrying, so that a
+// failure reported for an upload that was started does not start another.
+// The multipart operations fail with absos.ErrNotSupported if the wrapped
+// bucket does not support them.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, key)
+	if err != nil {
+		return absos.MultipartUpload{}, err
+	}
+	return mb.InitiateMultipart(ctx, key, opts...)
+}
+
+// UploadPart uploads a part, retrying temporary failures. Before each
+// retry, data is rewound to the offset it had when UploadPart was called.
+func (b *Bucket) UploadPart(ctx context.Context, upload absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return absos.Part{}, err
+	}
+	return rewinding(ctx, b, upload.Key, data, func() (absos.Part, error) {
+		return mb.UploadPart(ctx, upload, number, data)
+	})
+}
+
+// ListParts lists the parts of a multipart upload, retrying temporary
+// failures.
+func (b *Bucket) ListParts(ctx context.Context, upload absos.MultipartUpload) ([]absos.Part, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, b.policy, func() ([]absos.Part, error) {
+		return mb.ListParts(ctx, upload)
+	})
+}
+
+// CompleteMultipart assembles the parts of a multipart upload without
+// retrying: a retry of a completion that succeeded would fail with
+// absos.ErrUploadNotFound.
+func (b *Bucket) CompleteMultipart(ctx context.Context, upload absos.MultipartUpload, parts []absos.Part) error {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return err
+	}
+	return mb.CompleteMultipart(ctx, upload, parts)
+}
+
+// AbortMultipart aborts a multipart upload, retrying temporary failures. A
+// retry finding the upload missing succeeds.
+func (b *Bucket) AbortMultipart(ctx context.Context, upload absos.MultipartUpload) error {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err != nil {
+		return err
+	}
+	return runDelete(ctx, b.policy, absos.ErrUploadNotFound, func() error {
+		return mb.AbortMultipart(ctx, upload)
+	})
+}
+
+// ListMultipartUploads lists the multipart uploads in progress, retrying
+// temporary failures.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, b.policy, func() ([]absos.MultipartUpload, error) {
+		return mb.ListMultipartUploads(ctx, prefix)
+	})
+}
+
+// Versioning returns the versioning state of the bucket, retrying temporary
+// failures. The versioning operations fail with absos.ErrNotSupported if
+// the wrapped bucket does not support them.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return "", err
+	}
+	return do(ctx, b.policy, func() (absos.VersioningStatus, error) {
+		return vb.Versioning(ctx)
+	})
+}
+
+// SetVersioning enables or suspends versioning, retrying temporary failures.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return err
+	}
+	return run(ctx, b.policy, func() error {
+		return vb.SetVersioning(ctx, status)
+	})
+}
+
+// ListVersions lists a page of versions, retrying temporary failures.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, b.policy, func() (absos.VersionPage, error) {
+		return vb.ListVersions(ctx, prefix, token)
+	})
+}
+
+// GetVersion opens a version of an object, retrying temporary failures.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, b.policy, func() (io.ReadCloser, error) {
+		return vb.GetVersion(ctx, key, versionID)
+	})
+}
+
+// HeadVersion retrieves the metadata of a version of an object, retrying
+// temporary failures.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return do(ctx, b.policy, func() (absos.ObjectHeader, error) {
+		return vb.HeadVersion(ctx, key, versionID)
+	})
+}
+
+// DeleteVersion permanently removes a version or delete marker, retrying
+// temporary failures. A retry finding the version missing succeeds.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		return err
+	}
+	return runDelete(ctx, b.policy, absos.ErrVersionNotFound, func() error {
+		return vb.DeleteVersion(ctx, key, versionID)
+	})
+}
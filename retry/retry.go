@@ -0,0 +1,279 @@
+// Package retry wraps object stores and buckets to retry operations that
+// failed with a temporary error, such as absos.ErrThrottled, with jittered
+// exponential backoff:
+//
+//	store := retry.New(s3.New(client), retry.WithMaxAttempts(5))
+//	bucket = retry.NewBucket(bucket, retry.WithBackoff(50*time.Millisecond, time.Second))
+//
+// Only idempotent operations are retried, and deletes, which report a
+// missing object or bucket once repeated, succeed when a retry finds nothing
+// left to delete. Retries stop when the context is done or its deadline
+// would pass during the next delay, in which case the error of the last
+// attempt is returned.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// Default configuration values.
+const (
+	DefaultMaxAttempts = 4
+	DefaultBaseDelay   = 100 * time.Millisecond
+	DefaultMaxDelay    = 5 * time.Second
+)
+
+// Option configures the retries of a Store or Bucket.
+type Option func(*policy)
+
+// WithMaxAttempts sets the number of attempts of an operation, including
+// the first one.
+func WithMaxAttempts(n int) Option {
+	return func(p *policy) {
+		if n > 0 {
+			p.maxAttempts = n
+		}
+	}
+}
+
+// WithBackoff sets the delay before the first retry, which doubles with
+// every further retry up to maxDelay. Each delay is chosen at random between zero
+// and its bound, so that clients throttled together do not retry together.
+func WithBackoff(base, maxDelay time.Duration) Option {
+	return func(p *policy) {
+		if base > 0 && maxDelay >= base {
+			p.baseDelay, p.maxDelay = base, maxDelay
+		}
+	}
+}
+
+// WithRetryIf sets the function deciding which errors are retried,
+// absos.IsTemporary by default.
+func WithRetryIf(retryable func(err error) bool) Option {
+	return func(p *policy) {
+		if retryable != nil {
+			p.retryable = retryable
+		}
+	}
+}
+
+// policy decides when and how long to wait before retrying.
+type policy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	retryable   func(error) bool
+}
+
+func newPolicy(opts []Option) *policy {
+	p := &policy{
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultBaseDelay,
+		maxDelay:    DefaultMaxDelay,
+		retryable:   absos.IsTemporary,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// delay returns the time to wait before retry n, counting from zero.
+func (p *policy) delay(n int) time.Duration {
+	bound := p.baseDelay
+	for i := 0; i < n && bound < p.maxDelay; i++ {
+		bound *= 2
+	}
+	bound = min(bound, p.maxDelay)
+
+	return time.Duration(rand.Int63n(int64(bound) + 1))
+}
+
+// do calls op until it succeeds, fails with an error that is not retryable,
+// or runs out of attempts or time.
+func do[T any](ctx context.Context, p *policy, op func() (T, error)) (T, error) {
+	for n := 0; ; n++ {
+		v, err := op()
+		if err == nil || !p.retryable(err) || n+1 >= p.maxAttempts {
+			return v, err
+		}
+
+		delay := p.delay(n)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return v, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return v, err
+		}
+	}
+}
+
+// run is do for operations without a result.
+func run(ctx context.Context, p *policy, op func() error) error {
+	_, err := do(ctx, p, func() (struct{}, error) {
+		return struct{}{}, op()
+	})
+	return err
+}
+
+// runDelete is run for deletions: a retry failing with notFound succeeds, as
+// an attempt reported as failed may have deleted the target.
+func runDelete(ctx context.Context, p *policy, notFound error, op func() error) error {
+	retried := false
+	return run(ctx, p, func() error {
+		err := op()
+		if retried && errors.Is(err, notFound) {
+			return nil
+		}
+		retried = true
+		return err
+	})
+}
+
+// Store is an absos.ObjectStore retrying the operations of another store.
+// CreateBucket is not retried, as repeating a request that succeeded
+// although it was reported as failed would fail with
+// absos.ErrBucketAlreadyExists. The buckets returned by ListBuckets retry
+// their operations too.
+type Store struct {
+	store  absos.ObjectStore
+	policy *policy
+}
+
+var _ absos.ObjectStore = (*Store)(nil)
+
+// New returns a Store retrying the operations of store.
+func New(store absos.ObjectStore, opts ...Option) *Store {
+	return &Store{store: store, policy: newPolicy(opts)}
+}
+
+// CreateBucket creates a bucket without retrying.
+func (s *Store) CreateBucket(ctx context.Context, bucket string) error {
+	return s.store.CreateBucket(ctx, bucket)
+}
+
+// DeleteBucket deletes a bucket, retrying temporary failures. A retry
+// finding the bucket missing succeeds, as an attempt reported as failed may
+// have deleted it.
+func (s *Store) DeleteBucket(ctx context.Context, bucket string) error {
+	return runDelete(ctx, s.policy, absos.ErrBucketNotFound, func() error {
+		return s.store.DeleteBucket(ctx, bucket)
+	})
+}
+
+// ListBuckets lists the buckets, retrying temporary failures.
+func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := do(ctx, s.policy, func() ([]absos.Bucket, error) {
+		return s.store.ListBuckets(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, b := range buckets {
+		buckets[i] = &Bucket{Bucket: b, policy: s.policy}
+	}
+	return buckets, nil
+}
+
+// Bucket is an absos.Bucket retrying the operations of another bucket. It
+// implements the optional bucket interfaces too, retrying the idempotent
+// ones: range, conditional and version reads, copies, bulk deletes and the
+// parts of multipart uploads. Conditional writes and the initiation and
+// completion of multipart uploads are not retried, as repeating one that
+// succeeded would fail.
+//
+// Get only retries opening the object: errors while reading the returned
+// reader are not retried.
+//
+// Bucket implements every optional interface, so type assertions on it do
+// not tell what the wrapped bucket supports; assert on the Bucket field
+// instead. In particular, conditional reads are atomic only if the wrapped
+// bucket is an absos.ConditionalBucket.
+type Bucket struct {
+	absos.Bucket
+	policy *policy
+}
+
+// NewBucket returns a Bucket retrying the operations of b.
+func NewBucket(b absos.Bucket, opts ...Option) *Bucket {
+	return &Bucket{Bucket: b, policy: newPolicy(opts)}
+}
+
+// ObjectPage lists a page of objects, retrying temporary failures.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	return do(ctx, b.policy, func() (absos.Page, error) {
+		return b.Bucket.ObjectPage(ctx, prefix, delimiter, token)
+	})
+}
+
+// Head retrieves the metadata of an object, retrying temporary failures.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	return do(ctx, b.policy, func() (absos.ObjectHeader, error) {
+		return b.Bucket.Head(ctx, key)
+	})
+}
+
+// Get opens an object, retrying temporary failures.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return do(ctx, b.policy, func() (io.ReadCloser, error) {
+		return b.Bucket.Get(ctx, key)
+	})
+}
+
+// Put uploads an object, retrying temporary failures. Before each retry,
+// data is rewound to the offset it had when Put was called.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	_, err := rewinding(ctx, b, key, data, func() (struct{}, error) {
+		return struct{}{}, b.Bucket.Put(ctx, key, data, opts...)
+	})
+	return err
+}
+
+// rewinding is do for uploads of data, which is rewound to the offset it
+// had when rewinding was called before each retry.
+func rewinding[T any](ctx context.Context, b *Bucket, key string, data io.ReadSeeker, op func() (T, error)) (T, error) {
+	var zero T
+	start, err := data.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return zero, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	retried := false
+	return do(ctx, b.policy, func() (T, error) {
+		if retried {
+			if _, err := data.Seek(start, io.SeekStart); err != nil {
+				return zero, &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+			}
+		}
+		retried = true
+
+		return op()
+	})
+}
+
+// PutBatch uploads the objects of iter one by one with Put, so that each
+// of them is retried on its own.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	return absos.PutEach(ctx, b, iter)
+}
+
+// Delete removes an object, retrying temporary failures. A retry finding
+// the object missing succeeds, as an attempt reported as failed may have
+// deleted it.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	return runDelete(ctx, b.policy, absos.ErrObjectNotFound, func() error {
+		return b.Bucket.Delete(ctx, key)
+	})
+}
@@ -0,0 +1,341 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// flakyBucket fails the first failures calls of each method with err.
+// Put consumes its data before failing, as an interrupted upload does.
+type flakyBucket struct {
+	absos.Bucket
+	failures int64
+	err      error
+	calls    atomic.Int64
+}
+
+func (b *flakyBucket) fail(key string) error {
+	if b.calls.Add(1) <= b.failures {
+		return &absos.ObjectError{Bucket: b.Name(), Key: key, Err: b.err}
+	}
+	return nil
+}
+
+func (b *flakyBucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	if err := b.fail(key); err != nil {
+		return nil, err
+	}
+	return b.Bucket.Head(ctx, key)
+}
+
+func (b *flakyBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := b.fail(key); err != nil {
+		return nil, err
+	}
+	return b.Bucket.Get(ctx, key)
+}
+
+func (b *flakyBucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	if err := b.fail(key); err != nil {
+		_, _ = io.Copy(io.Discard, data)
+		return err
+	}
+	return b.Bucket.Put(ctx, key, data, opts...)
+}
+
+// Delete deletes the object before failing, as a request whose response is
+// lost does.
+func (b *flakyBucket) Delete(ctx context.Context, key string) error {
+	if err := b.Bucket.Delete(ctx, key); err != nil {
+		return err
+	}
+	return b.fail(key)
+}
+
+// flakyStore deletes buckets before failing the first failures calls of
+// DeleteBucket with err.
+type flakyStore struct {
+	absos.ObjectStore
+	failures int64
+	err      error
+	calls    atomic.Int64
+}
+
+func (s *flakyStore) DeleteBucket(ctx context.Context, bucket string) error {
+	if err := s.ObjectStore.DeleteBucket(ctx, bucket); err != nil {
+		return err
+	}
+	if s.calls.Add(1) <= s.failures {
+		return &absos.BucketError{Bucket: bucket, Err: s.err}
+	}
+	return nil
+}
+
+func newFlakyBucket(t *testing.T, failures int64, err error) *flakyBucket {
+	t.Helper()
+
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	return &flakyBucket{Bucket: buckets[0], failures: failures, err: err}
+}
+
+func fast(opts ...Option) []Option {
+	return append([]Option{WithBackoff(time.Millisecond, 5*time.Millisecond)}, opts...)
+}
+
+func TestRetryTemporary(t *testing.T) {
+	ctx := context.Background()
+	flaky := newFlakyBucket(t, 2, absos.ErrThrottled)
+	b := NewBucket(flaky, fast()...)
+
+	// The retried Put rewinds the data consumed by the failed attempts
+	data := strings.NewReader("prefix:data")
+	if _, err := data.Seek(7, io.SeekStart); err != nil {
+		t.Fatalf("failed to seek: %v", err)
+	}
+	if err := b.Put(ctx, "key", data); err != nil {
+		t.Fatalf("expected Put to succeed after retries, got %v", err)
+	}
+	if calls := flaky.calls.Load(); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+
+	r, err := b.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	defer r.Close()
+
+	if got, _ := io.ReadAll(r); string(got) != "data" {
+		t.Errorf("expected %q, got %q", "data", got)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	flaky := newFlakyBucket(t, 1, absos.ErrPermissionDenied)
+	b := NewBucket(flaky, fast()...)
+
+	if _, err := b.Head(context.Background(), "key"); !errors.Is(err, absos.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+	if calls := flaky.calls.Load(); calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	flaky := newFlakyBucket(t, 10, absos.ErrUnavailable)
+	b := NewBucket(flaky, fast(WithMaxAttempts(3))...)
+
+	if _, err := b.Head(context.Background(), "key"); !errors.Is(err, absos.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if calls := flaky.calls.Load(); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryIf(t *testing.T) {
+	ctx := context.Background()
+	flaky := newFlakyBucket(t, 1, absos.ErrObjectNotFound)
+	if err := flaky.Bucket.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	// Eventually consistent stores may report new objects as missing
+	b := NewBucket(flaky, fast(WithRetryIf(func(err error) bool {
+		return errors.Is(err, absos.ErrObjectNotFound)
+	}))...)
+
+	if _, err := b.Head(ctx, "key"); err != nil {
+		t.Errorf("expected Head to succeed after a retry, got %v", err)
+	}
+	if calls := flaky.calls.Load(); calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	flaky := newFlakyBucket(t, 10, absos.ErrThrottled)
+	b := NewBucket(flaky, WithBackoff(time.Hour, time.Hour), WithMaxAttempts(10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := b.Head(ctx, "key"); !errors.Is(err, absos.ErrThrottled) {
+		t.Errorf("expected the last ErrThrottled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected retries to stop before the deadline, took %v", elapsed)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	p := newPolicy([]Option{WithBackoff(10*time.Millisecond, 50*time.Millisecond)})
+
+	bounds := []time.Duration{10, 20, 40, 50, 50}
+	for n, bound := range bounds {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(n); d < 0 || d > bound*time.Millisecond {
+				t.Fatalf("retry %d: delay %v outside [0, %v]", n, d, bound*time.Millisecond)
+			}
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := New(memory.NewStore(), fast()...)
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil || len(buckets) != 1 {
+		t.Fatalf("expected 1 bucket, got %d and %v", len(buckets), err)
+	}
+	if _, ok := buckets[0].(*Bucket); !ok {
+		t.Errorf("expected a retrying *Bucket, got %T", buckets[0])
+	}
+
+	if err := store.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Errorf("failed to delete bucket: %v", err)
+	}
+}
+
+func TestRetryDelete(t *testing.T) {
+	ctx := context.Background()
+	fb := newFlakyBucket(t, 1, absos.ErrUnavailable)
+	b := NewBucket(fb, fast()...)
+
+	if err := fb.Bucket.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	// The first attempt deletes the object, so the retry finds it missing
+	if err := b.Delete(ctx, "key"); err != nil {
+		t.Errorf("expected the delete to succeed, got %v", err)
+	}
+	if fb.calls.Load() != 1 {
+		t.Errorf("expected the delete to be retried once, got %d failures", fb.calls.Load())
+	}
+
+	// A first attempt finding the object missing is not retried
+	if err := b.Delete(ctx, "key"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	fs := &flakyStore{ObjectStore: memory.NewStore(), failures: 1, err: absos.ErrUnavailable}
+	store := New(fs, fast()...)
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	if err := store.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Errorf("expected the bucket deletion to succeed, got %v", err)
+	}
+	if err := store.DeleteBucket(ctx, "test-bucket"); !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected ErrBucketNotFound, got %v", err)
+	}
+}
+
+func TestRetryDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	fb := newFlakyBucket(t, 2, absos.ErrThrottled)
+	b := NewBucket(fb, fast()...)
+
+	keys := []string{"a", "b", "c"}
+	for _, key := range keys {
+		if err := fb.Bucket.Put(ctx, key, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+	}
+
+	// The first attempt deletes every object but reports a and b as failed;
+	// their retry finds them missing
+	if err := absos.DeleteMany(ctx, b, keys); err != nil {
+		t.Errorf("expected the deletion to succeed, got %v", err)
+	}
+	for _, key := range keys {
+		if _, err := fb.Bucket.Head(ctx, key); !errors.Is(err, absos.ErrObjectNotFound) {
+			t.Errorf("expected %q to be deleted, got %v", key, err)
+		}
+	}
+
+	// Permanent failures are reported without retrying
+	fb = newFlakyBucket(t, 1, absos.ErrPermissionDenied)
+	b = NewBucket(fb, fast()...)
+	if err := fb.Bucket.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	err := b.DeleteMany(ctx, []string{"key", "missing"})
+	var batchErr *absos.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "key" {
+		t.Fatalf("expected a batch error for key, got %v", err)
+	}
+	if !errors.Is(err, absos.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+	if fb.calls.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", fb.calls.Load())
+	}
+}
+
+func TestRetryCapabilities(t *testing.T) {
+	ctx := context.Background()
+	flaky := newFlakyBucket(t, 2, absos.ErrThrottled)
+	b := NewBucket(flaky, fast()...)
+
+	// Streams to a bucket without native uploads go through the retried Put
+	if err := absos.Upload(ctx, b, "key", strings.NewReader("streamed")); err != nil {
+		t.Fatalf("expected Upload to succeed after retries, got %v", err)
+	}
+	if calls := flaky.calls.Load(); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+
+	flaky.calls.Store(0)
+	r, err := absos.GetRange(ctx, b, "key", 2, 3)
+	if err != nil {
+		t.Fatalf("expected GetRange to succeed after retries, got %v", err)
+	}
+	defer r.Close()
+
+	if got, _ := io.ReadAll(r); string(got) != "rea" {
+		t.Errorf("expected %q, got %q", "rea", got)
+	}
+
+	// Conditional writes are forwarded to the memory bucket
+	cb := NewBucket(flaky.Bucket, fast()...)
+	cond := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, cb, "new", strings.NewReader("data"), cond); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := absos.PutIf(ctx, cb, "new", strings.NewReader("data"), cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return New(memory.NewStore(), fast()...)
+	})
+}
@@ -0,0 +1,447 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Bucket is an S3 implementation of absos.Bucket.
+type Bucket struct {
+	client  s3iface.S3API
+	name    string
+	created time.Time
+	owner   absos.Owner
+}
+
+// Name returns the bucket name.
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+// CreationTime returns when the bucket was created.
+// It is the zero time for buckets obtained with Store.Bucket.
+func (b *Bucket) CreationTime() time.Time {
+	return b.created
+}
+
+// Owner returns the owner of the bucket, or nil if it is unknown.
+func (b *Bucket) Owner() absos.Owner {
+	return b.owner
+}
+
+// ObjectPage lists objects with ListObjectsV2.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.name),
+		Prefix: aws.String(prefix),
+	}
+	if delimiter != "" {
+		input.Delimiter = aws.String(delimiter)
+	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+
+	out, err := b.client.ListObjectsV2WithContext(ctx, input)
+	if err != nil {
+		return nil, bucketError(ctx, b.name, err)
+	}
+
+	p := &page{
+		objects:  make([]absos.Object, 0, len(out.Contents)),
+		prefixes: make([]string, 0, len(out.CommonPrefixes)),
+		next:     aws.StringValue(out.NextContinuationToken),
+		last:     !aws.BoolValue(out.IsTruncated),
+	}
+
+	for _, o := range out.Contents {
+		p.objects = append(p.objects, &object{
+			bucket:       b,
+			key:          aws.StringValue(o.Key),
+			size:         aws.Int64Value(o.Size),
+			modTime:      aws.TimeValue(o.LastModified),
+			etag:         parseETag(aws.StringValue(o.ETag)),
+			storageClass: aws.StringValue(o.StorageClass),
+		})
+	}
+
+	for _, cp := range out.CommonPrefixes {
+		p.prefixes = append(p.prefixes, aws.StringValue(cp.Prefix))
+	}
+
+	return p, nil
+}
+
+// Head retrieves object metadata with HeadObject.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	return b.HeadIf(ctx, key, absos.Conditions{})
+}
+
+// HeadIf retrieves object metadata with a conditional HeadObject.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	}
+	input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince = readConditions(cond)
+	input.ChecksumMode = aws.String(s3.ChecksumModeEnabled)
+
+	out, err := b.client.HeadObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, objectError(ctx, b.name, key, err)
+	}
+
+	h := &header{
+		bucket:       b.name,
+		key:          key,
+		size:         aws.Int64Value(out.ContentLength),
+		modTime:      aws.TimeValue(out.LastModified),
+		etag:         parseETag(aws.StringValue(out.ETag)),
+		mimeType:     aws.StringValue(out.ContentType),
+		encoding:     aws.StringValue(out.ContentEncoding),
+		cacheControl: aws.StringValue(out.CacheControl),
+		disposition:  aws.StringValue(out.ContentDisposition),
+		metadata:     make(map[string]string, len(out.Metadata)),
+		version:      aws.StringValue(out.VersionId),
+		redirect:     aws.StringValue(out.WebsiteRedirectLocation),
+		storageClass: aws.StringValue(out.StorageClass),
+		checksums:    parseChecksums(out.ChecksumCRC32C, out.ChecksumSHA1, out.ChecksumSHA256),
+	}
+
+	// S3 stores metadata keys in lower case; the SDK canonicalizes them.
+	for k, v := range out.Metadata {
+		h.metadata[strings.ToLower(k)] = aws.StringValue(v)
+	}
+
+	// HeadObject omits the storage class for STANDARD objects.
+	if h.storageClass == "" {
+		h.storageClass = s3.StorageClassStandard
+	}
+
+	if out.ServerSideEncryption != nil || out.SSECustomerAlgorithm != nil {
+		h.sse = &absos.SSE{
+			Algorithms:           aws.StringValue(out.SSECustomerAlgorithm),
+			KeyMD5:               aws.StringValue(out.SSECustomerKeyMD5),
+			KMSKeyId:             aws.StringValue(out.SSEKMSKeyId),
+			ServerSideEncryption: aws.StringValue(out.ServerSideEncryption),
+		}
+	}
+
+	return h, nil
+}
+
+// PutBatch uploads each object produced by the iterator with PutObject.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	return absos.PutEach(ctx, b, iter)
+}
+
+// Put uploads an object with PutObject.
+// Of the SSE options, only ServerSideEncryption and KMSKeyId are sent;
+// customer-provided keys are not supported.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	return b.PutIf(ctx, key, data, absos.Conditions{}, opts...)
+}
+
+// PutIf uploads an object with a conditional PutObject. S3 itself only
+// evaluates IfMatch and IfNotExists on writes and rejects other conditions
+// with absos.ErrNotSupported.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+		Body:   data,
+	}
+	putOptions(input, absos.NewPutOptions(opts...))
+
+	_, err := b.client.PutObjectWithContext(ctx, input, writeConditions(cond))
+	if err != nil {
+		return objectError(ctx, b.name, key, err)
+	}
+
+	return nil
+}
+
+// PutStream uploads an object from a reader of unknown length with the
+// s3manager uploader, which switches to a multipart upload for streams
+// larger than one part and aborts it if reading data fails.
+//
+// S3 ignores the checksums of multipart uploads, so expected checksums are
+// verified while data is read, and a mismatch aborts the upload.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	options := absos.NewPutOptions(opts...)
+	params := &s3.PutObjectInput{}
+	putOptions(params, options)
+
+	input := &s3manager.UploadInput{}
+	awsutil.Copy(input, params)
+	input.Bucket = aws.String(b.name)
+	input.Key = aws.String(key)
+	input.Body = absos.VerifyReader(io.NopCloser(data), options.Checksums)
+
+	_, err := s3manager.NewUploaderWithClient(b.client).UploadWithContext(ctx, input)
+	if err != nil {
+		return objectError(ctx, b.name, key, err)
+	}
+
+	return nil
+}
+
+// putOptions sets the fields of input corresponding to the non-zero options.
+func putOptions(input *s3.PutObjectInput, opts absos.PutOptions) {
+	optional := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return aws.String(s)
+	}
+
+	input.ContentType = optional(opts.ContentType)
+	input.ContentEncoding = optional(opts.ContentEncoding)
+	input.CacheControl = optional(opts.CacheControl)
+	input.ContentDisposition = optional(opts.ContentDisposition)
+	input.StorageClass = optional(opts.StorageClass)
+
+	if len(opts.Metadata) > 0 {
+		input.Metadata = aws.StringMap(opts.Metadata)
+	}
+
+	if opts.SSE != nil {
+		input.ServerSideEncryption = optional(opts.SSE.ServerSideEncryption)
+		input.SSEKMSKeyId = optional(opts.SSE.KMSKeyId)
+	}
+
+	input.ChecksumCRC32C = formatChecksum(opts.Checksums[absos.ChecksumCRC32C])
+	input.ChecksumSHA1 = formatChecksum(opts.Checksums[absos.ChecksumSHA1])
+	input.ChecksumSHA256 = formatChecksum(opts.Checksums[absos.ChecksumSHA256])
+}
+
+// Copy copies an object from another S3 bucket with CopyObject, using the
+// client of this bucket. CopyObject is limited to objects of up to 5 GB.
+// It returns absos.ErrNotSupported if src is not an S3 bucket.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	from, ok := src.(*Bucket)
+	if !ok {
+		return &absos.ObjectError{Bucket: b.name, Key: dstKey, Err: absos.ErrNotSupported}
+	}
+
+	params := &s3.PutObjectInput{}
+	input := &s3.CopyObjectInput{
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+	}
+
+	if options := absos.NewCopyOptions(opts...); options.Directive == absos.MetadataReplace {
+		putOptions(params, options.Replace)
+		awsutil.Copy(input, params)
+		input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+	}
+
+	input.Bucket = aws.String(b.name)
+	input.Key = aws.String(dstKey)
+	input.CopySource = aws.String(url.PathEscape(from.name) + "/" + escapeKey(srcKey))
+
+	_, err := b.client.CopyObjectWithContext(ctx, input)
+	if err != nil {
+		// S3 reports a missing source as NoSuchKey on the destination request
+		if errors.Is(translate(ctx, err), absos.ErrObjectNotFound) {
+			return objectError(ctx, from.name, srcKey, err)
+		}
+		return objectError(ctx, b.name, dstKey, err)
+	}
+
+	return nil
+}
+
+// readConditions returns the conditional fields of HeadObject and GetObject
+// requests for cond.
+func readConditions(cond absos.Conditions) (ifMatch, ifNoneMatch *string, ifModifiedSince, ifUnmodifiedSince *time.Time) {
+	if cond.IfMatch != nil {
+		ifMatch = aws.String(formatETag(cond.IfMatch))
+	}
+	if cond.IfNotExists {
+		ifNoneMatch = aws.String("*")
+	} else if cond.IfNoneMatch != nil {
+		ifNoneMatch = aws.String(formatETag(cond.IfNoneMatch))
+	}
+	if !cond.IfModifiedSince.IsZero() {
+		ifModifiedSince = aws.Time(cond.IfModifiedSince)
+	}
+	if !cond.IfUnmodifiedSince.IsZero() {
+		ifUnmodifiedSince = aws.Time(cond.IfUnmodifiedSince)
+	}
+	return ifMatch, ifNoneMatch, ifModifiedSince, ifUnmodifiedSince
+}
+
+// writeConditions returns a request option sending cond as HTTP headers.
+// The SDK has no fields for the conditions of writes.
+func writeConditions(cond absos.Conditions) request.Option {
+	headers := make(map[string]string)
+
+	ifMatch, ifNoneMatch, _, ifUnmodifiedSince := readConditions(cond)
+	if ifMatch != nil {
+		headers["If-Match"] = *ifMatch
+	}
+	if ifNoneMatch != nil {
+		headers["If-None-Match"] = *ifNoneMatch
+	}
+	if ifUnmodifiedSince != nil {
+		headers["If-Unmodified-Since"] = ifUnmodifiedSince.UTC().Format(http.TimeFormat)
+	}
+
+	return request.WithSetRequestHeaders(headers)
+}
+
+// escapeKey escapes each segment of key for use in a URL path.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Get retrieves an object with GetObject.
+// The caller must close the returned reader.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.GetIf(ctx, key, absos.Conditions{})
+}
+
+// GetIf retrieves an object with a conditional GetObject.
+// The caller must close the returned reader.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	}
+	input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince = readConditions(cond)
+	input.ChecksumMode = aws.String(s3.ChecksumModeEnabled)
+
+	out, err := b.client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, objectError(ctx, b.name, key, err)
+	}
+
+	checksums := parseChecksums(out.ChecksumCRC32C, out.ChecksumSHA1, out.ChecksumSHA256)
+	return absos.VerifyReader(out.Body, checksums), nil
+}
+
+// GetRange retrieves part of an object with a ranged GetObject.
+// The caller must close the returned reader.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	var rng string
+	switch {
+	case offset < 0:
+		rng = fmt.Sprintf("bytes=%d", offset)
+	case length == 0:
+		return nil, &absos.ObjectError{Bucket: b.name, Key: key, Err: absos.ErrInvalidRange}
+	case length < 0:
+		rng = fmt.Sprintf("bytes=%d-", offset)
+	default:
+		rng = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	out, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+		Range:  aws.String(rng),
+	})
+	if err != nil {
+		return nil, objectError(ctx, b.name, key, err)
+	}
+
+	return out.Body, nil
+}
+
+// Delete removes an object with DeleteObject. The object is checked with
+// HeadObject first, at the cost of a second request, in order to return
+// absos.ErrObjectNotFound; see the package documentation.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	return b.DeleteIf(ctx, key, absos.Conditions{})
+}
+
+// DeleteIf removes an object with a conditional DeleteObject, checking
+// that it exists with HeadObject first like Delete. S3 itself only
+// evaluates IfMatch on deletes and rejects other conditions with
+// absos.ErrNotSupported.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	if _, err := b.Head(ctx, key); err != nil {
+		return err
+	}
+
+	_, err := b.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	}, writeConditions(cond))
+	if err != nil {
+		return objectError(ctx, b.name, key, err)
+	}
+
+	return nil
+}
+
+// deleteLimit is the maximum number of keys in a DeleteObjects request.
+const deleteLimit = 1000
+
+// DeleteLimit returns the maximum number of keys accepted by DeleteMany.
+func (b *Bucket) DeleteLimit() int {
+	return deleteLimit
+}
+
+// DeleteMany removes the objects with a single quiet DeleteObjects request,
+// reporting the keys S3 could not delete in a *absos.BatchError.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	objects := make([]*s3.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+	}
+
+	out, err := b.client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(b.name),
+		Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		return bucketError(ctx, b.name, err)
+	}
+
+	if len(out.Errors) == 0 {
+		return nil
+	}
+
+	failed := make([]*absos.ObjectError, len(out.Errors))
+	for i, e := range out.Errors {
+		err := awserr.New(aws.StringValue(e.Code), aws.StringValue(e.Message), nil)
+		failed[i] = &absos.ObjectError{Bucket: b.name, Key: aws.StringValue(e.Key), Err: translate(ctx, err)}
+	}
+
+	return &absos.BatchError{Bucket: b.name, Errors: failed}
+}
+
+type page struct {
+	objects  []absos.Object
+	prefixes []string
+	next     string
+	last     bool
+}
+
+func (p *page) Objects() []absos.Object { return p.objects }
+func (p *page) Prefixes() []string      { return p.prefixes }
+func (p *page) NextPage() string        { return p.next }
+func (p *page) Last() bool              { return p.last }
@@ -0,0 +1,86 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/absfs/absos"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// sentinels maps S3 error codes to the corresponding absos errors.
+// "NotFound", "Forbidden", "PreconditionFailed" and "NotModified" are also
+// reported for requests whose response carries no error body.
+var sentinels = map[string]error{
+	s3.ErrCodeNoSuchBucket:            absos.ErrBucketNotFound,
+	s3.ErrCodeNoSuchKey:               absos.ErrObjectNotFound,
+	"NotFound":                        absos.ErrObjectNotFound,
+	s3.ErrCodeBucketAlreadyExists:     absos.ErrBucketAlreadyExists,
+	s3.ErrCodeBucketAlreadyOwnedByYou: absos.ErrBucketAlreadyExists,
+	"BucketNotEmpty":                  absos.ErrBucketNotEmpty,
+	"InvalidBucketName":               absos.ErrInvalidBucketName,
+	"KeyTooLongError":                 absos.ErrInvalidKey,
+	"InvalidRange":                    absos.ErrInvalidRange,
+	s3.ErrCodeNoSuchUpload:            absos.ErrUploadNotFound,
+	"InvalidPart":                     absos.ErrInvalidPart,
+	"InvalidPartOrder":                absos.ErrInvalidPart,
+	"EntityTooSmall":                  absos.ErrInvalidPart,
+	"PreconditionFailed":              absos.ErrPreconditionFailed,
+	"ConditionalRequestConflict":      absos.ErrPreconditionFailed,
+	"NotModified":                     absos.ErrNotModified,
+	"NotImplemented":                  absos.ErrNotSupported,
+	"BadDigest":                       absos.ErrChecksumMismatch,
+	"SlowDown":                        absos.ErrThrottled,
+	"Throttling":                      absos.ErrThrottled,
+	"ThrottlingException":             absos.ErrThrottled,
+	"RequestLimitExceeded":            absos.ErrThrottled,
+	"TooManyRequests":                 absos.ErrThrottled,
+	"ServiceUnavailable":              absos.ErrUnavailable,
+	"InternalError":                   absos.ErrUnavailable,
+	"RequestTimeout":                  absos.ErrUnavailable,
+	"AccessDenied":                    absos.ErrPermissionDenied,
+	"AllAccessDisabled":               absos.ErrPermissionDenied,
+	"Forbidden":                       absos.ErrPermissionDenied,
+}
+
+// translate maps err to the matching absos sentinel error, keeping err in
+// the chain so the original AWS error remains available through errors.As.
+func translate(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+
+	// The SDK nests the errors of failed parts in upload errors, but its
+	// errors do not implement Unwrap.
+	var aerr awserr.Error
+	cause := err
+	for ; errors.As(cause, &aerr); cause = aerr.OrigErr() {
+		if sentinel, ok := sentinels[aerr.Code()]; ok {
+			return fmt.Errorf("%w: %w", sentinel, err)
+		}
+	}
+
+	// Checksum mismatches of streamed uploads end the chain of read errors
+	if errors.Is(cause, absos.ErrChecksumMismatch) {
+		return fmt.Errorf("%w: %w", cause, err)
+	}
+
+	return err
+}
+
+// bucketError translates err and wraps it in an *absos.BucketError.
+func bucketError(ctx context.Context, bucket string, err error) error {
+	return &absos.BucketError{Bucket: bucket, Err: translate(ctx, err)}
+}
+
+// objectError translates err and wraps it in an *absos.ObjectError.
+// A missing bucket is reported as a *absos.BucketError instead.
+func objectError(ctx context.Context, bucket, key string, err error) error {
+	err = translate(ctx, err)
+	if errors.Is(err, absos.ErrBucketNotFound) {
+		return &absos.BucketError{Bucket: bucket, Err: err}
+	}
+	return &absos.ObjectError{Bucket: bucket, Key: key, Err: err}
+}
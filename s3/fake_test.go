@@ -0,0 +1,234 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeS3 is a minimal in-process stand-in for the S3 REST API, supporting
+// just enough of the protocol to exercise the backend over real HTTP.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]*fakeObject
+	created map[string]time.Time
+}
+
+type fakeObject struct {
+	data    []byte
+	header  http.Header
+	modTime time.Time
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		buckets: make(map[string]map[string]*fakeObject),
+		created: make(map[string]time.Time),
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+
+	if bucket == "forbidden" {
+		fakeError(w, r, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	switch {
+	case bucket == "":
+		f.listBuckets(w)
+	case key == "":
+		f.serveBucket(w, r, bucket)
+	default:
+		f.serveObject(w, r, bucket, key)
+	}
+}
+
+func (f *fakeS3) listBuckets(w http.ResponseWriter) {
+	type entry struct {
+		Name         string
+		CreationDate time.Time
+	}
+	var result struct {
+		XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+		Owner   struct{ ID, DisplayName string }
+		Buckets []entry `xml:"Buckets>Bucket"`
+	}
+	result.Owner.ID = "owner-id"
+	result.Owner.DisplayName = "owner"
+	for name := range f.buckets {
+		result.Buckets = append(result.Buckets, entry{Name: name, CreationDate: f.created[name]})
+	}
+	fakeXML(w, result)
+}
+
+func (f *fakeS3) serveBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	objects, exists := f.buckets[bucket]
+
+	switch r.Method {
+	case http.MethodPut:
+		if exists {
+			fakeError(w, r, http.StatusConflict, "BucketAlreadyOwnedByYou")
+			return
+		}
+		f.buckets[bucket] = make(map[string]*fakeObject)
+		f.created[bucket] = time.Now().UTC().Truncate(time.Second)
+	case http.MethodDelete:
+		switch {
+		case !exists:
+			fakeError(w, r, http.StatusNotFound, "NoSuchBucket")
+		case len(objects) > 0:
+			fakeError(w, r, http.StatusConflict, "BucketNotEmpty")
+		default:
+			delete(f.buckets, bucket)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case http.MethodGet:
+		if !exists {
+			fakeError(w, r, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		f.listObjects(w, r, objects)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) listObjects(w http.ResponseWriter, r *http.Request, objects map[string]*fakeObject) {
+	q := r.URL.Query()
+	prefix, delimiter, token := q.Get("prefix"), q.Get("delimiter"), q.Get("continuation-token")
+	maxKeys := 2
+	if n, err := strconv.Atoi(q.Get("max-keys")); err == nil {
+		maxKeys = n
+	}
+
+	keys := make([]string, 0, len(objects))
+	for k := range objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		Size         int64
+		ETag         string
+		LastModified time.Time
+		StorageClass string
+	}
+	var result struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		Contents              []content `xml:"Contents"`
+		CommonPrefixes        []struct{ Prefix string }
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}
+
+	seen := make(map[string]bool)
+	count := 0
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) || k <= token {
+			continue
+		}
+		entry := k
+		if i := strings.Index(k[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			entry = k[:len(prefix)+i+len(delimiter)]
+			if seen[entry] || entry <= token {
+				continue
+			}
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		count++
+		result.NextContinuationToken = entry
+		if entry != k {
+			seen[entry] = true
+			result.CommonPrefixes = append(result.CommonPrefixes, struct{ Prefix string }{entry})
+			continue
+		}
+		obj := objects[k]
+		result.Contents = append(result.Contents, content{
+			Key:          k,
+			Size:         int64(len(obj.data)),
+			ETag:         obj.header.Get("ETag"),
+			LastModified: obj.modTime,
+			StorageClass: "STANDARD",
+		})
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	fakeXML(w, result)
+}
+
+func (f *fakeS3) serveObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	objects, exists := f.buckets[bucket]
+	if !exists {
+		fakeError(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	obj := objects[key]
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(data)
+		h := make(http.Header)
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") || strings.HasPrefix(k, "X-Amz-Server-Side-Encryption") ||
+				k == "Content-Type" || k == "X-Amz-Storage-Class" || k == "X-Amz-Website-Redirect-Location" {
+				h[k] = v
+			}
+		}
+		h.Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		objects[key] = &fakeObject{data: data, header: h, modTime: time.Now().UTC().Truncate(time.Second)}
+		w.Header().Set("ETag", h.Get("ETag"))
+	case http.MethodGet, http.MethodHead:
+		if obj == nil {
+			fakeError(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func fakeError(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	}
+}
+
+func fakeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
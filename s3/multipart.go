@@ -0,0 +1,165 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// InitiateMultipart starts an upload with CreateMultipartUpload.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	params := &s3.PutObjectInput{}
+	putOptions(params, absos.NewPutOptions(opts...))
+
+	input := &s3.CreateMultipartUploadInput{}
+	awsutil.Copy(input, params)
+	input.Bucket = aws.String(b.name)
+	input.Key = aws.String(key)
+
+	out, err := b.client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return absos.MultipartUpload{}, objectError(ctx, b.name, key, err)
+	}
+
+	// The response carries no initiation time, so the local time is used
+	return absos.MultipartUpload{
+		Bucket:    b.name,
+		Key:       key,
+		ID:        aws.StringValue(out.UploadId),
+		Initiated: time.Now(),
+	}, nil
+}
+
+// UploadPart uploads a part with UploadPart.
+func (b *Bucket) UploadPart(ctx context.Context, u absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	size, err := data.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = data.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return absos.Part{}, &absos.ObjectError{Bucket: b.name, Key: u.Key, Err: err}
+	}
+
+	out, err := b.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(b.name),
+		Key:        aws.String(u.Key),
+		UploadId:   aws.String(u.ID),
+		PartNumber: aws.Int64(int64(number)),
+		Body:       data,
+	})
+	if err != nil {
+		return absos.Part{}, objectError(ctx, b.name, u.Key, err)
+	}
+
+	return absos.Part{
+		Number: number,
+		Size:   size,
+		ETag:   parseETag(aws.StringValue(out.ETag)),
+	}, nil
+}
+
+// ListParts lists the uploaded parts with ListParts, following pagination.
+func (b *Bucket) ListParts(ctx context.Context, u absos.MultipartUpload) ([]absos.Part, error) {
+	var parts []absos.Part
+
+	input := &s3.ListPartsInput{
+		Bucket:   aws.String(b.name),
+		Key:      aws.String(u.Key),
+		UploadId: aws.String(u.ID),
+	}
+
+	err := b.client.ListPartsPagesWithContext(ctx, input, func(out *s3.ListPartsOutput, last bool) bool {
+		for _, p := range out.Parts {
+			parts = append(parts, absos.Part{
+				Number:  int(aws.Int64Value(p.PartNumber)),
+				Size:    aws.Int64Value(p.Size),
+				ETag:    parseETag(aws.StringValue(p.ETag)),
+				ModTime: aws.TimeValue(p.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, objectError(ctx, b.name, u.Key, err)
+	}
+
+	return parts, nil
+}
+
+// CompleteMultipart completes the upload with CompleteMultipartUpload.
+func (b *Bucket) CompleteMultipart(ctx context.Context, u absos.MultipartUpload, parts []absos.Part) error {
+	completed := make([]*s3.CompletedPart, len(parts))
+	for i, p := range parts {
+		completed[i] = &s3.CompletedPart{
+			PartNumber: aws.Int64(int64(p.Number)),
+			ETag:       aws.String(formatETag(p.ETag)),
+		}
+	}
+
+	_, err := b.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(b.name),
+		Key:             aws.String(u.Key),
+		UploadId:        aws.String(u.ID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return objectError(ctx, b.name, u.Key, err)
+	}
+
+	return nil
+}
+
+// AbortMultipart aborts the upload with AbortMultipartUpload.
+func (b *Bucket) AbortMultipart(ctx context.Context, u absos.MultipartUpload) error {
+	_, err := b.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(b.name),
+		Key:      aws.String(u.Key),
+		UploadId: aws.String(u.ID),
+	})
+	if err != nil {
+		return objectError(ctx, b.name, u.Key, err)
+	}
+
+	return nil
+}
+
+// ListMultipartUploads lists in-progress uploads with ListMultipartUploads,
+// following pagination.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	var uploads []absos.MultipartUpload
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(b.name),
+		Prefix: aws.String(prefix),
+	}
+
+	err := b.client.ListMultipartUploadsPagesWithContext(ctx, input, func(out *s3.ListMultipartUploadsOutput, last bool) bool {
+		for _, u := range out.Uploads {
+			uploads = append(uploads, absos.MultipartUpload{
+				Bucket:    b.name,
+				Key:       aws.StringValue(u.Key),
+				ID:        aws.StringValue(u.UploadId),
+				Initiated: aws.TimeValue(u.Initiated),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, bucketError(ctx, b.name, err)
+	}
+
+	sort.SliceStable(uploads, func(i, j int) bool {
+		if uploads[i].Key != uploads[j].Key {
+			return uploads[i].Key < uploads[j].Key
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+
+	return uploads, nil
+}
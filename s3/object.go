@@ -0,0 +1,134 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// object is an entry returned by Bucket.ObjectPage.
+type object struct {
+	bucket       *Bucket
+	key          string
+	size         int64
+	modTime      time.Time
+	etag         []byte
+	storageClass string
+}
+
+func (o *object) Bucket() string        { return o.bucket.name }
+func (o *object) Key() string           { return o.key }
+func (o *object) Size() int64           { return o.size }
+func (o *object) ModTime() time.Time    { return o.modTime }
+func (o *object) AccessTime() time.Time { return o.modTime }
+func (o *object) ETag() []byte          { return o.etag }
+func (o *object) StorageClass() string  { return o.storageClass }
+
+func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
+	return o.bucket.Head(ctx, o.key)
+}
+
+func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
+	return o.bucket.Get(ctx, o.key)
+}
+
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return o.bucket.GetRange(ctx, o.key, offset, length)
+}
+
+// header is the result of Bucket.Head.
+type header struct {
+	bucket       string
+	key          string
+	size         int64
+	modTime      time.Time
+	etag         []byte
+	mimeType     string
+	encoding     string
+	cacheControl string
+	disposition  string
+	metadata     map[string]string
+	version      string
+	redirect     string
+	sse          *absos.SSE
+	storageClass string
+	checksums    absos.Checksums
+}
+
+func (h *header) Bucket() string                   { return h.bucket }
+func (h *header) Key() string                      { return h.key }
+func (h *header) Size() int64                      { return h.size }
+func (h *header) ModTime() time.Time               { return h.modTime }
+func (h *header) AccessTime() time.Time            { return h.modTime }
+func (h *header) ETag() []byte                     { return h.etag }
+func (h *header) MimeType() string                 { return h.mimeType }
+func (h *header) ContentEncoding() string          { return h.encoding }
+func (h *header) CacheControl() string             { return h.cacheControl }
+func (h *header) ContentDisposition() string       { return h.disposition }
+func (h *header) Metadata() map[string]string      { return h.metadata }
+func (h *header) Version() string                  { return h.version }
+func (h *header) Redirect() string                 { return h.redirect }
+func (h *header) ServerSideEncryption() *absos.SSE { return h.sse }
+func (h *header) StorageClass() string             { return h.storageClass }
+func (h *header) Checksums() absos.Checksums       { return h.checksums }
+
+// formatETag is the inverse of parseETag. ETags that parseETag returned
+// unchanged are recognized by consisting of printable characters only.
+func formatETag(etag []byte) string {
+	raw := string(etag)
+	if _, err := hex.DecodeString(raw); err == nil || strings.IndexFunc(raw, notPrintable) >= 0 {
+		raw = hex.EncodeToString(etag)
+	}
+	return `"` + raw + `"`
+}
+
+func notPrintable(r rune) bool {
+	return r <= ' ' || r > '~'
+}
+
+// parseETag decodes a quoted hexadecimal ETag into its raw bytes.
+// ETags that are not plain hex digests, such as those of multipart uploads,
+// are returned unquoted but otherwise unchanged.
+func parseETag(etag string) []byte {
+	etag = strings.Trim(etag, `"`)
+	if etag == "" {
+		return nil
+	}
+
+	if b, err := hex.DecodeString(etag); err == nil {
+		return b
+	}
+
+	return []byte(etag)
+}
+
+// parseChecksums decodes the base64 checksums returned by S3. Checksums of
+// multipart uploads, which are checksums of the part checksums followed by
+// the number of parts, are skipped.
+func parseChecksums(crc32c, sha1, sha256 *string) absos.Checksums {
+	checksums := make(absos.Checksums)
+	for a, v := range map[absos.ChecksumAlgorithm]*string{
+		absos.ChecksumCRC32C: crc32c,
+		absos.ChecksumSHA1:   sha1,
+		absos.ChecksumSHA256: sha256,
+	} {
+		if sum, err := base64.StdEncoding.DecodeString(aws.StringValue(v)); err == nil && len(sum) > 0 {
+			checksums[a] = sum
+		}
+	}
+	return checksums
+}
+
+// formatChecksum encodes sum as a checksum header value, or nil if sum is empty.
+func formatChecksum(sum []byte) *string {
+	if len(sum) == 0 {
+		return nil
+	}
+	return aws.String(base64.StdEncoding.EncodeToString(sum))
+}
@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Presign returns a SigV4 presigned URL for a GetObject, PutObject or
+// HeadObject request. S3 accepts expiry times of up to seven days.
+func (b *Bucket) Presign(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if err := absos.CheckPresign(method, expires); err != nil {
+		return "", &absos.ObjectError{Bucket: b.name, Key: key, Err: err}
+	}
+
+	var req *request.Request
+	switch method {
+	case http.MethodGet:
+		req, _ = b.client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String(b.name), Key: aws.String(key)})
+	case http.MethodPut:
+		req, _ = b.client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String(b.name), Key: aws.String(key)})
+	case http.MethodHead:
+		req, _ = b.client.HeadObjectRequest(&s3.HeadObjectInput{Bucket: aws.String(b.name), Key: aws.String(key)})
+	}
+	req.SetContext(ctx)
+
+	url, err := req.Presign(expires)
+	if err != nil {
+		return "", objectError(ctx, b.name, key, err)
+	}
+
+	return url, nil
+}
@@ -0,0 +1,620 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/filestore"
+	"github.com/absfs/absos/server"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// newTestStore returns a Store talking to an in-process S3 server backed by
+// a filestore that returns at most two entries per listing page.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	backend, err := filestore.New(t.TempDir(), filestore.WithPageSize(2))
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	return newClientStore(t, server.New(backend))
+}
+
+// newClientStore returns a Store talking to an in-process server running handler.
+func newClientStore(t *testing.T, handler http.Handler) *Store {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(srv.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:       aws.Int(0),
+	})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	return New(s3.New(sess))
+}
+
+// newFakeStore returns a Store talking to an in-process fake S3 server, which
+// reports bucket owners and keeps the headers of PutObject requests, and the
+// SDK client it uses.
+func newFakeStore(t *testing.T) (*Store, *s3.S3) {
+	t.Helper()
+
+	store := newClientStore(t, newFakeS3())
+	return store, store.client.(*s3.S3)
+}
+
+func newTestBucket(t *testing.T) *Bucket {
+	t.Helper()
+
+	store := newTestStore(t)
+	if err := store.CreateBucket(context.Background(), "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	return store.Bucket("test-bucket")
+}
+
+func TestStoreBuckets(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Creating a duplicate bucket
+	err := store.CreateBucket(ctx, "test-bucket")
+	if !errors.Is(err, absos.ErrBucketAlreadyExists) {
+		t.Errorf("expected ErrBucketAlreadyExists, got %v", err)
+	}
+
+	var bucketErr *absos.BucketError
+	if !errors.As(err, &bucketErr) || bucketErr.Bucket != "test-bucket" {
+		t.Errorf("expected *absos.BucketError for test-bucket, got %v", err)
+	}
+
+	// The AWS error stays available
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != s3.ErrCodeBucketAlreadyOwnedByYou {
+		t.Errorf("expected wrapped awserr.Error, got %v", err)
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(buckets) != 1 || buckets[0].Name() != "test-bucket" {
+		t.Fatalf("expected [test-bucket], got %v", buckets)
+	}
+
+	if buckets[0].CreationTime().IsZero() {
+		t.Error("expected creation time to be set")
+	}
+
+	if err := store.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = store.DeleteBucket(ctx, "test-bucket")
+	if !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected ErrBucketNotFound, got %v", err)
+	}
+}
+
+func TestStoreBucketOwner(t *testing.T) {
+	store, _ := newFakeStore(t)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(buckets) != 1 || buckets[0].Name() != "test-bucket" {
+		t.Fatalf("expected [test-bucket], got %v", buckets)
+	}
+
+	if own := buckets[0].Owner(); own == nil || own.ID() != "owner-id" {
+		t.Errorf("expected owner-id, got %v", own)
+	}
+}
+
+func TestStoreDeleteNonEmptyBucket(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("test data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	err := New(bucket.client).DeleteBucket(ctx, "test-bucket")
+	if !errors.Is(err, absos.ErrBucketNotEmpty) {
+		t.Errorf("expected ErrBucketNotEmpty, got %v", err)
+	}
+}
+
+func TestBucketPutGetHead(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	testData := "Hello, World!"
+	if err := bucket.Put(ctx, "test-key", strings.NewReader(testData)); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	reader, err := bucket.Get(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read object: %v", err)
+	}
+
+	if string(content) != testData {
+		t.Errorf("expected %q, got %q", testData, string(content))
+	}
+
+	header, err := bucket.Head(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.Bucket() != "test-bucket" || header.Key() != "test-key" {
+		t.Errorf("unexpected bucket/key %q/%q", header.Bucket(), header.Key())
+	}
+
+	if header.Size() != int64(len(testData)) {
+		t.Errorf("expected size %d, got %d", len(testData), header.Size())
+	}
+
+	if header.StorageClass() != s3.StorageClassStandard {
+		t.Errorf("expected storage class %s, got %q", s3.StorageClassStandard, header.StorageClass())
+	}
+
+	if len(header.ETag()) != 16 {
+		t.Errorf("expected 16-byte MD5 ETag, got %x", header.ETag())
+	}
+}
+
+func TestBucketPutHeaders(t *testing.T) {
+	store, client := newFakeStore(t)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	// Populate the remaining header fields directly through the SDK
+	testData := "Hello, World!"
+	_, err := client.PutObject(&s3.PutObjectInput{
+		Bucket:                  aws.String("test-bucket"),
+		Key:                     aws.String("rich-key"),
+		Body:                    strings.NewReader(testData),
+		ContentType:             aws.String("text/plain"),
+		Metadata:                map[string]*string{"Color": aws.String("blue")},
+		StorageClass:            aws.String(s3.StorageClassStandardIa),
+		ServerSideEncryption:    aws.String(s3.ServerSideEncryptionAwsKms),
+		SSEKMSKeyId:             aws.String("key-id"),
+		WebsiteRedirectLocation: aws.String("/elsewhere"),
+	})
+	if err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	header, err := store.Bucket("test-bucket").Head(ctx, "rich-key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.Bucket() != "test-bucket" || header.Key() != "rich-key" {
+		t.Errorf("unexpected bucket/key %q/%q", header.Bucket(), header.Key())
+	}
+
+	if header.Size() != int64(len(testData)) {
+		t.Errorf("expected size %d, got %d", len(testData), header.Size())
+	}
+
+	if header.MimeType() != "text/plain" {
+		t.Errorf("expected mime type text/plain, got %q", header.MimeType())
+	}
+
+	if header.Metadata()["color"] != "blue" {
+		t.Errorf("expected metadata color=blue, got %v", header.Metadata())
+	}
+
+	if header.StorageClass() != s3.StorageClassStandardIa {
+		t.Errorf("expected storage class %s, got %q", s3.StorageClassStandardIa, header.StorageClass())
+	}
+
+	if header.Redirect() != "/elsewhere" {
+		t.Errorf("expected redirect /elsewhere, got %q", header.Redirect())
+	}
+
+	sse := header.ServerSideEncryption()
+	if sse == nil || sse.ServerSideEncryption != s3.ServerSideEncryptionAwsKms || sse.KMSKeyId != "key-id" {
+		t.Errorf("unexpected server-side encryption %+v", sse)
+	}
+
+	if len(header.ETag()) != 16 {
+		t.Errorf("expected 16-byte MD5 ETag, got %x", header.ETag())
+	}
+}
+
+func TestBucketHeadFields(t *testing.T) {
+	store := newClientStore(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Length", "13")
+		h.Set("Content-Type", "text/plain")
+		h.Set("ETag", `"65a8e27d8879283831b664bd8b7f0ad4"`)
+		h.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		h.Set("x-amz-meta-Color", "blue")
+		h.Set("x-amz-storage-class", s3.StorageClassStandardIa)
+		h.Set("x-amz-version-id", "v1")
+		h.Set("x-amz-website-redirect-location", "/elsewhere")
+		h.Set("x-amz-server-side-encryption", s3.ServerSideEncryptionAwsKms)
+		h.Set("x-amz-server-side-encryption-aws-kms-key-id", "key-id")
+	}))
+
+	header, err := store.Bucket("test-bucket").Head(context.Background(), "rich-key")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.Size() != 13 || header.MimeType() != "text/plain" {
+		t.Errorf("unexpected size %d and mime type %q", header.Size(), header.MimeType())
+	}
+
+	if header.ModTime().Year() != 2006 {
+		t.Errorf("unexpected modification time %v", header.ModTime())
+	}
+
+	if header.Metadata()["color"] != "blue" {
+		t.Errorf("expected metadata color=blue, got %v", header.Metadata())
+	}
+
+	if header.StorageClass() != s3.StorageClassStandardIa {
+		t.Errorf("expected storage class %s, got %q", s3.StorageClassStandardIa, header.StorageClass())
+	}
+
+	if header.Version() != "v1" || header.Redirect() != "/elsewhere" {
+		t.Errorf("unexpected version %q and redirect %q", header.Version(), header.Redirect())
+	}
+
+	sse := header.ServerSideEncryption()
+	if sse == nil || sse.ServerSideEncryption != s3.ServerSideEncryptionAwsKms || sse.KMSKeyId != "key-id" {
+		t.Errorf("unexpected server-side encryption %+v", sse)
+	}
+
+	if len(header.ETag()) != 16 {
+		t.Errorf("expected 16-byte MD5 ETag, got %x", header.ETag())
+	}
+}
+
+func TestBucketNotFoundErrors(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	_, err := bucket.Get(ctx, "missing")
+	if !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound from Get, got %v", err)
+	}
+
+	var objErr *absos.ObjectError
+	if !errors.As(err, &objErr) || objErr.Key != "missing" {
+		t.Errorf("expected *absos.ObjectError for missing, got %v", err)
+	}
+
+	// HEAD responses carry no error code in the body
+	if _, err := bucket.Head(ctx, "missing"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound from Head, got %v", err)
+	}
+
+	if err := bucket.Delete(ctx, "missing"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound from Delete, got %v", err)
+	}
+
+	missing := New(bucket.client).Bucket("missing-bucket")
+	err = missing.Put(ctx, "key", strings.NewReader("data"))
+	if !errors.Is(err, absos.ErrBucketNotFound) {
+		t.Errorf("expected ErrBucketNotFound, got %v", err)
+	}
+
+	var bucketErr *absos.BucketError
+	if !errors.As(err, &bucketErr) {
+		t.Errorf("expected *absos.BucketError, got %T", err)
+	}
+}
+
+func TestBucketPermissionDenied(t *testing.T) {
+	store := newClientStore(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		if r.Method != http.MethodHead {
+			_, _ = io.WriteString(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>")
+		}
+	}))
+	ctx := context.Background()
+
+	_, err := store.Bucket("forbidden").Get(ctx, "key")
+	if !errors.Is(err, absos.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied from Get, got %v", err)
+	}
+
+	_, err = store.Bucket("forbidden").Head(ctx, "key")
+	if !errors.Is(err, absos.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied from Head, got %v", err)
+	}
+}
+
+func TestBucketThrottled(t *testing.T) {
+	store := newClientStore(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>")
+	}))
+
+	_, err := store.Bucket("busy").Get(context.Background(), "key")
+	if !errors.Is(err, absos.ErrThrottled) || !absos.IsTemporary(err) {
+		t.Errorf("expected a temporary ErrThrottled, got %v", err)
+	}
+}
+
+func TestBucketDelete(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	if err := bucket.Put(ctx, "test-key", strings.NewReader("test data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	if err := bucket.Delete(ctx, "test-key"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := bucket.Get(ctx, "test-key"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound for deleted object, got %v", err)
+	}
+}
+
+func TestBucketObjectPage(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	keys := []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "e.txt"}
+	for _, key := range keys {
+		if err := bucket.Put(ctx, key, bytes.NewReader([]byte(key))); err != nil {
+			t.Fatalf("failed to put object %s: %v", key, err)
+		}
+	}
+
+	// Walk all pages with a delimiter
+	var objects, prefixes []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > len(keys) {
+			t.Fatal("pagination did not terminate")
+		}
+
+		page, err := bucket.ObjectPage(ctx, "", "/", token)
+		if err != nil {
+			t.Fatalf("failed to list objects: %v", err)
+		}
+
+		for _, obj := range page.Objects() {
+			objects = append(objects, obj.Key())
+			if obj.Bucket() != "test-bucket" || obj.Size() != int64(len(obj.Key())) {
+				t.Errorf("unexpected object %s/%s size %d", obj.Bucket(), obj.Key(), obj.Size())
+			}
+		}
+		prefixes = append(prefixes, page.Prefixes()...)
+
+		if page.Last() {
+			if page.NextPage() != "" {
+				t.Errorf("expected empty token on last page, got %q", page.NextPage())
+			}
+			break
+		}
+		token = page.NextPage()
+	}
+
+	sort.Strings(objects)
+	if strings.Join(objects, ",") != "a.txt,b.txt,e.txt" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	if strings.Join(prefixes, ",") != "dir/" {
+		t.Errorf("unexpected prefixes %v", prefixes)
+	}
+
+	// Objects returned by a page can be opened
+	page, err := bucket.ObjectPage(ctx, "dir/", "", "")
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+
+	obj := page.Objects()[0]
+	reader, err := obj.Open(ctx)
+	if err != nil {
+		t.Fatalf("failed to open object: %v", err)
+	}
+	defer reader.Close()
+
+	content, _ := io.ReadAll(reader)
+	if string(content) != obj.Key() {
+		t.Errorf("expected %q, got %q", obj.Key(), content)
+	}
+
+	if _, err := obj.Head(ctx); err != nil {
+		t.Errorf("failed to head listed object: %v", err)
+	}
+}
+
+func TestBucketPutBatch(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	iter := absos.NewBatchIterator(
+		absos.BatchObject{Key: "a.txt", Data: strings.NewReader("a")},
+		absos.BatchObject{Key: "b.txt", Data: strings.NewReader("b")},
+	)
+
+	if err := bucket.PutBatch(ctx, iter); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, key := range []string{"a.txt", "b.txt"} {
+		if _, err := bucket.Head(ctx, key); err != nil {
+			t.Errorf("expected %s to be uploaded, got %v", key, err)
+		}
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	bucket := newTestBucket(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := bucket.Get(ctx, "key"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		etag     string
+		expected []byte
+	}{
+		{`"d41d8cd98f00b204e9800998ecf8427e"`, []byte{0xd4, 0x1d, 0x8c, 0xd9, 0x8f, 0x00, 0xb2, 0x04, 0xe9, 0x80, 0x09, 0x98, 0xec, 0xf8, 0x42, 0x7e}},
+		{`"9b2cf535f27731c974343645a3985328-3"`, []byte("9b2cf535f27731c974343645a3985328-3")},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseETag(tt.etag); !bytes.Equal(got, tt.expected) {
+			t.Errorf("parseETag(%q) = %x, expected %x", tt.etag, got, tt.expected)
+		}
+
+		if tt.etag != "" {
+			if got := formatETag(tt.expected); got != tt.etag {
+				t.Errorf("formatETag(%x) = %s, expected %s", tt.expected, got, tt.etag)
+			}
+		}
+	}
+}
+
+func TestBucketPutStreamMultipart(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	// Larger than one part of the uploader, so it switches to multipart
+	data := bytes.Repeat([]byte("0123456789"), absos.MinPartSize/10*2+1)
+	err := bucket.PutStream(ctx, "big", io.MultiReader(bytes.NewReader(data)), absos.WithContentType("text/plain"))
+	if err != nil {
+		t.Fatalf("failed to put stream: %v", err)
+	}
+
+	reader, err := bucket.Get(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	defer reader.Close()
+
+	content, _ := io.ReadAll(reader)
+	if !bytes.Equal(content, data) {
+		t.Errorf("expected %d bytes, got %d", len(data), len(content))
+	}
+
+	header, err := bucket.Head(ctx, "big")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+	if header.MimeType() != "text/plain" {
+		t.Errorf("expected MIME type text/plain, got %q", header.MimeType())
+	}
+
+	uploads, err := bucket.ListMultipartUploads(ctx, "")
+	if err != nil || len(uploads) != 0 {
+		t.Errorf("expected no uploads left, got %v (%v)", uploads, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return newTestStore(t)
+	})
+}
+
+func TestBucketPresign(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBucket(t)
+
+	put, err := b.Presign(ctx, http.MethodPut, "dir/key", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+
+	// The test server does not verify signatures, but serves the URL
+	req, err := http.NewRequest(http.MethodPut, put, strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	resp.Body.Close()
+
+	get, err := b.Presign(ctx, http.MethodGet, "dir/key", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to presign: %v", err)
+	}
+	if !strings.Contains(get, "X-Amz-Signature=") || !strings.Contains(get, "X-Amz-Expires=3600") {
+		t.Errorf("expected a SigV4 URL, got %s", get)
+	}
+
+	resp, err = http.Get(get)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != "data" {
+		t.Errorf("expected %q, got %q (%v)", "data", data, err)
+	}
+
+	if _, err := b.Presign(ctx, http.MethodDelete, "dir/key", time.Hour); !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for DELETE, got %v", err)
+	}
+}
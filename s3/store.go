@@ -0,0 +1,103 @@
+// Package s3 implements the absos interfaces on top of Amazon S3 and
+// S3-compatible services using aws-sdk-go.
+//
+// AWS error codes are translated into the absos sentinel errors and wrapped
+// in *absos.BucketError or *absos.ObjectError, so callers can use errors.Is
+// without depending on the SDK. The original AWS error stays in the chain
+// and remains available through errors.As.
+//
+// S3 does not report deletions of missing keys, so Delete and DeleteIf send
+// a HeadObject request before DeleteObject in order to return
+// absos.ErrObjectNotFound as absos.Bucket requires. Deleting an object thus
+// takes two requests; callers that do not need the check, such as cleanup
+// jobs, can use DeleteMany, which sends one DeleteObjects request per
+// thousand keys and ignores missing ones.
+package s3
+
+import (
+	"context"
+
+	"github.com/absfs/absos"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Store is an S3 implementation of absos.ObjectStore.
+type Store struct {
+	client s3iface.S3API
+}
+
+// New creates a store that issues requests with the given S3 client.
+func New(client s3iface.S3API) *Store {
+	return &Store{client: client}
+}
+
+// Bucket returns a handle to the named bucket without checking that it exists.
+// Operations on a missing bucket fail with absos.ErrBucketNotFound.
+func (s *Store) Bucket(name string) *Bucket {
+	return &Bucket{client: s.client, name: name}
+}
+
+// CreateBucket creates a new bucket in the client's region.
+func (s *Store) CreateBucket(ctx context.Context, name string) error {
+	input := &s3.CreateBucketInput{Bucket: aws.String(name)}
+
+	// Buckets outside us-east-1 must name their region explicitly.
+	if c, ok := s.client.(*s3.S3); ok {
+		if region := aws.StringValue(c.Config.Region); region != "" && region != "us-east-1" {
+			input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
+				LocationConstraint: aws.String(region),
+			}
+		}
+	}
+
+	if _, err := s.client.CreateBucketWithContext(ctx, input); err != nil {
+		return bucketError(ctx, name, err)
+	}
+
+	return nil
+}
+
+// DeleteBucket deletes an empty bucket.
+func (s *Store) DeleteBucket(ctx context.Context, name string) error {
+	_, err := s.client.DeleteBucketWithContext(ctx, &s3.DeleteBucketInput{Bucket: aws.String(name)})
+	if err != nil {
+		return bucketError(ctx, name, err)
+	}
+
+	return nil
+}
+
+// ListBuckets returns all buckets owned by the authenticated sender.
+func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	out, err := s.client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, translate(ctx, err)
+	}
+
+	var own absos.Owner
+	if out.Owner != nil {
+		own = &owner{name: aws.StringValue(out.Owner.DisplayName), id: aws.StringValue(out.Owner.ID)}
+	}
+
+	buckets := make([]absos.Bucket, 0, len(out.Buckets))
+	for _, b := range out.Buckets {
+		buckets = append(buckets, &Bucket{
+			client:  s.client,
+			name:    aws.StringValue(b.Name),
+			created: aws.TimeValue(b.CreationDate),
+			owner:   own,
+		})
+	}
+
+	return buckets, nil
+}
+
+type owner struct {
+	name string
+	id   string
+}
+
+func (o *owner) Name() string { return o.name }
+func (o *owner) ID() string   { return o.id }
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/listing"
+)
+
+func (s *Server) listBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets, err := s.store.ListBuckets(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result := listAllMyBucketsResult{Xmlns: xmlns}
+	for _, b := range buckets {
+		if own := b.Owner(); own != nil && result.Owner == nil {
+			result.Owner = &owner{ID: own.ID(), DisplayName: own.Name()}
+		}
+		result.Buckets = append(result.Buckets, bucketEntry{
+			Name:         b.Name(),
+			CreationDate: b.CreationTime().UTC().Format(time.RFC3339),
+		})
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+func (s *Server) createBucket(w http.ResponseWriter, r *http.Request, name string) {
+	defer s.forget(name)
+	if err := s.store.CreateBucket(r.Context(), name); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/"+name)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteBucket(w http.ResponseWriter, r *http.Request, name string) {
+	defer s.forget(name)
+	if err := s.store.DeleteBucket(r.Context(), name); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) bucketLocation(w http.ResponseWriter, r *http.Request, name string) {
+	if _, err := s.bucket(r.Context(), name); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeXML(w, http.StatusOK, locationConstraint{Xmlns: xmlns})
+}
+
+// listObjects implements ListObjectsV2 on top of Bucket.ObjectPage. Pages of
+// the bucket are merged or split to return max-keys entries per response, so
+// continuation tokens refer to a page of the bucket and the last entry
+// returned from it.
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request, name string) {
+	b, err := s.bucket(r.Context(), name)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	q := r.URL.Query()
+	prefix, delimiter, token := q.Get("prefix"), q.Get("delimiter"), q.Get("continuation-token")
+
+	maxKeys := maxListKeys
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeError(w, r, errInvalidArgument)
+			return
+		}
+		maxKeys = min(n, maxListKeys)
+	}
+
+	// The start-after parameter only applies to the first page.
+	cursor := listCursor{After: q.Get("start-after")}
+	if token != "" {
+		if cursor, err = decodeListCursor(token); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+	}
+
+	entries, next, err := listEntries(r.Context(), b, prefix, delimiter, cursor, maxKeys)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// S3 tools request URL-encoded keys so that any byte sequence survives XML.
+	encode := func(s string) string { return s }
+	encodingType := ""
+	if q.Get("encoding-type") == "url" {
+		encode = url.QueryEscape
+		encodingType = "url"
+	}
+
+	result := listBucketResult{
+		Xmlns:             xmlns,
+		Name:              name,
+		Prefix:            encode(prefix),
+		Delimiter:         encode(delimiter),
+		MaxKeys:           maxKeys,
+		EncodingType:      encodingType,
+		StartAfter:        encode(q.Get("start-after")),
+		ContinuationToken: token,
+		IsTruncated:       next != nil,
+		KeyCount:          len(entries),
+	}
+	if next != nil {
+		result.NextContinuationToken = next.encode()
+	}
+
+	for _, e := range entries {
+		if e.obj == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: encode(e.prefix)})
+			continue
+		}
+		result.Contents = append(result.Contents, objectEntry{
+			Key:          encode(e.obj.Key()),
+			LastModified: e.obj.ModTime().UTC().Format(time.RFC3339Nano),
+			ETag:         formatETag(e.obj.ETag()),
+			Size:         e.obj.Size(),
+			StorageClass: storageClass(e.obj.StorageClass()),
+		})
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+// maxListKeys is the maximum number of entries in a ListObjectsV2 response.
+const maxListKeys = 1000
+
+// listCursor is the position of a listing: the token of the page of the
+// bucket to read next, and the last entry already returned from it.
+type listCursor struct {
+	Token string `json:"t,omitempty"`
+	After string `json:"a,omitempty"`
+}
+
+func (c *listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(token string) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, listing.ErrInvalidToken
+	}
+	return c, nil
+}
+
+// listEntry is an object or a common prefix of a listing.
+type listEntry struct {
+	obj    absos.Object
+	prefix string
+}
+
+func (e listEntry) key() string {
+	if e.obj != nil {
+		return e.obj.Key()
+	}
+	return e.prefix
+}
+
+// listEntries returns up to maxKeys entries of b following cursor, and the
+// cursor of the next entries, or nil if there are none.
+func listEntries(ctx context.Context, b absos.Bucket, prefix, delimiter string, cursor listCursor, maxKeys int) ([]listEntry, *listCursor, error) {
+	// Like S3, an empty page is not truncated.
+	if maxKeys == 0 {
+		return nil, nil, nil
+	}
+
+	var entries []listEntry
+	for {
+		page, err := b.ObjectPage(ctx, prefix, delimiter, cursor.Token)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, e := range mergeEntries(page) {
+			if e.key() <= cursor.After {
+				continue
+			}
+			if len(entries) == maxKeys {
+				return entries, &listCursor{Token: cursor.Token, After: cursor.After}, nil
+			}
+			entries = append(entries, e)
+			cursor.After = e.key()
+		}
+
+		if page.Last() {
+			return entries, nil, nil
+		}
+		cursor.Token = page.NextPage()
+	}
+}
+
+// mergeEntries returns the objects and common prefixes of page in
+// lexicographic order.
+func mergeEntries(page absos.Page) []listEntry {
+	objects, prefixes := page.Objects(), page.Prefixes()
+	entries := make([]listEntry, 0, len(objects)+len(prefixes))
+	for len(objects) > 0 || len(prefixes) > 0 {
+		if len(prefixes) == 0 || (len(objects) > 0 && objects[0].Key() < prefixes[0]) {
+			entries = append(entries, listEntry{obj: objects[0]})
+			objects = objects[1:]
+		} else {
+			entries = append(entries, listEntry{prefix: prefixes[0]})
+			prefixes = prefixes[1:]
+		}
+	}
+	return entries
+}
+
+// maxDeleteKeys is the maximum number of keys in a DeleteObjects request.
+const maxDeleteKeys = 1000
+
+// deleteObjects implements DeleteObjects on top of absos.DeleteMany.
+func (s *Server) deleteObjects(w http.ResponseWriter, r *http.Request, name string) {
+	b, err := s.bucket(r.Context(), name)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	var req deleteRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Objects) > maxDeleteKeys {
+		s.writeError(w, r, errMalformedXML)
+		return
+	}
+
+	keys := make([]string, len(req.Objects))
+	for i, obj := range req.Objects {
+		keys[i] = obj.Key
+	}
+
+	failed := make(map[string]error)
+	err = absos.DeleteMany(r.Context(), b, keys)
+	var batchErr *absos.BatchError
+	if errors.As(err, &batchErr) {
+		for _, e := range batchErr.Errors {
+			failed[e.Key] = e
+		}
+	} else if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result := deleteResult{Xmlns: xmlns}
+	for _, key := range keys {
+		if err, ok := failed[key]; ok {
+			e := toS3Error(err)
+			result.Errors = append(result.Errors, deleteError{Key: key, Code: e.Code, Message: e.Message})
+		} else if !req.Quiet {
+			result.Deleted = append(result.Deleted, deletedEntry{Key: key})
+		}
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+// storageClass returns class, defaulting to STANDARD when it is unknown.
+func storageClass(class string) string {
+	if class == "" {
+		return "STANDARD"
+	}
+	return class
+}
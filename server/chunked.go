@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errMalformedChunk = errors.New("malformed aws-chunked body")
+
+// chunkedReader decodes the aws-chunked encoding, in which the payload is
+// sent as a series of "<hex size>[;chunk-signature=...]\r\n<data>\r\n"
+// chunks ending with a zero-sized chunk and optional trailers. Chunk
+// signatures and trailing checksums are not verified.
+type chunkedReader struct {
+	r         *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func newChunkedReader(r io.Reader) *chunkedReader {
+	return &chunkedReader{r: bufio.NewReader(r)}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err == nil && c.remaining == 0 {
+		err = c.expectCRLF()
+	}
+
+	return n, err
+}
+
+// nextChunk reads the next chunk header.
+func (c *chunkedReader) nextChunk() error {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return errMalformedChunk
+	}
+
+	size, _, _ := strings.Cut(strings.TrimRight(line, "\r\n"), ";")
+	n, err := strconv.ParseInt(size, 16, 64)
+	if err != nil || n < 0 {
+		return errMalformedChunk
+	}
+
+	if n == 0 {
+		c.done = true
+		// Discard trailers up to the final empty line, if any.
+		for {
+			line, err := c.r.ReadString('\n')
+			if strings.TrimRight(line, "\r\n") == "" || err != nil {
+				return nil
+			}
+		}
+	}
+
+	c.remaining = n
+	return nil
+}
+
+func (c *chunkedReader) expectCRLF() error {
+	var crlf [2]byte
+	if _, err := io.ReadFull(c.r, crlf[:]); err != nil || string(crlf[:]) != "\r\n" {
+		return errMalformedChunk
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/listing"
+)
+
+// s3Error is an error reported with an S3 error code and HTTP status.
+type s3Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *s3Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	errMethodNotAllowed = &s3Error{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	errNotImplemented   = &s3Error{"NotImplemented", "A header or query you provided implies functionality that is not implemented.", http.StatusNotImplemented}
+	errInvalidArgument  = &s3Error{"InvalidArgument", "Invalid Argument", http.StatusBadRequest}
+	errIncompleteBody   = &s3Error{"IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header.", http.StatusBadRequest}
+	errMalformedXML     = &s3Error{"MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest}
+	errInternal         = &s3Error{"InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError}
+)
+
+// codes maps the absos sentinel errors to their S3 counterparts.
+var codes = []struct {
+	err error
+	s3  *s3Error
+}{
+	{absos.ErrBucketNotFound, &s3Error{"NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound}},
+	{absos.ErrObjectNotFound, &s3Error{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}},
+	{absos.ErrBucketAlreadyExists, &s3Error{"BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", http.StatusConflict}},
+	{absos.ErrBucketNotEmpty, &s3Error{"BucketNotEmpty", "The bucket you tried to delete is not empty.", http.StatusConflict}},
+	{absos.ErrInvalidBucketName, &s3Error{"InvalidBucketName", "The specified bucket is not valid.", http.StatusBadRequest}},
+	{absos.ErrInvalidKey, &s3Error{"InvalidArgument", "The specified key is not valid.", http.StatusBadRequest}},
+	{absos.ErrPermissionDenied, &s3Error{"AccessDenied", "Access Denied", http.StatusForbidden}},
+	{absos.ErrInvalidRange, &s3Error{"InvalidRange", "The requested range is not satisfiable", http.StatusRequestedRangeNotSatisfiable}},
+	{absos.ErrUploadNotFound, &s3Error{"NoSuchUpload", "The specified multipart upload does not exist.", http.StatusNotFound}},
+	{absos.ErrInvalidPart, &s3Error{"InvalidPart", "One or more of the specified parts could not be found or did not match.", http.StatusBadRequest}},
+	{absos.ErrPreconditionFailed, &s3Error{"PreconditionFailed", "At least one of the pre-conditions you specified did not hold", http.StatusPreconditionFailed}},
+	{absos.ErrNotModified, &s3Error{"NotModified", "Not Modified", http.StatusNotModified}},
+	{absos.ErrChecksumMismatch, &s3Error{"BadDigest", "The checksum you specified did not match the calculated checksum.", http.StatusBadRequest}},
+	{absos.ErrThrottled, &s3Error{"SlowDown", "Please reduce your request rate.", http.StatusServiceUnavailable}},
+	{absos.ErrUnavailable, &s3Error{"ServiceUnavailable", "Service is unable to handle request.", http.StatusServiceUnavailable}},
+	{absos.ErrNotSupported, errNotImplemented},
+	{listing.ErrInvalidToken, &s3Error{"InvalidArgument", "The continuation token provided is incorrect.", http.StatusBadRequest}},
+}
+
+// toS3Error maps err to the S3 error reported to the client.
+func toS3Error(err error) *s3Error {
+	var e *s3Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.s3
+		}
+	}
+
+	return errInternal
+}
+
+// writeError writes err as an S3 XML error response. HEAD and 304 Not
+// Modified responses carry only the status code.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := toS3Error(err)
+
+	if r.Method == http.MethodHead || e.Status == http.StatusNotModified {
+		w.WriteHeader(e.Status)
+		return
+	}
+
+	writeXML(w, e.Status, errorResponse{
+		Code:      e.Code,
+		Message:   e.Message,
+		Resource:  r.URL.Path,
+		RequestID: w.Header().Get("x-amz-request-id"),
+	})
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+// multipartBucket returns b as an absos.MultipartBucket, writing a
+// NotImplemented error if it does not support multipart uploads.
+func multipartBucket(w http.ResponseWriter, r *http.Request, b absos.Bucket) (absos.MultipartBucket, bool) {
+	mb, ok := b.(absos.MultipartBucket)
+	if !ok {
+		writeError(w, r, errNotImplemented)
+	}
+	return mb, ok
+}
+
+// upload returns the multipart upload addressed by the request.
+func upload(r *http.Request, b absos.Bucket, key string) absos.MultipartUpload {
+	return absos.MultipartUpload{Bucket: b.Name(), Key: key, ID: r.URL.Query().Get("uploadId")}
+}
+
+func (s *Server) initiateMultipart(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	mb, ok := multipartBucket(w, r, b)
+	if !ok {
+		return
+	}
+
+	u, err := mb.InitiateMultipart(r.Context(), key, putOptions(r.Header)...)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Xmlns:    xmlns,
+		Bucket:   u.Bucket,
+		Key:      u.Key,
+		UploadID: u.ID,
+	})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	mb, ok := multipartBucket(w, r, b)
+	if !ok {
+		return
+	}
+
+	// UploadPartCopy is not supported
+	if r.Header.Get("x-amz-copy-source") != "" {
+		s.writeError(w, r, errNotImplemented)
+		return
+	}
+
+	number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil {
+		s.writeError(w, r, errInvalidArgument)
+		return
+	}
+
+	body := &requestBody{r: r.Body}
+	if isChunked(r) {
+		body.r = newChunkedReader(r.Body)
+	}
+
+	// Parts may be up to 5 GiB, so they are spooled to disk rather than
+	// memory for UploadPart, which requires a seekable reader.
+	data, err := spool(body)
+	if err != nil {
+		if body.err != nil {
+			err = errIncompleteBody
+		}
+		s.writeError(w, r, err)
+		return
+	}
+	defer closeSpool(data)
+
+	part, err := mb.UploadPart(r.Context(), upload(r, b, key), number, data)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(part.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listParts(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	mb, ok := multipartBucket(w, r, b)
+	if !ok {
+		return
+	}
+
+	u := upload(r, b, key)
+	parts, err := mb.ListParts(r.Context(), u)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result := listPartsResult{
+		Xmlns:    xmlns,
+		Bucket:   u.Bucket,
+		Key:      u.Key,
+		UploadID: u.ID,
+		MaxParts: absos.MaxParts,
+	}
+	for _, p := range parts {
+		result.Parts = append(result.Parts, partEntry{
+			PartNumber:   p.Number,
+			LastModified: p.ModTime.UTC().Format(time.RFC3339),
+			ETag:         formatETag(p.ETag),
+			Size:         p.Size,
+		})
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+func (s *Server) completeMultipart(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	mb, ok := multipartBucket(w, r, b)
+	if !ok {
+		return
+	}
+
+	var req completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, errMalformedXML)
+		return
+	}
+
+	parts := make([]absos.Part, len(req.Parts))
+	for i, p := range req.Parts {
+		parts[i] = absos.Part{Number: p.PartNumber, ETag: parseETag(p.ETag)}
+	}
+
+	u := upload(r, b, key)
+	if err := mb.CompleteMultipart(r.Context(), u, parts); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result := completeMultipartUploadResult{
+		Xmlns:    xmlns,
+		Location: "/" + u.Bucket + "/" + u.Key,
+		Bucket:   u.Bucket,
+		Key:      u.Key,
+	}
+	if header, err := b.Head(r.Context(), key); err == nil && len(header.ETag()) > 0 {
+		result.ETag = formatETag(header.ETag())
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+func (s *Server) abortMultipart(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	mb, ok := multipartBucket(w, r, b)
+	if !ok {
+		return
+	}
+
+	if err := mb.AbortMultipart(r.Context(), upload(r, b, key)); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listMultipartUploads returns every in-progress upload in a single page.
+func (s *Server) listMultipartUploads(w http.ResponseWriter, r *http.Request, name string) {
+	b, err := s.bucket(r.Context(), name)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	mb, ok := multipartBucket(w, r, b)
+	if !ok {
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	uploads, err := mb.ListMultipartUploads(r.Context(), prefix)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result := listMultipartUploadsResult{
+		Xmlns:      xmlns,
+		Bucket:     name,
+		Prefix:     prefix,
+		MaxUploads: len(uploads),
+	}
+	for _, u := range uploads {
+		result.Uploads = append(result.Uploads, uploadEntry{
+			Key:       u.Key,
+			UploadID:  u.ID,
+			Initiated: u.Initiated.UTC().Format(time.RFC3339),
+		})
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+// parseETag decodes an ETag sent by a client, the inverse of formatETag.
+func parseETag(etag string) []byte {
+	etag = strings.Trim(etag, `"`)
+	if b, err := hex.DecodeString(etag); err == nil {
+		return b
+	}
+	return []byte(etag)
+}
@@ -0,0 +1,494 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/absfs/absos"
+)
+
+func (s *Server) headObject(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	header, err := s.head(r, b, key)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeObjectHeader(w, header)
+	writeChecksums(w, r, header)
+	w.WriteHeader(http.StatusOK)
+}
+
+// getAttempts is the number of times getObject reads an object replaced
+// between its Head and its Get before it gives up.
+const getAttempts = 3
+
+// getObject serves the object with its header. The body is read from the
+// object described by the header: if the object is replaced in between, the
+// request starts over, and fails with 503 Service Unavailable once it ran
+// out of attempts.
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	offset, length, ranged := parseRange(r.Header.Get("Range"))
+
+	var (
+		header   absos.ObjectHeader
+		body     io.ReadCloser
+		start, n int64
+		err      error
+	)
+	for attempt := 0; attempt < getAttempts; attempt++ {
+		if header, err = s.head(r, b, key); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		start, n = 0, -1
+		if ranged {
+			if start, n, err = absos.ResolveRange(header.Size(), offset, length); err != nil {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", header.Size()))
+				s.writeError(w, r, err)
+				return
+			}
+		}
+
+		body, err = open(r.Context(), b, key, header.ETag(), start, n)
+		if !errors.Is(err, absos.ErrPreconditionFailed) {
+			break
+		}
+	}
+
+	if errors.Is(err, absos.ErrPreconditionFailed) {
+		err = &absos.ObjectError{Bucket: b.Name(), Key: key, Err: absos.ErrUnavailable}
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	writeObjectHeader(w, header)
+	if ranged {
+		w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+n-1, header.Size()))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		writeChecksums(w, r, header)
+		w.WriteHeader(http.StatusOK)
+	}
+	_, _ = io.Copy(w, body)
+}
+
+// open opens the object with the specified key, or n bytes of it from
+// start if n is positive. Unless etag is empty, it fails with
+// absos.ErrPreconditionFailed if the object no longer has this ETag.
+func open(ctx context.Context, b absos.Bucket, key string, etag []byte, start, n int64) (io.ReadCloser, error) {
+	cond := absos.Conditions{IfMatch: etag}
+	switch {
+	case len(etag) == 0 && n > 0:
+		return absos.GetRange(ctx, b, key, start, n)
+	case len(etag) == 0:
+		return b.Get(ctx, key)
+	case n > 0:
+		return absos.GetRangeIf(ctx, b, key, start, n, cond)
+	default:
+		return absos.GetIf(ctx, b, key, cond)
+	}
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	if r.Header.Get("x-amz-copy-source") != "" {
+		s.copyObject(w, r, b, key)
+		return
+	}
+
+	body := &requestBody{r: r.Body}
+	if isChunked(r) {
+		body.r = newChunkedReader(r.Body)
+	}
+
+	checksums, err := requestChecksums(r.Header)
+	if err != nil {
+		s.writeError(w, r, errInvalidArgument)
+		return
+	}
+
+	opts := putOptions(r.Header)
+	for a, sum := range checksums {
+		opts = append(opts, absos.WithChecksum(a, sum))
+	}
+
+	if cond, ok := conditions(r.Header); ok {
+		err = putIf(r.Context(), b, key, body, cond, opts...)
+	} else {
+		err = absos.Upload(r.Context(), b, key, body, opts...)
+	}
+
+	if err != nil {
+		if body.err != nil {
+			err = errIncompleteBody
+		}
+		s.writeError(w, r, err)
+		return
+	}
+
+	if header, err := b.Head(r.Context(), key); err == nil {
+		if etag := header.ETag(); len(etag) > 0 {
+			w.Header().Set("ETag", formatETag(etag))
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	source := r.Header.Get("x-amz-copy-source")
+	source, _, _ = strings.Cut(source, "?")
+
+	srcBucket, srcKey, ok := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	if !ok || srcKey == "" {
+		s.writeError(w, r, errInvalidArgument)
+		return
+	}
+
+	srcKey, err := url.PathUnescape(srcKey)
+	if err != nil {
+		s.writeError(w, r, errInvalidArgument)
+		return
+	}
+
+	src, err := s.bucket(r.Context(), srcBucket)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	var opts []absos.CopyOption
+	switch r.Header.Get("x-amz-metadata-directive") {
+	case "", "COPY":
+	case "REPLACE":
+		opts = append(opts, absos.WithReplacedMetadata(putOptions(r.Header)...))
+	default:
+		s.writeError(w, r, errInvalidArgument)
+		return
+	}
+
+	if err := absos.Copy(r.Context(), src, srcKey, b, key, opts...); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	header, err := b.Head(r.Context(), key)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result := copyObjectResult{
+		Xmlns:        xmlns,
+		LastModified: header.ModTime().UTC().Format(time.RFC3339),
+	}
+	if etag := header.ETag(); len(etag) > 0 {
+		result.ETag = formatETag(etag)
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+// putIf stores a conditional PutObject request body, which is spooled to a
+// temporary file since absos.PutIf requires a seekable reader.
+func putIf(ctx context.Context, b absos.Bucket, key string, data io.Reader, cond absos.Conditions, opts ...absos.PutOption) error {
+	f, err := spool(data)
+	if err != nil {
+		return err
+	}
+	defer closeSpool(f)
+
+	return absos.PutIf(ctx, b, key, f, cond, opts...)
+}
+
+// spool copies data to a temporary file, to be released with closeSpool, and
+// rewinds it, so that request bodies of any size can be passed to methods
+// requiring a seekable reader.
+func spool(data io.Reader) (*os.File, error) {
+	f, err := os.CreateTemp("", "absos-server-*")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(f, data)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		closeSpool(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// closeSpool closes and removes a file returned by spool.
+func closeSpool(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// head retrieves the metadata of the object, evaluating the conditional
+// headers of the request.
+func (s *Server) head(r *http.Request, b absos.Bucket, key string) (absos.ObjectHeader, error) {
+	if cond, ok := conditions(r.Header); ok {
+		return absos.HeadIf(r.Context(), b, key, cond)
+	}
+	return b.Head(r.Context(), key)
+}
+
+// conditions returns the preconditions set by the conditional headers of a
+// request, and whether there are any. Malformed dates are ignored, as HTTP
+// requires.
+func conditions(h http.Header) (absos.Conditions, bool) {
+	var cond absos.Conditions
+	var ok bool
+
+	if v := h.Get("If-Match"); v != "" && v != "*" {
+		cond.IfMatch, ok = parseETag(v), true
+	}
+
+	if v := h.Get("If-None-Match"); v == "*" {
+		cond.IfNotExists, ok = true, true
+	} else if v != "" {
+		cond.IfNoneMatch, ok = parseETag(v), true
+	}
+
+	if t, err := http.ParseTime(h.Get("If-Modified-Since")); err == nil {
+		cond.IfModifiedSince, ok = t, true
+	}
+	if t, err := http.ParseTime(h.Get("If-Unmodified-Since")); err == nil {
+		cond.IfUnmodifiedSince, ok = t, true
+	}
+
+	return cond, ok
+}
+
+// requestBody records the error that ended reading a request body, so that
+// failed uploads caused by the client can be told apart from store errors.
+type requestBody struct {
+	r   io.Reader
+	err error
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// putOptions returns the options set by the headers of a PutObject request.
+func putOptions(h http.Header) []absos.PutOption {
+	var opts []absos.PutOption
+
+	add := func(value string, option func(string) absos.PutOption) {
+		if value != "" {
+			opts = append(opts, option(value))
+		}
+	}
+
+	add(h.Get("Content-Type"), absos.WithContentType)
+	add(contentEncoding(h.Get("Content-Encoding")), absos.WithContentEncoding)
+	add(h.Get("Cache-Control"), absos.WithCacheControl)
+	add(h.Get("Content-Disposition"), absos.WithContentDisposition)
+	add(h.Get("x-amz-storage-class"), absos.WithStorageClass)
+
+	metadata := make(map[string]string)
+	for k, v := range h {
+		if name, ok := strings.CutPrefix(strings.ToLower(k), "x-amz-meta-"); ok && len(v) > 0 {
+			metadata[name] = v[0]
+		}
+	}
+	if len(metadata) > 0 {
+		opts = append(opts, absos.WithMetadata(metadata))
+	}
+
+	sse := &absos.SSE{
+		ServerSideEncryption: h.Get("x-amz-server-side-encryption"),
+		KMSKeyId:             h.Get("x-amz-server-side-encryption-aws-kms-key-id"),
+	}
+	if sse.ServerSideEncryption != "" || sse.KMSKeyId != "" {
+		opts = append(opts, absos.WithSSE(sse))
+	}
+
+	return opts
+}
+
+// requestChecksums decodes the x-amz-checksum-* headers of a request.
+func requestChecksums(h http.Header) (absos.Checksums, error) {
+	checksums := make(absos.Checksums)
+	for _, a := range absos.ChecksumAlgorithms {
+		v := h.Get(checksumHeader(a))
+		if v == "" {
+			continue
+		}
+
+		sum, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		checksums[a] = sum
+	}
+	return checksums, nil
+}
+
+// checksumHeader returns the name of the header holding a checksum.
+func checksumHeader(a absos.ChecksumAlgorithm) string {
+	return "x-amz-checksum-" + strings.ToLower(string(a))
+}
+
+// contentEncoding removes the aws-chunked transfer encoding from a
+// Content-Encoding header, leaving the encoding of the stored object.
+func contentEncoding(header string) string {
+	var encodings []string
+	for _, e := range strings.Split(header, ",") {
+		if e = strings.TrimSpace(e); e != "" && e != "aws-chunked" {
+			encodings = append(encodings, e)
+		}
+	}
+	return strings.Join(encodings, ",")
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, b absos.Bucket, key string) {
+	// S3 reports success when deleting a missing key.
+	var err error
+	if cond, ok := conditions(r.Header); ok {
+		err = absos.DeleteIf(r.Context(), b, key, cond)
+	} else {
+		err = b.Delete(r.Context(), key)
+	}
+	if err != nil && !errors.Is(err, absos.ErrObjectNotFound) {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeObjectHeader sets the S3 response headers describing an object.
+func writeObjectHeader(w http.ResponseWriter, header absos.ObjectHeader) {
+	h := w.Header()
+	h.Set("Content-Length", strconv.FormatInt(header.Size(), 10))
+	h.Set("Last-Modified", header.ModTime().UTC().Format(http.TimeFormat))
+	h.Set("Accept-Ranges", "bytes")
+
+	if etag := header.ETag(); len(etag) > 0 {
+		h.Set("ETag", formatETag(etag))
+	}
+
+	setIf(h, "Content-Type", header.MimeType())
+	setIf(h, "Content-Encoding", header.ContentEncoding())
+	setIf(h, "Cache-Control", header.CacheControl())
+	setIf(h, "Content-Disposition", header.ContentDisposition())
+
+	for k, v := range header.Metadata() {
+		h.Set("x-amz-meta-"+k, v)
+	}
+
+	if class := header.StorageClass(); class != "" && class != "STANDARD" {
+		h.Set("x-amz-storage-class", class)
+	}
+
+	if v := header.Version(); v != "" {
+		h.Set("x-amz-version-id", v)
+	}
+
+	if v := header.Redirect(); v != "" {
+		h.Set("x-amz-website-redirect-location", v)
+	}
+
+	if sse := header.ServerSideEncryption(); sse != nil {
+		setIf(h, "x-amz-server-side-encryption", sse.ServerSideEncryption)
+		setIf(h, "x-amz-server-side-encryption-aws-kms-key-id", sse.KMSKeyId)
+		setIf(h, "x-amz-server-side-encryption-customer-algorithm", sse.Algorithms)
+		setIf(h, "x-amz-server-side-encryption-customer-key-MD5", sse.KeyMD5)
+	}
+}
+
+// writeChecksums sets the checksum headers of an object if the request
+// enabled them with x-amz-checksum-mode, as S3 does.
+func writeChecksums(w http.ResponseWriter, r *http.Request, header absos.ObjectHeader) {
+	if !strings.EqualFold(r.Header.Get("x-amz-checksum-mode"), "ENABLED") {
+		return
+	}
+
+	for a, sum := range header.Checksums() {
+		w.Header().Set(checksumHeader(a), base64.StdEncoding.EncodeToString(sum))
+	}
+}
+
+func setIf(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
+
+// formatETag quotes the hexadecimal form of an ETag, as S3 does.
+func formatETag(etag []byte) string {
+	return `"` + hex.EncodeToString(etag) + `"`
+}
+
+// parseRange parses a "bytes=" Range header into the offset and length
+// arguments of absos.GetRange. It returns false for absent or malformed
+// headers and for requests of multiple ranges, which are served in full.
+func parseRange(header string) (offset, length int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		if n == 0 {
+			// An empty suffix is unsatisfiable.
+			return 0, 0, true
+		}
+		return -n, 0, true
+	}
+
+	offset, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, false
+	}
+
+	if last == "" {
+		return offset, -1, true
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < offset {
+		return 0, 0, false
+	}
+
+	return offset, end - offset + 1, true
+}
+
+// isChunked reports whether the request body uses the aws-chunked encoding
+// of streaming SigV4 uploads.
+func isChunked(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") ||
+		strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked")
+}
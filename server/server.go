@@ -0,0 +1,178 @@
+// Package server exposes any absos.ObjectStore over the S3 REST protocol, so
+// that existing S3 tools and SDKs can be pointed at it.
+//
+// Only path-style requests ("http://host/bucket/key") are supported and
+// request signatures are not verified. The server is intended for tests and
+// local development; put it behind an authenticating proxy otherwise.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/absfs/absos"
+)
+
+// Server is an http.Handler serving an absos.ObjectStore as an S3 endpoint.
+type Server struct {
+	store absos.ObjectStore
+
+	// buckets caches the buckets of the store by name, so that requests do
+	// not list all buckets to resolve theirs.
+	mu      sync.Mutex
+	buckets map[string]absos.Bucket
+}
+
+// New creates a server backed by store.
+func New(store absos.ObjectStore) *Server {
+	return &Server{store: store, buckets: make(map[string]absos.Bucket)}
+}
+
+// ServeHTTP dispatches S3 service, bucket and object requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("x-amz-request-id", requestID())
+	w.Header().Set("Server", "absos")
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+
+	switch {
+	case bucket == "":
+		s.serveService(w, r)
+	case key == "":
+		s.serveBucket(w, r, bucket)
+	default:
+		s.serveObject(w, r, bucket, key)
+	}
+}
+
+func (s *Server) serveService(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	s.listBuckets(w, r)
+}
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodPut:
+		s.createBucket(w, r, name)
+	case http.MethodDelete:
+		s.deleteBucket(w, r, name)
+	case http.MethodPost:
+		if !r.URL.Query().Has("delete") {
+			s.writeError(w, r, errNotImplemented)
+			return
+		}
+		s.deleteObjects(w, r, name)
+	case http.MethodHead:
+		if _, err := s.bucket(r.Context(), name); err != nil {
+			s.writeError(w, r, err)
+		}
+	case http.MethodGet:
+		switch q := r.URL.Query(); {
+		case q.Has("location"):
+			s.bucketLocation(w, r, name)
+		case q.Get("list-type") == "2":
+			s.listObjects(w, r, name)
+		case q.Has("uploads"):
+			s.listMultipartUploads(w, r, name)
+		default:
+			s.writeError(w, r, errNotImplemented)
+		}
+	default:
+		s.writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	b, err := s.bucket(r.Context(), bucket)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	q := r.URL.Query()
+	multipart := q.Has("uploadId")
+
+	switch {
+	case r.Method == http.MethodHead:
+		s.headObject(w, r, b, key)
+	case r.Method == http.MethodGet && multipart:
+		s.listParts(w, r, b, key)
+	case r.Method == http.MethodGet:
+		s.getObject(w, r, b, key)
+	case r.Method == http.MethodPut && multipart:
+		s.uploadPart(w, r, b, key)
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, b, key)
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		s.initiateMultipart(w, r, b, key)
+	case r.Method == http.MethodPost && multipart:
+		s.completeMultipart(w, r, b, key)
+	case r.Method == http.MethodDelete && multipart:
+		s.abortMultipart(w, r, b, key)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, r, b, key)
+	default:
+		s.writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+// bucket looks up the named bucket, listing the buckets of the store if it
+// is not cached.
+func (s *Server) bucket(ctx context.Context, name string) (absos.Bucket, error) {
+	s.mu.Lock()
+	b, ok := s.buckets[name]
+	s.mu.Unlock()
+	if ok {
+		return b, nil
+	}
+
+	buckets, err := s.store.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range buckets {
+		s.buckets[b.Name()] = b
+	}
+
+	if b, ok := s.buckets[name]; ok {
+		return b, nil
+	}
+	return nil, &absos.BucketError{Bucket: name, Err: absos.ErrBucketNotFound}
+}
+
+// forget removes the named bucket from the cache, so that the next request
+// looks it up again.
+func (s *Server) forget(name string) {
+	s.mu.Lock()
+	delete(s.buckets, name)
+	s.mu.Unlock()
+}
+
+// writeError writes the S3 error response for err. Buckets reported missing
+// are removed from the cache, in case they were deleted, or deleted and
+// created again, other than through the server.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, absos.ErrBucketNotFound) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		s.forget(name)
+	}
+	writeError(w, r, err)
+}
+
+func requestID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return strings.ToUpper(hex.EncodeToString(id[:]))
+}
@@ -0,0 +1,701 @@
+package server
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+	"github.com/absfs/absos/filestore"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// newTestClient starts a server backed by store and returns an SDK client for it.
+func newTestClient(t *testing.T, store absos.ObjectStore) (*s3.S3, string) {
+	t.Helper()
+
+	srv := httptest.NewServer(New(store))
+	t.Cleanup(srv.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(srv.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:       aws.Int(0),
+	})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	return s3.New(sess), srv.URL
+}
+
+func errorCode(err error) string {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func TestServerBuckets(t *testing.T) {
+	client, _ := newTestClient(t, memory.NewStore())
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	_, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")})
+	if code := errorCode(err); code != s3.ErrCodeBucketAlreadyOwnedByYou {
+		t.Errorf("expected BucketAlreadyOwnedByYou, got %v", err)
+	}
+
+	out, err := client.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	if len(out.Buckets) != 1 || aws.StringValue(out.Buckets[0].Name) != "test-bucket" {
+		t.Errorf("expected [test-bucket], got %v", out.Buckets)
+	}
+
+	if _, err := client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Errorf("failed to head bucket: %v", err)
+	}
+
+	if _, err := client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to delete bucket: %v", err)
+	}
+
+	_, err = client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("test-bucket")})
+	if code := errorCode(err); code != s3.ErrCodeNoSuchBucket {
+		t.Errorf("expected NoSuchBucket, got %v", err)
+	}
+}
+
+func TestServerDeleteNonEmptyBucket(t *testing.T) {
+	client, _ := newTestClient(t, memory.NewStore())
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	_, err := client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("test-key"),
+		Body:   strings.NewReader("test data"),
+	})
+	if err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	_, err = client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("test-bucket")})
+	if code := errorCode(err); code != "BucketNotEmpty" {
+		t.Errorf("expected BucketNotEmpty, got %v", err)
+	}
+}
+
+// countingStore counts the calls to ListBuckets.
+type countingStore struct {
+	absos.ObjectStore
+	lists int
+}
+
+func (s *countingStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	s.lists++
+	return s.ObjectStore.ListBuckets(ctx)
+}
+
+func TestServerBucketCache(t *testing.T) {
+	store := &countingStore{ObjectStore: memory.NewStore()}
+	client, _ := newTestClient(t, store)
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	put := func() error {
+		_, err := client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String("key"),
+			Body:   strings.NewReader("data"),
+		})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := put(); err != nil {
+			t.Fatalf("failed to put object: %v", err)
+		}
+	}
+	if store.lists != 1 {
+		t.Errorf("expected the bucket to be looked up once, got %d", store.lists)
+	}
+
+	// Buckets deleted and created again behind the server are looked up again
+	ctx := context.Background()
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+	if err := buckets[0].Delete(ctx, "key"); err != nil {
+		t.Fatalf("failed to delete object: %v", err)
+	}
+	if err := store.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to delete bucket: %v", err)
+	}
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	if code := errorCode(put()); code != s3.ErrCodeNoSuchBucket {
+		t.Errorf("expected NoSuchBucket from the stale bucket, got %v", code)
+	}
+	if err := put(); err != nil {
+		t.Errorf("failed to put object: %v", err)
+	}
+}
+
+func TestServerObjects(t *testing.T) {
+	client, _ := newTestClient(t, memory.NewStore())
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	testData := "Hello, World!"
+	_, err := client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("dir/hello world.txt"),
+		Body:   strings.NewReader(testData),
+	})
+	if err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	get, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("dir/hello world.txt"),
+	})
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	defer get.Body.Close()
+
+	content, err := io.ReadAll(get.Body)
+	if err != nil || string(content) != testData {
+		t.Errorf("expected %q, got %q (%v)", testData, content, err)
+	}
+
+	head, err := client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("dir/hello world.txt"),
+	})
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if aws.Int64Value(head.ContentLength) != int64(len(testData)) {
+		t.Errorf("expected length %d, got %d", len(testData), aws.Int64Value(head.ContentLength))
+	}
+
+	_, err = client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("missing"),
+	})
+	if code := errorCode(err); code != s3.ErrCodeNoSuchKey {
+		t.Errorf("expected NoSuchKey, got %v", err)
+	}
+
+	_, err = client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("missing-bucket"),
+		Key:    aws.String("key"),
+	})
+	if code := errorCode(err); code != s3.ErrCodeNoSuchBucket {
+		t.Errorf("expected NoSuchBucket, got %v", err)
+	}
+
+	// Deleting is idempotent, as in S3
+	for i := 0; i < 2; i++ {
+		_, err = client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String("dir/hello world.txt"),
+		})
+		if err != nil {
+			t.Fatalf("failed to delete object: %v", err)
+		}
+	}
+}
+
+func TestServerDeleteObjects(t *testing.T) {
+	client, _ := newTestClient(t, memory.NewStore())
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(key),
+		})
+		if err != nil {
+			t.Fatalf("failed to put object: %v", err)
+		}
+	}
+
+	out, err := client.DeleteObjects(&s3.DeleteObjectsInput{
+		Bucket: aws.String("test-bucket"),
+		Delete: &s3.Delete{Objects: []*s3.ObjectIdentifier{
+			{Key: aws.String("a")},
+			{Key: aws.String("c")},
+			{Key: aws.String("missing")},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to delete objects: %v", err)
+	}
+
+	// Missing keys are reported as deleted, as in S3
+	var deleted []string
+	for _, d := range out.Deleted {
+		deleted = append(deleted, aws.StringValue(d.Key))
+	}
+	if strings.Join(deleted, ",") != "a,c,missing" || len(out.Errors) != 0 {
+		t.Errorf("expected a, c and missing to be deleted, got %v and errors %v", deleted, out.Errors)
+	}
+
+	list, err := client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("test-bucket")})
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+	if len(list.Contents) != 1 || aws.StringValue(list.Contents[0].Key) != "b" {
+		t.Errorf("expected only b to remain, got %v", list.Contents)
+	}
+}
+
+func TestServerListObjects(t *testing.T) {
+	store, err := filestore.New(t.TempDir(), filestore.WithPageSize(2))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	client, _ := newTestClient(t, store)
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test-bucket")}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	keys := []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "e.txt"}
+	for _, key := range keys {
+		_, err := client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(key),
+		})
+		if err != nil {
+			t.Fatalf("failed to put object %s: %v", key, err)
+		}
+	}
+
+	var objects, prefixes []string
+	pages := 0
+	err = client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:    aws.String("test-bucket"),
+		Delimiter: aws.String("/"),
+		MaxKeys:   aws.Int64(2),
+	}, func(out *s3.ListObjectsV2Output, last bool) bool {
+		pages++
+		for _, o := range out.Contents {
+			objects = append(objects, aws.StringValue(o.Key))
+		}
+		for _, p := range out.CommonPrefixes {
+			prefixes = append(prefixes, aws.StringValue(p.Prefix))
+		}
+		return pages <= len(keys)
+	})
+	if err != nil {
+		t.Fatalf("failed to list objects: %v", err)
+	}
+
+	if pages != 2 {
+		t.Errorf("expected 2 pages, got %d", pages)
+	}
+
+	if strings.Join(objects, ",") != "a.txt,b.txt,e.txt" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	if strings.Join(prefixes, ",") != "dir/" {
+		t.Errorf("unexpected prefixes %v", prefixes)
+	}
+
+	// Pages of the store are merged and split to return max-keys entries
+	for _, maxKeys := range []int64{1, 3, 1000} {
+		var listed []string
+		input := &s3.ListObjectsV2Input{
+			Bucket:     aws.String("test-bucket"),
+			StartAfter: aws.String("a.txt"),
+			MaxKeys:    aws.Int64(maxKeys),
+		}
+		for pages := 0; ; pages++ {
+			if pages > len(keys) {
+				t.Fatal("pagination did not terminate")
+			}
+
+			out, err := client.ListObjectsV2(input)
+			if err != nil {
+				t.Fatalf("failed to list objects: %v", err)
+			}
+			if n := int64(len(out.Contents)); n > maxKeys || aws.Int64Value(out.KeyCount) != n {
+				t.Errorf("max-keys %d: got %d keys, KeyCount %d", maxKeys, n, aws.Int64Value(out.KeyCount))
+			}
+			for _, o := range out.Contents {
+				listed = append(listed, aws.StringValue(o.Key))
+			}
+
+			if !aws.BoolValue(out.IsTruncated) {
+				break
+			}
+			input.ContinuationToken = out.NextContinuationToken
+		}
+
+		if strings.Join(listed, ",") != "b.txt,dir/c.txt,dir/d.txt,e.txt" {
+			t.Errorf("max-keys %d: unexpected objects %v", maxKeys, listed)
+		}
+	}
+}
+
+func TestServerErrorResponse(t *testing.T) {
+	_, endpoint := newTestClient(t, memory.NewStore())
+
+	resp, err := http.Get(endpoint + "/missing-bucket/key")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+
+	var body errorResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+
+	if body.Code != "NoSuchBucket" || body.Resource != "/missing-bucket/key" || body.RequestID == "" {
+		t.Errorf("unexpected error response %+v", body)
+	}
+}
+
+func TestServerChunkedUpload(t *testing.T) {
+	store := memory.NewStore()
+	_, endpoint := newTestClient(t, store)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	body := "5;chunk-signature=abc\r\nhello\r\n6;chunk-signature=def\r\n world\r\n0;chunk-signature=ghi\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"
+	req, _ := http.NewRequest(http.MethodPut, endpoint+"/test-bucket/chunked", strings.NewReader(body))
+	req.Header.Set("x-amz-content-sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD")
+	req.Header.Set("x-amz-decoded-content-length", "11")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	reader, err := buckets[0].Get(ctx, "chunked")
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	defer reader.Close()
+
+	content, _ := io.ReadAll(reader)
+	if string(content) != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", content)
+	}
+}
+
+func TestServerPutHeaders(t *testing.T) {
+	store := memory.NewStore()
+	client, _ := newTestClient(t, store)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	_, err := client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:             aws.String("test-bucket"),
+		Key:                aws.String("page.html"),
+		Body:               strings.NewReader("<html></html>"),
+		ContentType:        aws.String("text/html"),
+		CacheControl:       aws.String("no-cache"),
+		ContentDisposition: aws.String("inline"),
+		Metadata:           map[string]*string{"Author": aws.String("bob")},
+		StorageClass:       aws.String(s3.StorageClassStandardIa),
+	})
+	if err != nil {
+		t.Fatalf("PutObject failed: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	header, err := buckets[0].Head(ctx, "page.html")
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+
+	if header.MimeType() != "text/html" || header.CacheControl() != "no-cache" || header.ContentDisposition() != "inline" {
+		t.Errorf("unexpected content headers %q, %q, %q", header.MimeType(), header.CacheControl(), header.ContentDisposition())
+	}
+
+	if header.Metadata()["author"] != "bob" {
+		t.Errorf("expected author metadata, got %v", header.Metadata())
+	}
+
+	if header.StorageClass() != s3.StorageClassStandardIa {
+		t.Errorf("expected storage class %s, got %s", s3.StorageClassStandardIa, header.StorageClass())
+	}
+
+	out, err := client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("page.html"),
+	})
+	if err != nil {
+		t.Fatalf("HeadObject failed: %v", err)
+	}
+
+	if aws.StringValue(out.CacheControl) != "no-cache" || aws.StringValue(out.Metadata["Author"]) != "bob" {
+		t.Errorf("unexpected HeadObject output %v", out)
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	tests := []struct {
+		header, expected string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"aws-chunked", ""},
+		{"aws-chunked,gzip", "gzip"},
+		{"gzip, aws-chunked", "gzip"},
+	}
+
+	for _, tt := range tests {
+		if actual := contentEncoding(tt.header); actual != tt.expected {
+			t.Errorf("contentEncoding(%q): expected %q, got %q", tt.header, tt.expected, actual)
+		}
+	}
+}
+
+func TestServerIncompleteBody(t *testing.T) {
+	store := memory.NewStore()
+	_, endpoint := newTestClient(t, store)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	// The second chunk is truncated
+	body := "5;chunk-signature=abc\r\nhello\r\n6;chunk-signature=def\r\n wo"
+	req, _ := http.NewRequest(http.MethodPut, endpoint+"/test-bucket/truncated", strings.NewReader(body))
+	req.Header.Set("x-amz-content-sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	if _, err := buckets[0].Head(ctx, "truncated"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Errorf("expected no object after incomplete upload, got %v", err)
+	}
+}
+
+func TestServerEncodingTypeURL(t *testing.T) {
+	store := memory.NewStore()
+	_, endpoint := newTestClient(t, store)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	if err := buckets[0].Put(ctx, "a&b c", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	resp, err := http.Get(endpoint + "/test-bucket?list-type=2&encoding-type=url")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+
+	if result.EncodingType != "url" || len(result.Contents) != 1 || result.Contents[0].Key != "a%26b+c" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestServerRange(t *testing.T) {
+	store := memory.NewStore()
+	_, endpoint := newTestClient(t, store)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	if err := buckets[0].Put(ctx, "digits", strings.NewReader("0123456789")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	tests := []struct {
+		header       string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"bytes=2-4", http.StatusPartialContent, "bytes 2-4/10", "234"},
+		{"bytes=7-", http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"bytes=-2", http.StatusPartialContent, "bytes 8-9/10", "89"},
+		{"bytes=5-100", http.StatusPartialContent, "bytes 5-9/10", "56789"},
+		{"bytes=10-", http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{"bytes=0-1,3-4", http.StatusOK, "", "0123456789"},
+		{"items=0-1", http.StatusOK, "", "0123456789"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, endpoint+"/test-bucket/digits", nil)
+		req.Header.Set("Range", tt.header)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.header, tt.status, resp.StatusCode)
+		}
+
+		if got := resp.Header.Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("%s: expected Content-Range %q, got %q", tt.header, tt.contentRange, got)
+		}
+
+		if tt.status != http.StatusRequestedRangeNotSatisfiable && string(body) != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.header, tt.body, body)
+		}
+	}
+}
+
+// racingStore replaces an object after each of the first replaces Heads of
+// it, like a writer racing with the reads of the server.
+type racingStore struct {
+	absos.ObjectStore
+	replaces atomic.Int64
+}
+
+func (s *racingStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := s.ObjectStore.ListBuckets(ctx)
+	for i, b := range buckets {
+		buckets[i] = &racingBucket{Bucket: b, s: s}
+	}
+	return buckets, err
+}
+
+type racingBucket struct {
+	absos.Bucket
+	s *racingStore
+}
+
+func (b *racingBucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	header, err := b.Bucket.Head(ctx, key)
+	if n := b.s.replaces.Add(-1); err == nil && n >= 0 {
+		err = b.Bucket.Put(ctx, key, strings.NewReader(fmt.Sprintf("replaced %d", n)))
+	}
+	return header, err
+}
+
+func TestServerGetReplaced(t *testing.T) {
+	store := &racingStore{ObjectStore: memory.NewStore()}
+	_, endpoint := newTestClient(t, store)
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	buckets, _ := store.ObjectStore.ListBuckets(ctx)
+	if err := buckets[0].Put(ctx, "key", strings.NewReader("original")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	// The object replaced after the first Head is served with its own header
+	store.replaces.Store(1)
+	resp, err := http.Get(endpoint + "/test-bucket/key")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	sum := md5.Sum(body)
+	if resp.StatusCode != http.StatusOK || string(body) != "replaced 0" {
+		t.Errorf("expected the replaced object, got %d: %q", resp.StatusCode, body)
+	}
+	if etag := resp.Header.Get("ETag"); etag != fmt.Sprintf("%q", hex.EncodeToString(sum[:])) {
+		t.Errorf("expected the ETag of the body, got %s", etag)
+	}
+
+	// An object replaced on every attempt is unavailable
+	store.replaces.Store(100)
+	req, _ := http.NewRequest(http.MethodGet, endpoint+"/test-bucket/key", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+}
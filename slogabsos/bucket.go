@@ -0,0 +1,194 @@
+package slogabsos
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/measure"
+)
+
+// Store is an absos.ObjectStore logging its operations. The buckets returned
+// by ListBuckets log their operations too.
+type Store struct {
+	store absos.ObjectStore
+	l     *logger
+}
+
+var _ absos.ObjectStore = (*Store)(nil)
+
+// New returns a Store logging the operations of store to l, or to
+// slog.Default() if l is nil.
+func New(store absos.ObjectStore, l *slog.Logger, opts ...Option) *Store {
+	return &Store{store: store, l: newLogger(l, opts)}
+}
+
+// CreateBucket creates a bucket.
+func (s *Store) CreateBucket(ctx context.Context, bucket string) error {
+	op := s.l.start(ctx, OpCreateBucket, bucket)
+	err := s.store.CreateBucket(ctx, bucket)
+	op.end(-1, err)
+	return err
+}
+
+// DeleteBucket deletes a bucket.
+func (s *Store) DeleteBucket(ctx context.Context, bucket string) error {
+	op := s.l.start(ctx, OpDeleteBucket, bucket)
+	err := s.store.DeleteBucket(ctx, bucket)
+	op.end(-1, err)
+	return err
+}
+
+// ListBuckets lists the buckets of the store. The size logged is the number
+// of buckets.
+func (s *Store) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	op := s.l.start(ctx, OpListBuckets, "")
+	buckets, err := s.store.ListBuckets(ctx)
+	op.end(int64(len(buckets)), err)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, b := range buckets {
+		buckets[i] = &Bucket{Bucket: b, l: s.l}
+	}
+	return buckets, nil
+}
+
+// Bucket is an absos.Bucket logging its operations. It implements the
+// optional bucket interfaces too, logging them under their own operation
+// names: range reads, conditional requests, streamed uploads and bulk
+// deletes fall back like the package helpers, while copies, multipart
+// uploads, presigning and versioning fail with absos.ErrNotSupported if the
+// wrapped bucket lacks them. Objects returned in pages log their operations
+// too.
+//
+// Bucket implements every optional interface, so type assertions on it do
+// not tell what the wrapped bucket supports; assert on the Bucket field
+// instead. In particular, conditional reads are atomic only if the wrapped
+// bucket is an absos.ConditionalBucket.
+type Bucket struct {
+	absos.Bucket
+	l *logger
+}
+
+// NewBucket returns a Bucket logging the operations of b to l, or to
+// slog.Default() if l is nil.
+func NewBucket(b absos.Bucket, l *slog.Logger, opts ...Option) *Bucket {
+	return &Bucket{Bucket: b, l: newLogger(l, opts)}
+}
+
+// ObjectPage lists a page of objects. The size logged is the number of
+// objects and prefixes in the page.
+func (b *Bucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (absos.Page, error) {
+	op := b.l.start(ctx, OpObjectPage, b.Name(), slog.String("prefix", b.l.redacted(prefix)))
+	page, err := b.Bucket.ObjectPage(ctx, prefix, delimiter, token)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	op.end(int64(len(page.Objects())+len(page.Prefixes())), nil)
+	return &loggedPage{Page: page, l: b.l}, nil
+}
+
+// Head retrieves the metadata of an object.
+func (b *Bucket) Head(ctx context.Context, key string) (absos.ObjectHeader, error) {
+	op := b.l.start(ctx, OpHead, b.Name(), b.l.key(key))
+	header, err := b.Bucket.Head(ctx, key)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	op.end(header.Size(), nil)
+	return header, nil
+}
+
+// PutBatch uploads the objects of iter.
+func (b *Bucket) PutBatch(ctx context.Context, iter absos.BatchIterator) error {
+	op := b.l.start(ctx, OpPutBatch, b.Name())
+	err := b.Bucket.PutBatch(ctx, iter)
+	op.end(-1, err)
+	return err
+}
+
+// Put uploads an object. The size logged is the remaining length of data,
+// determined by seeking.
+func (b *Bucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	op := b.l.start(ctx, OpPut, b.Name(), b.l.key(key))
+
+	size, err := measure.Remaining(data)
+	if err != nil {
+		err = &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	} else {
+		err = b.Bucket.Put(ctx, key, data, opts...)
+	}
+
+	op.end(size, err)
+	return err
+}
+
+// Get opens an object. It is logged when the reader is closed, with the
+// number of bytes read.
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	op := b.l.start(ctx, OpGet, b.Name(), b.l.key(key))
+	r, err := b.Bucket.Get(ctx, key)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// Delete removes an object.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	op := b.l.start(ctx, OpDelete, b.Name(), b.l.key(key))
+	err := b.Bucket.Delete(ctx, key)
+	op.end(-1, err)
+	return err
+}
+
+// loggedPage logs the operations of the objects of a page.
+type loggedPage struct {
+	absos.Page
+	l *logger
+}
+
+func (p *loggedPage) Objects() []absos.Object {
+	objects := p.Page.Objects()
+	wrapped := make([]absos.Object, len(objects))
+	for i, obj := range objects {
+		wrapped[i] = &object{Object: obj, l: p.l}
+	}
+	return wrapped
+}
+
+// object is an object of a page logging its operations.
+type object struct {
+	absos.Object
+	l *logger
+}
+
+func (o *object) Head(ctx context.Context) (absos.ObjectHeader, error) {
+	op := o.l.start(ctx, OpHead, o.Bucket(), o.l.key(o.Key()))
+	header, err := o.Object.Head(ctx)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	op.end(header.Size(), nil)
+	return header, nil
+}
+
+func (o *object) Open(ctx context.Context) (io.ReadCloser, error) {
+	op := o.l.start(ctx, OpOpen, o.Bucket(), o.l.key(o.Key()))
+	r, err := o.Object.Open(ctx)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
@@ -0,0 +1,361 @@
+package slogabsos
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/internal/capability"
+	"github.com/absfs/absos/internal/measure"
+)
+
+// Names of the logged operations of the optional bucket interfaces.
+const (
+	OpGetRange             = "GetRange"
+	OpOpenRange            = "OpenRange"
+	OpPutIf                = "PutIf"
+	OpGetIf                = "GetIf"
+	OpHeadIf               = "HeadIf"
+	OpDeleteIf             = "DeleteIf"
+	OpPutStream            = "PutStream"
+	OpCopy                 = "Copy"
+	OpDeleteMany           = "DeleteMany"
+	OpPresign              = "Presign"
+	OpInitiateMultipart    = "InitiateMultipart"
+	OpUploadPart           = "UploadPart"
+	OpListParts            = "ListParts"
+	OpCompleteMultipart    = "CompleteMultipart"
+	OpAbortMultipart       = "AbortMultipart"
+	OpListMultipartUploads = "ListMultipartUploads"
+	OpVersioning           = "Versioning"
+	OpSetVersioning        = "SetVersioning"
+	OpListVersions         = "ListVersions"
+	OpGetVersion           = "GetVersion"
+	OpHeadVersion          = "HeadVersion"
+	OpDeleteVersion        = "DeleteVersion"
+)
+
+var (
+	_ absos.RangeBucket       = (*Bucket)(nil)
+	_ absos.ConditionalBucket = (*Bucket)(nil)
+	_ absos.StreamBucket      = (*Bucket)(nil)
+	_ absos.CopyBucket        = (*Bucket)(nil)
+	_ absos.MultipartBucket   = (*Bucket)(nil)
+	_ absos.VersionedBucket   = (*Bucket)(nil)
+	_ absos.BulkDeleteBucket  = (*Bucket)(nil)
+	_ absos.Presigner         = (*Bucket)(nil)
+	_ absos.RangeObject       = (*object)(nil)
+)
+
+// GetRange opens part of an object, reading the whole object if the wrapped
+// bucket cannot read ranges. It is logged when the reader is closed, with
+// the number of bytes read.
+func (b *Bucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	op := b.l.start(ctx, OpGetRange, b.Name(), b.l.key(key), slog.Int64("offset", offset), slog.Int64("length", length))
+	r, err := absos.GetRange(ctx, b.Bucket, key, offset, length)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// PutIf uploads an object if cond holds. It fails with
+// absos.ErrNotSupported if the wrapped bucket does not support conditional
+// writes.
+func (b *Bucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond absos.Conditions, opts ...absos.PutOption) error {
+	op := b.l.start(ctx, OpPutIf, b.Name(), b.l.key(key))
+
+	size, err := measure.Remaining(data)
+	if err != nil {
+		err = &absos.ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	} else {
+		err = absos.PutIf(ctx, b.Bucket, key, data, cond, opts...)
+	}
+
+	op.end(size, err)
+	return err
+}
+
+// GetIf opens an object if cond holds. It is logged when the reader is
+// closed, with the number of bytes read.
+func (b *Bucket) GetIf(ctx context.Context, key string, cond absos.Conditions) (io.ReadCloser, error) {
+	op := b.l.start(ctx, OpGetIf, b.Name(), b.l.key(key))
+	r, err := absos.GetIf(ctx, b.Bucket, key, cond)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// HeadIf retrieves the metadata of an object if cond holds.
+func (b *Bucket) HeadIf(ctx context.Context, key string, cond absos.Conditions) (absos.ObjectHeader, error) {
+	op := b.l.start(ctx, OpHeadIf, b.Name(), b.l.key(key))
+	header, err := absos.HeadIf(ctx, b.Bucket, key, cond)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	op.end(header.Size(), nil)
+	return header, nil
+}
+
+// DeleteIf removes an object if cond holds. It fails with
+// absos.ErrNotSupported if the wrapped bucket does not support conditional
+// writes.
+func (b *Bucket) DeleteIf(ctx context.Context, key string, cond absos.Conditions) error {
+	op := b.l.start(ctx, OpDeleteIf, b.Name(), b.l.key(key))
+	err := absos.DeleteIf(ctx, b.Bucket, key, cond)
+	op.end(-1, err)
+	return err
+}
+
+// PutStream uploads an object read from data until io.EOF with
+// absos.Upload. The size logged is the number of bytes read from data.
+func (b *Bucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...absos.PutOption) error {
+	op := b.l.start(ctx, OpPutStream, b.Name(), b.l.key(key))
+	c := &measure.Counter{R: data}
+	err := absos.Upload(ctx, b.Bucket, key, c, opts...)
+	op.end(c.N, err)
+	return err
+}
+
+// Copy copies an object within the wrapped bucket's provider. It fails with
+// absos.ErrNotSupported if the wrapped bucket cannot copy from src, so that
+// absos.Copy falls back to reading and uploading the object.
+func (b *Bucket) Copy(ctx context.Context, src absos.Bucket, srcKey, dstKey string, opts ...absos.CopyOption) error {
+	if s, ok := src.(*Bucket); ok {
+		src = s.Bucket
+	}
+
+	op := b.l.start(ctx, OpCopy, b.Name(), b.l.key(dstKey),
+		slog.String("source_bucket", src.Name()), slog.String("source_key", b.l.redacted(srcKey)))
+
+	cb, err := capability.As[absos.CopyBucket](b.Bucket, dstKey)
+	if err == nil {
+		err = cb.Copy(ctx, src, srcKey, dstKey, opts...)
+	}
+
+	op.end(-1, err)
+	return err
+}
+
+// DeleteLimit returns the number of keys the wrapped bucket deletes at
+// once, or 1 if it deletes them one by one.
+func (b *Bucket) DeleteLimit() int {
+	return capability.DeleteLimit(b.Bucket)
+}
+
+// DeleteMany deletes the objects with the given keys. The size logged is the
+// number of keys.
+func (b *Bucket) DeleteMany(ctx context.Context, keys []string) error {
+	op := b.l.start(ctx, OpDeleteMany, b.Name())
+	err := absos.DeleteMany(ctx, b.Bucket, keys)
+	op.end(int64(len(keys)), err)
+	return err
+}
+
+// Presign returns a URL for method on an object. It fails with
+// absos.ErrNotSupported if the wrapped bucket cannot presign URLs. The URL
+// grants access to the object, so it is not logged.
+func (b *Bucket) Presign(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	op := b.l.start(ctx, OpPresign, b.Name(), b.l.key(key), slog.String("method", method), slog.Duration("expires", expires))
+	p, err := capability.As[absos.Presigner](b.Bucket, key)
+	var url string
+	if err == nil {
+		url, err = p.Presign(ctx, method, key, expires)
+	}
+	op.end(-1, err)
+	return url, err
+}
+
+// InitiateMultipart starts a multipart upload. The multipart operations fail
+// with absos.ErrNotSupported if the wrapped bucket does not support them.
+func (b *Bucket) InitiateMultipart(ctx context.Context, key string, opts ...absos.PutOption) (absos.MultipartUpload, error) {
+	op := b.l.start(ctx, OpInitiateMultipart, b.Name(), b.l.key(key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, key)
+	var upload absos.MultipartUpload
+	if err == nil {
+		upload, err = mb.InitiateMultipart(ctx, key, opts...)
+	}
+	op.end(-1, err)
+	return upload, err
+}
+
+// UploadPart uploads a part of a multipart upload. The size logged is the
+// remaining length of data, determined by seeking.
+func (b *Bucket) UploadPart(ctx context.Context, upload absos.MultipartUpload, number int, data io.ReadSeeker) (absos.Part, error) {
+	op := b.l.start(ctx, OpUploadPart, b.Name(), b.l.key(upload.Key), slog.Int("part", number))
+
+	var part absos.Part
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	size := int64(-1)
+	if err == nil {
+		if size, err = measure.Remaining(data); err != nil {
+			err = &absos.ObjectError{Bucket: b.Name(), Key: upload.Key, Err: err}
+		} else {
+			part, err = mb.UploadPart(ctx, upload, number, data)
+		}
+	}
+
+	op.end(size, err)
+	return part, err
+}
+
+// ListParts lists the parts of a multipart upload. The size logged is the
+// number of parts.
+func (b *Bucket) ListParts(ctx context.Context, upload absos.MultipartUpload) ([]absos.Part, error) {
+	op := b.l.start(ctx, OpListParts, b.Name(), b.l.key(upload.Key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	var parts []absos.Part
+	if err == nil {
+		parts, err = mb.ListParts(ctx, upload)
+	}
+	op.end(int64(len(parts)), err)
+	return parts, err
+}
+
+// CompleteMultipart assembles the parts of a multipart upload.
+func (b *Bucket) CompleteMultipart(ctx context.Context, upload absos.MultipartUpload, parts []absos.Part) error {
+	op := b.l.start(ctx, OpCompleteMultipart, b.Name(), b.l.key(upload.Key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err == nil {
+		err = mb.CompleteMultipart(ctx, upload, parts)
+	}
+	op.end(-1, err)
+	return err
+}
+
+// AbortMultipart aborts a multipart upload.
+func (b *Bucket) AbortMultipart(ctx context.Context, upload absos.MultipartUpload) error {
+	op := b.l.start(ctx, OpAbortMultipart, b.Name(), b.l.key(upload.Key))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, upload.Key)
+	if err == nil {
+		err = mb.AbortMultipart(ctx, upload)
+	}
+	op.end(-1, err)
+	return err
+}
+
+// ListMultipartUploads lists the multipart uploads in progress. The size
+// logged is the number of uploads.
+func (b *Bucket) ListMultipartUploads(ctx context.Context, prefix string) ([]absos.MultipartUpload, error) {
+	op := b.l.start(ctx, OpListMultipartUploads, b.Name(), slog.String("prefix", b.l.redacted(prefix)))
+	mb, err := capability.As[absos.MultipartBucket](b.Bucket, "")
+	var uploads []absos.MultipartUpload
+	if err == nil {
+		uploads, err = mb.ListMultipartUploads(ctx, prefix)
+	}
+	op.end(int64(len(uploads)), err)
+	return uploads, err
+}
+
+// Versioning returns the versioning state of the bucket. The versioning
+// operations fail with absos.ErrNotSupported if the wrapped bucket does not
+// support them.
+func (b *Bucket) Versioning(ctx context.Context) (absos.VersioningStatus, error) {
+	op := b.l.start(ctx, OpVersioning, b.Name())
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	var status absos.VersioningStatus
+	if err == nil {
+		status, err = vb.Versioning(ctx)
+	}
+	op.end(-1, err)
+	return status, err
+}
+
+// SetVersioning enables or suspends versioning.
+func (b *Bucket) SetVersioning(ctx context.Context, status absos.VersioningStatus) error {
+	op := b.l.start(ctx, OpSetVersioning, b.Name(), slog.String("status", string(status)))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err == nil {
+		err = vb.SetVersioning(ctx, status)
+	}
+	op.end(-1, err)
+	return err
+}
+
+// ListVersions lists a page of versions. The size logged is the number of
+// versions in the page.
+func (b *Bucket) ListVersions(ctx context.Context, prefix, token string) (absos.VersionPage, error) {
+	op := b.l.start(ctx, OpListVersions, b.Name(), slog.String("prefix", b.l.redacted(prefix)))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, "")
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	page, err := vb.ListVersions(ctx, prefix, token)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	op.end(int64(len(page.Versions())), nil)
+	return page, nil
+}
+
+// GetVersion opens a version of an object. It is logged when the reader is
+// closed, with the number of bytes read.
+func (b *Bucket) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	op := b.l.start(ctx, OpGetVersion, b.Name(), b.l.key(key), slog.String("version", versionID))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	r, err := vb.GetVersion(ctx, key, versionID)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
+
+// HeadVersion retrieves the metadata of a version of an object.
+func (b *Bucket) HeadVersion(ctx context.Context, key, versionID string) (absos.ObjectHeader, error) {
+	op := b.l.start(ctx, OpHeadVersion, b.Name(), b.l.key(key), slog.String("version", versionID))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	header, err := vb.HeadVersion(ctx, key, versionID)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+
+	op.end(header.Size(), nil)
+	return header, nil
+}
+
+// DeleteVersion permanently removes a version or delete marker.
+func (b *Bucket) DeleteVersion(ctx context.Context, key, versionID string) error {
+	op := b.l.start(ctx, OpDeleteVersion, b.Name(), b.l.key(key), slog.String("version", versionID))
+	vb, err := capability.As[absos.VersionedBucket](b.Bucket, key)
+	if err == nil {
+		err = vb.DeleteVersion(ctx, key, versionID)
+	}
+	op.end(-1, err)
+	return err
+}
+
+// OpenRange opens part of the object, reading the whole object if it cannot
+// read ranges. It is logged when the reader is closed, with the number of
+// bytes read.
+func (o *object) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	op := o.l.start(ctx, OpOpenRange, o.Bucket(), o.l.key(o.Key()), slog.Int64("offset", offset), slog.Int64("length", length))
+	r, err := absos.OpenRange(ctx, o.Object, offset, length)
+	if err != nil {
+		op.end(-1, err)
+		return nil, err
+	}
+	return op.reader(r), nil
+}
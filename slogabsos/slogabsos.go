@@ -0,0 +1,190 @@
+// Package slogabsos logs the operations of object stores and buckets with
+// log/slog, whatever their backend:
+//
+//	store := slogabsos.New(s3.New(client), logger,
+//		slogabsos.WithLevel(slogabsos.OpPut, slog.LevelInfo),
+//		slogabsos.WithSampling(slogabsos.OpGet, 100),
+//		slogabsos.WithRedact(path.Dir))
+//
+// Each operation is logged once it completes with the attributes op, bucket,
+// key, size, duration and, for failures, error. Operations returning a
+// reader, such as Get, GetRange and Open, are logged when the reader is
+// closed, with the number of bytes read.
+//
+// Successful operations are logged at slog.LevelDebug unless configured
+// otherwise, and failures at slog.LevelError.
+package slogabsos
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/absfs/absos/internal/measure"
+)
+
+// Names of the logged operations.
+const (
+	OpCreateBucket = "CreateBucket"
+	OpDeleteBucket = "DeleteBucket"
+	OpListBuckets  = "ListBuckets"
+	OpObjectPage   = "ObjectPage"
+	OpHead         = "Head"
+	OpPutBatch     = "PutBatch"
+	OpPut          = "Put"
+	OpGet          = "Get"
+	OpDelete       = "Delete"
+	OpOpen         = "Open"
+)
+
+// Option configures the logging.
+type Option func(*config)
+
+// WithLevel sets the level at which successful calls of op are logged.
+func WithLevel(op string, level slog.Leveler) Option {
+	return func(c *config) {
+		c.levels[op] = level
+	}
+}
+
+// WithErrorLevel sets the level at which failed operations are logged.
+func WithErrorLevel(level slog.Leveler) Option {
+	return func(c *config) {
+		c.errorLevel = level
+	}
+}
+
+// WithRedact sets a function applied to keys and prefixes before they are
+// logged, for example to hide personal data embedded in keys.
+func WithRedact(redact func(key string) string) Option {
+	return func(c *config) {
+		c.redact = redact
+	}
+}
+
+// WithSampling logs only one of every n successful calls of op, such as
+// high-volume Get or Head traffic. Failures are always logged.
+func WithSampling(op string, n int) Option {
+	return func(c *config) {
+		if n > 1 {
+			c.samplers[op] = &sampler{n: uint64(n)}
+		}
+	}
+}
+
+type config struct {
+	levels     map[string]slog.Leveler
+	errorLevel slog.Leveler
+	redact     func(string) string
+	samplers   map[string]*sampler
+}
+
+// sampler selects one of every n calls.
+type sampler struct {
+	n     uint64
+	calls atomic.Uint64
+}
+
+func (s *sampler) sample() bool {
+	return (s.calls.Add(1)-1)%s.n == 0
+}
+
+// logger logs operations for a Store and its buckets.
+type logger struct {
+	log *slog.Logger
+	config
+}
+
+func newLogger(l *slog.Logger, opts []Option) *logger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	lg := &logger{log: l, config: config{
+		levels:     make(map[string]slog.Leveler),
+		errorLevel: slog.LevelError,
+		samplers:   make(map[string]*sampler),
+	}}
+	for _, opt := range opts {
+		opt(&lg.config)
+	}
+	return lg
+}
+
+// operation is a logged operation in progress.
+type operation struct {
+	l      *logger
+	ctx    context.Context
+	name   string
+	attrs  []slog.Attr
+	start  time.Time
+	logged bool
+}
+
+// start begins logging the operation name on bucket, which is empty for
+// operations on the store.
+func (l *logger) start(ctx context.Context, name, bucket string, attrs ...slog.Attr) *operation {
+	op := &operation{l: l, ctx: ctx, name: name, start: time.Now()}
+
+	op.attrs = append(op.attrs, slog.String("op", name))
+	if bucket != "" {
+		op.attrs = append(op.attrs, slog.String("bucket", bucket))
+	}
+	op.attrs = append(op.attrs, attrs...)
+
+	return op
+}
+
+// key returns the attribute of key, redacted if configured.
+func (l *logger) key(key string) slog.Attr {
+	return slog.String("key", l.redacted(key))
+}
+
+func (l *logger) redacted(key string) string {
+	if l.redact != nil {
+		return l.redact(key)
+	}
+	return key
+}
+
+// end logs the operation with its size, if known, and its outcome.
+func (o *operation) end(size int64, err error) {
+	if o.logged {
+		return
+	}
+	o.logged = true
+
+	level := slog.LevelDebug
+	switch {
+	case err != nil:
+		level = o.l.errorLevel.Level()
+	case o.l.levels[o.name] != nil:
+		level = o.l.levels[o.name].Level()
+	}
+
+	if !o.l.log.Enabled(o.ctx, level) {
+		return
+	}
+	if s := o.l.samplers[o.name]; err == nil && s != nil && !s.sample() {
+		return
+	}
+
+	attrs := o.attrs
+	if size >= 0 {
+		attrs = append(attrs, slog.Int64("size", size))
+	}
+	attrs = append(attrs, slog.Duration("duration", time.Since(o.start)))
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	o.l.log.LogAttrs(o.ctx, level, "absos "+o.name, attrs...)
+}
+
+// reader returns r counting the bytes read from an object, which logs the
+// operation that opened it when closed.
+func (o *operation) reader(r io.ReadCloser) io.ReadCloser {
+	return measure.NewReader(r, o.end)
+}
@@ -0,0 +1,229 @@
+package slogabsos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// newTestBucket returns a logging bucket of a memory store and the buffer
+// its JSON log is written to.
+func newTestBucket(t *testing.T, opts ...Option) (absos.Bucket, *bytes.Buffer) {
+	t.Helper()
+
+	ctx := context.Background()
+	store := memory.NewStore()
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	buckets, err := New(store, l, opts...).ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+	buf.Reset()
+
+	return buckets[0], &buf
+}
+
+// records decodes the log records written to buf and resets it.
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var recs []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("failed to decode log: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+	buf.Reset()
+
+	return recs
+}
+
+func TestLog(t *testing.T) {
+	b, buf := newTestBucket(t, WithLevel(OpPut, slog.LevelInfo))
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "key", strings.NewReader("hello")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	recs := records(t, buf)
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(recs))
+	}
+	rec := recs[0]
+	if rec["level"] != "INFO" || rec["op"] != "Put" || rec["bucket"] != "test-bucket" || rec["key"] != "key" || rec["size"] != 5.0 {
+		t.Errorf("unexpected Put record %v", rec)
+	}
+	if _, ok := rec["duration"]; !ok {
+		t.Error("expected a duration")
+	}
+
+	r, err := b.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if len(records(t, buf)) != 0 {
+		t.Error("expected Get to be logged when the reader is closed")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	recs = records(t, buf)
+	if len(recs) != 1 || recs[0]["level"] != "DEBUG" || recs[0]["op"] != "Get" || recs[0]["size"] != 5.0 {
+		t.Errorf("unexpected Get records %v", recs)
+	}
+}
+
+func TestLogError(t *testing.T) {
+	b, buf := newTestBucket(t, WithErrorLevel(slog.LevelWarn))
+
+	if _, err := b.Head(context.Background(), "missing"); !errors.Is(err, absos.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	recs := records(t, buf)
+	if len(recs) != 1 || recs[0]["level"] != "WARN" || !strings.Contains(recs[0]["error"].(string), "object not found") {
+		t.Errorf("unexpected records %v", recs)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	b, buf := newTestBucket(t, WithRedact(func(key string) string {
+		return strings.Repeat("*", len(key))
+	}))
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "users/alice", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	records(t, buf)
+
+	if err := b.Delete(ctx, "users/alice"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := b.ObjectPage(ctx, "users/", "", ""); err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	recs := records(t, buf)
+	if len(recs) != 2 || recs[0]["key"] != "***********" || recs[1]["prefix"] != "******" {
+		t.Errorf("expected redacted keys, got %v", recs)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	b, buf := newTestBucket(t, WithSampling(OpHead, 3))
+	ctx := context.Background()
+
+	if err := b.Put(ctx, "key", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	records(t, buf)
+
+	for i := 0; i < 6; i++ {
+		if _, err := b.Head(ctx, "key"); err != nil {
+			t.Fatalf("failed to head: %v", err)
+		}
+	}
+	if n := len(records(t, buf)); n != 2 {
+		t.Errorf("expected 2 of 6 Heads logged, got %d", n)
+	}
+
+	// Failures are always logged
+	for i := 0; i < 3; i++ {
+		_, _ = b.Head(ctx, "missing")
+	}
+	if n := len(records(t, buf)); n != 3 {
+		t.Errorf("expected 3 failed Heads logged, got %d", n)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	b, buf := newTestBucket(t)
+	ctx := context.Background()
+
+	cond := absos.Conditions{IfNotExists: true}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("hello"), cond); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := absos.PutIf(ctx, b, "key", strings.NewReader("again"), cond); !errors.Is(err, absos.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	recs := records(t, buf)
+	if len(recs) != 2 || recs[0]["op"] != "PutIf" || recs[0]["size"] != 5.0 || recs[1]["error"] == nil {
+		t.Errorf("unexpected PutIf records %v", recs)
+	}
+
+	r, err := absos.GetRange(ctx, b, "key", 1, 3)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "ell" {
+		t.Errorf("expected ell, got %q", data)
+	}
+
+	recs = records(t, buf)
+	if len(recs) != 1 || recs[0]["op"] != "GetRange" || recs[0]["offset"] != 1.0 || recs[0]["size"] != 3.0 {
+		t.Errorf("unexpected GetRange records %v", recs)
+	}
+
+	if err := absos.Upload(ctx, b, "stream", strings.NewReader("streamed")); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	recs = records(t, buf)
+	if len(recs) != 1 || recs[0]["op"] != "PutStream" || recs[0]["size"] != 8.0 {
+		t.Errorf("unexpected PutStream records %v", recs)
+	}
+
+	if err := absos.DeleteMany(ctx, b, []string{"key", "stream"}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	recs = records(t, buf)
+	if len(recs) != 1 || recs[0]["op"] != "DeleteMany" || recs[0]["size"] != 2.0 {
+		t.Errorf("unexpected DeleteMany records %v", recs)
+	}
+
+	// The memory bucket cannot presign URLs
+	if _, err := b.(*Bucket).Presign(ctx, "GET", "key", time.Minute); !errors.Is(err, absos.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+
+	recs = records(t, buf)
+	if len(recs) != 1 || recs[0]["op"] != "Presign" || recs[0]["method"] != "GET" || recs[0]["error"] == nil {
+		t.Errorf("unexpected Presign records %v", recs)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return New(memory.NewStore(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+}
@@ -0,0 +1,124 @@
+package absos
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+)
+
+// StreamBucket is implemented by buckets that can upload an object from a
+// reader of unknown length without buffering it whole, for example by
+// splitting it into multipart parts.
+//
+// PutStream must not make a partial object visible: if reading data fails
+// or ctx is canceled, the upload is aborted and any previous object with
+// the same key is left unchanged.
+type StreamBucket interface {
+	Bucket
+
+	// PutStream uploads an object with the specified key from data, which
+	// is read until io.EOF.
+	PutStream(ctx context.Context, key string, data io.Reader, opts ...PutOption) error
+}
+
+// spoolMemory is the amount of data Upload buffers in memory before it
+// spools the rest of the stream to a temporary file.
+const spoolMemory = 1 << 20
+
+// Upload stores the contents of data under key, reading it until io.EOF.
+// It uses the bucket's StreamBucket implementation when available, and
+// otherwise uploads parts of at least MinPartSize bytes to a MultipartBucket.
+// Streams to other buckets are buffered, in a temporary file once they
+// exceed 1 MiB, and uploaded with Put. A read error aborts the upload
+// without creating or modifying the object.
+func Upload(ctx context.Context, b Bucket, key string, data io.Reader, opts ...PutOption) error {
+	if sb, ok := b.(StreamBucket); ok {
+		return sb.PutStream(ctx, key, data, opts...)
+	}
+
+	if mb, ok := b.(MultipartBucket); ok {
+		return uploadMultipart(ctx, mb, key, data, opts...)
+	}
+
+	var buf bytes.Buffer
+	_, err := io.CopyN(&buf, data, spoolMemory+1)
+	if err == io.EOF {
+		return b.Put(ctx, key, bytes.NewReader(buf.Bytes()), opts...)
+	}
+	if err != nil {
+		return &ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	f, err := os.CreateTemp("", "absos-upload-*")
+	if err != nil {
+		return &ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = io.Copy(f, io.MultiReader(&buf, data))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return &ObjectError{Bucket: b.Name(), Key: key, Err: err}
+	}
+
+	return b.Put(ctx, key, f, opts...)
+}
+
+// Writer uploads the data written to it as a single object. The object is
+// committed by Close; CloseWithError aborts the upload instead, so a
+// partially written object never becomes visible.
+//
+// A Writer is not safe for concurrent use.
+type Writer struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+// NewWriter returns a Writer that streams its data to key in b with Upload.
+// The upload runs until the Writer is closed or ctx is canceled.
+func NewWriter(ctx context.Context, b Bucket, key string, opts ...PutOption) *Writer {
+	pr, pw := io.Pipe()
+	w := &Writer{pw: pw, done: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+		w.err = Upload(ctx, b, key, pr, opts...)
+
+		// Unblock writers if the upload ended before reading everything
+		_ = pr.CloseWithError(w.err)
+	}()
+
+	return w
+}
+
+// Write implements io.Writer. It blocks until the upload has consumed p,
+// and returns the upload's error if the upload has failed.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close completes the upload and waits for it to be committed. It returns
+// the error of the upload, if any.
+func (w *Writer) Close() error {
+	_ = w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// CloseWithError aborts the upload with err, or with ErrUploadAborted if
+// err is nil, and waits for it to stop. The object is left unchanged.
+// It always returns nil.
+func (w *Writer) CloseWithError(err error) error {
+	if err == nil {
+		err = ErrUploadAborted
+	}
+
+	_ = w.pw.CloseWithError(err)
+	<-w.done
+	return nil
+}
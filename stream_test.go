@@ -0,0 +1,176 @@
+package absos_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+)
+
+func newStreamBucket(t *testing.T) absos.Bucket {
+	t.Helper()
+
+	store := memory.NewStore()
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	return buckets[0]
+}
+
+func readObject(t *testing.T, b absos.Bucket, key string) []byte {
+	t.Helper()
+
+	reader, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", key, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", key, err)
+	}
+	return content
+}
+
+// errorAfter returns the data of r followed by err.
+func errorAfter(r io.Reader, err error) io.Reader {
+	return io.MultiReader(r, &errReader{err})
+}
+
+type errReader struct{ err error }
+
+func (r *errReader) Read(p []byte) (int, error) { return 0, r.err }
+
+func TestUploadFallback(t *testing.T) {
+	ctx := context.Background()
+	bucket := plainBucket{newStreamBucket(t)}
+
+	if _, ok := absos.Bucket(bucket).(absos.StreamBucket); ok {
+		t.Fatal("expected bucket without stream support")
+	}
+
+	small := []byte("hello")
+	large := bytes.Repeat([]byte("0123456789abcdef"), 1<<17) // 2 MiB, spooled to disk
+
+	for name, data := range map[string][]byte{"small": small, "large": large} {
+		// Hide the Seek method of the reader
+		err := absos.Upload(ctx, bucket, name, io.MultiReader(bytes.NewReader(data)), absos.WithContentType("text/plain"))
+		if err != nil {
+			t.Fatalf("failed to upload %s: %v", name, err)
+		}
+
+		if content := readObject(t, bucket, name); !bytes.Equal(content, data) {
+			t.Errorf("%s: expected %d bytes, got %d", name, len(data), len(content))
+		}
+
+		header, _ := bucket.Head(ctx, name)
+		if header.MimeType() != "text/plain" {
+			t.Errorf("%s: expected MIME type text/plain, got %s", name, header.MimeType())
+		}
+	}
+}
+
+func TestUploadReadError(t *testing.T) {
+	ctx := context.Background()
+	errBroken := errors.New("broken stream")
+
+	buckets := map[string]absos.Bucket{
+		"stream":   newStreamBucket(t),
+		"fallback": plainBucket{newStreamBucket(t)},
+	}
+
+	for name, bucket := range buckets {
+		err := absos.Upload(ctx, bucket, "key", errorAfter(strings.NewReader("partial"), errBroken))
+		if !errors.Is(err, errBroken) {
+			t.Errorf("%s: expected error %v, got %v", name, errBroken, err)
+		}
+
+		if _, err := bucket.Head(ctx, "key"); !errors.Is(err, absos.ErrObjectNotFound) {
+			t.Errorf("%s: expected no object after failed upload, got %v", name, err)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	ctx := context.Background()
+	bucket := newStreamBucket(t)
+
+	w := absos.NewWriter(ctx, bucket, "greeting", absos.WithContentType("text/plain"))
+	for _, s := range []string{"Hello", ", ", "World!"} {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	if content := readObject(t, bucket, "greeting"); string(content) != "Hello, World!" {
+		t.Errorf("expected %q, got %q", "Hello, World!", content)
+	}
+}
+
+func TestWriterAbort(t *testing.T) {
+	ctx := context.Background()
+	bucket := newStreamBucket(t)
+
+	if err := bucket.Put(ctx, "key", strings.NewReader("original")); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	w := absos.NewWriter(ctx, bucket, "key")
+	if _, err := io.WriteString(w, "replacement"); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if err := w.CloseWithError(nil); err != nil {
+		t.Fatalf("failed to abort writer: %v", err)
+	}
+
+	// The previous object is left unchanged
+	if content := readObject(t, bucket, "key"); string(content) != "original" {
+		t.Errorf("expected %q, got %q", "original", content)
+	}
+}
+
+func TestWriterUploadError(t *testing.T) {
+	ctx := context.Background()
+	bucket := &failingPutBucket{newStreamBucket(t)}
+
+	w := absos.NewWriter(ctx, bucket, "key")
+	if _, err := io.WriteString(w, "data"); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if err := w.Close(); !errors.Is(err, errPut) {
+		t.Errorf("expected close to fail with %v, got %v", errPut, err)
+	}
+
+	// Writes after Close fail
+	if _, err := io.WriteString(w, "more"); err == nil {
+		t.Error("expected write after close to fail")
+	}
+}
+
+var errPut = errors.New("put failed")
+
+// failingPutBucket hides the stream support of the wrapped bucket and fails
+// every Put.
+type failingPutBucket struct {
+	absos.Bucket
+}
+
+func (b *failingPutBucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...absos.PutOption) error {
+	return errPut
+}
@@ -0,0 +1,310 @@
+package absos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sub returns a view of the objects of b under prefix, such as the objects
+// of one tenant of a shared bucket. Keys passed to the view are relative to
+// prefix, which is prepended to them before they reach b, and keys returned
+// by its listings and headers have the prefix removed.
+//
+// A slash is appended to prefix unless it ends with one. Keys containing a
+// ".." segment, which could escape the prefix on providers resolving paths,
+// are rejected with ErrInvalidKey, as are prefixes containing one.
+//
+// The view implements RangeBucket, ConditionalBucket, StreamBucket and
+// BulkDeleteBucket on top of the package-level helpers, so capabilities of b
+// remain in use. It implements CopyBucket too, failing with ErrNotSupported
+// if b cannot copy. Errors of type *ObjectError report the key relative to
+// prefix.
+func Sub(b Bucket, prefix string) (Bucket, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if prefix == "/" || escapes(prefix) {
+		return nil, &ObjectError{Bucket: b.Name(), Key: prefix, Err: fmt.Errorf("%w: invalid prefix", ErrInvalidKey)}
+	}
+
+	return &subBucket{Bucket: b, prefix: prefix}, nil
+}
+
+// escapes reports whether key contains a ".." segment.
+func escapes(key string) bool {
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// subBucket is the view of a bucket returned by Sub.
+type subBucket struct {
+	Bucket
+	prefix string
+}
+
+var (
+	_ RangeBucket       = (*subBucket)(nil)
+	_ ConditionalBucket = (*subBucket)(nil)
+	_ StreamBucket      = (*subBucket)(nil)
+	_ BulkDeleteBucket  = (*subBucket)(nil)
+	_ CopyBucket        = (*subBucket)(nil)
+)
+
+// key returns the key of b holding the object key of the view.
+func (s *subBucket) key(key string) (string, error) {
+	if key == "" || escapes(key) {
+		return "", &ObjectError{Bucket: s.Name(), Key: key, Err: ErrInvalidKey}
+	}
+	return s.prefix + key, nil
+}
+
+// err returns err with the key of an *ObjectError made relative to the
+// prefix.
+func (s *subBucket) err(err error) error {
+	var oe *ObjectError
+	if errors.As(err, &oe) && oe == err && strings.HasPrefix(oe.Key, s.prefix) {
+		e := *oe
+		e.Key = strings.TrimPrefix(oe.Key, s.prefix)
+		return &e
+	}
+	return err
+}
+
+func (s *subBucket) ObjectPage(ctx context.Context, prefix, delimiter, token string) (Page, error) {
+	if escapes(prefix) {
+		return nil, &ObjectError{Bucket: s.Name(), Key: prefix, Err: ErrInvalidKey}
+	}
+
+	page, err := s.Bucket.ObjectPage(ctx, s.prefix+prefix, delimiter, token)
+	if err != nil {
+		return nil, s.err(err)
+	}
+	return &subPage{Page: page, s: s}, nil
+}
+
+func (s *subBucket) Head(ctx context.Context, key string) (ObjectHeader, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := s.Bucket.Head(ctx, k)
+	if err != nil {
+		return nil, s.err(err)
+	}
+	return &subHeader{ObjectHeader: header, key: key}, nil
+}
+
+func (s *subBucket) HeadIf(ctx context.Context, key string, cond Conditions) (ObjectHeader, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := HeadIf(ctx, s.Bucket, k, cond)
+	if err != nil {
+		return nil, s.err(err)
+	}
+	return &subHeader{ObjectHeader: header, key: key}, nil
+}
+
+func (s *subBucket) PutBatch(ctx context.Context, iter BatchIterator) error {
+	return PutEach(ctx, s, iter)
+}
+
+func (s *subBucket) Put(ctx context.Context, key string, data io.ReadSeeker, opts ...PutOption) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.err(s.Bucket.Put(ctx, k, data, opts...))
+}
+
+func (s *subBucket) PutIf(ctx context.Context, key string, data io.ReadSeeker, cond Conditions, opts ...PutOption) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.err(PutIf(ctx, s.Bucket, k, data, cond, opts...))
+}
+
+func (s *subBucket) PutStream(ctx context.Context, key string, data io.Reader, opts ...PutOption) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.err(Upload(ctx, s.Bucket, k, data, opts...))
+}
+
+func (s *subBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.Bucket.Get(ctx, k)
+	return r, s.err(err)
+}
+
+func (s *subBucket) GetIf(ctx context.Context, key string, cond Conditions) (io.ReadCloser, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := GetIf(ctx, s.Bucket, k, cond)
+	return r, s.err(err)
+}
+
+func (s *subBucket) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := GetRange(ctx, s.Bucket, k, offset, length)
+	return r, s.err(err)
+}
+
+func (s *subBucket) Delete(ctx context.Context, key string) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.err(s.Bucket.Delete(ctx, k))
+}
+
+func (s *subBucket) DeleteIf(ctx context.Context, key string, cond Conditions) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.err(DeleteIf(ctx, s.Bucket, k, cond))
+}
+
+func (s *subBucket) DeleteLimit() int {
+	if bb, ok := s.Bucket.(BulkDeleteBucket); ok {
+		return bb.DeleteLimit()
+	}
+	return 1
+}
+
+func (s *subBucket) DeleteMany(ctx context.Context, keys []string) error {
+	var failed []*ObjectError
+	mapped := make([]string, 0, len(keys))
+	for _, key := range keys {
+		k, err := s.key(key)
+		if err != nil {
+			failed = append(failed, objectError(s.Name(), key, err))
+			continue
+		}
+		mapped = append(mapped, k)
+	}
+
+	err := DeleteMany(ctx, s.Bucket, mapped)
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		for _, objErr := range batchErr.Errors {
+			failed = append(failed, objectError(s.Name(), objErr.Key, s.err(objErr)))
+		}
+	} else if err != nil {
+		return s.err(err)
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{Bucket: s.Name(), Errors: failed}
+	}
+	return nil
+}
+
+// Copy copies srcKey in src to dstKey in the view. If src is a view too, the
+// key is resolved in the bucket it views, so that copies between views of
+// the same bucket stay within the provider.
+func (s *subBucket) Copy(ctx context.Context, src Bucket, srcKey, dstKey string, opts ...CopyOption) error {
+	k, err := s.key(dstKey)
+	if err != nil {
+		return err
+	}
+
+	if ss, ok := src.(*subBucket); ok {
+		if srcKey, err = ss.key(srcKey); err != nil {
+			return err
+		}
+		src = ss.Bucket
+	}
+
+	cb, ok := s.Bucket.(CopyBucket)
+	if !ok {
+		return &ObjectError{Bucket: s.Name(), Key: dstKey, Err: ErrNotSupported}
+	}
+	return s.err(cb.Copy(ctx, src, srcKey, k, opts...))
+}
+
+// subPage is a page listed by a subBucket, with keys relative to its prefix.
+type subPage struct {
+	Page
+	s *subBucket
+}
+
+func (p *subPage) Objects() []Object {
+	objects := p.Page.Objects()
+	sub := make([]Object, len(objects))
+	for i, obj := range objects {
+		sub[i] = &subObject{Object: obj, s: p.s}
+	}
+	return sub
+}
+
+func (p *subPage) Prefixes() []string {
+	prefixes := p.Page.Prefixes()
+	sub := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		sub[i] = strings.TrimPrefix(prefix, p.s.prefix)
+	}
+	return sub
+}
+
+// subObject is an object listed by a subBucket.
+type subObject struct {
+	Object
+	s *subBucket
+}
+
+var _ RangeObject = (*subObject)(nil)
+
+func (o *subObject) Key() string {
+	return strings.TrimPrefix(o.Object.Key(), o.s.prefix)
+}
+
+func (o *subObject) Head(ctx context.Context) (ObjectHeader, error) {
+	header, err := o.Object.Head(ctx)
+	if err != nil {
+		return nil, o.s.err(err)
+	}
+	return &subHeader{ObjectHeader: header, key: o.Key()}, nil
+}
+
+func (o *subObject) Open(ctx context.Context) (io.ReadCloser, error) {
+	r, err := o.Object.Open(ctx)
+	return r, o.s.err(err)
+}
+
+func (o *subObject) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	r, err := OpenRange(ctx, o.Object, offset, length)
+	return r, o.s.err(err)
+}
+
+// subHeader is the header of an object of a subBucket.
+type subHeader struct {
+	ObjectHeader
+	key string
+}
+
+func (h *subHeader) Key() string { return h.key }
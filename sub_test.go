@@ -0,0 +1,191 @@
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/absostest"
+	"github.com/absfs/absos/examples/memory"
+)
+
+func newSubBucket(t *testing.T, prefix string) (absos.Bucket, absos.Bucket) {
+	t.Helper()
+
+	ctx := context.Background()
+	store := memory.NewStore()
+	if err := store.CreateBucket(ctx, "shared"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	buckets, err := store.ListBuckets(ctx)
+	if err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+
+	sub, err := absos.Sub(buckets[0], prefix)
+	if err != nil {
+		t.Fatalf("failed to create view: %v", err)
+	}
+	return sub, buckets[0]
+}
+
+func TestSub(t *testing.T) {
+	sub, b := newSubBucket(t, "tenants/alice")
+	ctx := context.Background()
+
+	if err := sub.Put(ctx, "docs/a.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := b.Put(ctx, "tenants/bob/docs/b.txt", strings.NewReader("other")); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	if _, err := b.Head(ctx, "tenants/alice/docs/a.txt"); err != nil {
+		t.Fatalf("expected the key to be prefixed: %v", err)
+	}
+
+	header, err := sub.Head(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatalf("failed to head: %v", err)
+	}
+	if header.Key() != "docs/a.txt" || header.Size() != 5 {
+		t.Errorf("unexpected header %q of %d bytes", header.Key(), header.Size())
+	}
+
+	r, err := absos.GetRange(ctx, sub, "docs/a.txt", 1, 3)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "ell" {
+		t.Errorf("expected ell, got %q", data)
+	}
+
+	page, err := sub.ObjectPage(ctx, "", "/", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if prefixes := page.Prefixes(); len(prefixes) != 1 || prefixes[0] != "docs/" {
+		t.Errorf("expected prefix docs/, got %v", prefixes)
+	}
+
+	page, err = sub.ObjectPage(ctx, "docs/", "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	objects := page.Objects()
+	if len(objects) != 1 || objects[0].Key() != "docs/a.txt" {
+		t.Fatalf("expected docs/a.txt only, got %d objects", len(objects))
+	}
+	if header, err := objects[0].Head(ctx); err != nil || header.Key() != "docs/a.txt" {
+		t.Errorf("unexpected object header: %v", err)
+	}
+
+	if err := sub.Delete(ctx, "docs/a.txt"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	_, err = sub.Get(ctx, "docs/a.txt")
+	var oe *absos.ObjectError
+	if !errors.As(err, &oe) || !errors.Is(err, absos.ErrObjectNotFound) || oe.Key != "docs/a.txt" {
+		t.Errorf("expected ErrObjectNotFound for docs/a.txt, got %v", err)
+	}
+}
+
+func TestSubEscape(t *testing.T) {
+	sub, b := newSubBucket(t, "tenants/alice/")
+	ctx := context.Background()
+
+	for _, key := range []string{"../bob/secret", "docs/../../bob/secret", "..", ""} {
+		if err := sub.Put(ctx, key, strings.NewReader("data")); !errors.Is(err, absos.ErrInvalidKey) {
+			t.Errorf("Put(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+		if _, err := sub.Get(ctx, key); !errors.Is(err, absos.ErrInvalidKey) {
+			t.Errorf("Get(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+	if _, err := sub.ObjectPage(ctx, "../", "", ""); !errors.Is(err, absos.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for listing, got %v", err)
+	}
+
+	// Dots within a segment do not escape
+	if err := sub.Put(ctx, "a..b", strings.NewReader("data")); err != nil {
+		t.Errorf("failed to put a..b: %v", err)
+	}
+
+	for _, prefix := range []string{"", "/", "tenants/../admin"} {
+		if _, err := absos.Sub(b, prefix); !errors.Is(err, absos.ErrInvalidKey) {
+			t.Errorf("Sub(%q): expected ErrInvalidKey, got %v", prefix, err)
+		}
+	}
+}
+
+func TestSubDeleteManyCopy(t *testing.T) {
+	sub, b := newSubBucket(t, "tenants/alice")
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		if err := sub.Put(ctx, key, strings.NewReader(key)); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+	}
+
+	// Copies between views of the same bucket stay within the provider
+	other, err := absos.Sub(b, "tenants/bob")
+	if err != nil {
+		t.Fatalf("failed to create view: %v", err)
+	}
+	if err := other.(absos.CopyBucket).Copy(ctx, sub, "a", "copy"); err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	if _, err := b.Head(ctx, "tenants/bob/copy"); err != nil {
+		t.Errorf("expected the copy in the other view: %v", err)
+	}
+
+	err = absos.DeleteMany(ctx, sub, []string{"a", "b", "missing", "../bob/copy"})
+	var batchErr *absos.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "../bob/copy" {
+		t.Fatalf("expected a batch error for the escaping key, got %v", err)
+	}
+	if !errors.Is(err, absos.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	for _, key := range []string{"tenants/alice/a", "tenants/alice/b"} {
+		if _, err := b.Head(ctx, key); !errors.Is(err, absos.ErrObjectNotFound) {
+			t.Errorf("expected %q to be deleted, got %v", key, err)
+		}
+	}
+	if _, err := b.Head(ctx, "tenants/bob/copy"); err != nil {
+		t.Errorf("expected the other view to be kept: %v", err)
+	}
+}
+
+// subStore scopes the buckets of a store to a prefix.
+type subStore struct {
+	absos.ObjectStore
+}
+
+func (s *subStore) ListBuckets(ctx context.Context) ([]absos.Bucket, error) {
+	buckets, err := s.ObjectStore.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, b := range buckets {
+		if buckets[i], err = absos.Sub(b, "tenant/"); err != nil {
+			return nil, err
+		}
+	}
+	return buckets, nil
+}
+
+func TestSubConformance(t *testing.T) {
+	absostest.RunConformance(t, func(t *testing.T) absos.ObjectStore {
+		return &subStore{ObjectStore: memory.NewStore()}
+	})
+}
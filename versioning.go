@@ -0,0 +1,109 @@
+package absos
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// VersioningStatus is the versioning state of a bucket. The values match
+// those of the S3 API.
+type VersioningStatus string
+
+const (
+	// VersioningDisabled is the state of buckets that never had versioning
+	// enabled. Once enabled, versioning can only be suspended.
+	VersioningDisabled VersioningStatus = ""
+
+	// VersioningEnabled keeps every version of an object. Deleting an
+	// object adds a delete marker, which hides the object without removing
+	// its versions.
+	VersioningEnabled VersioningStatus = "Enabled"
+
+	// VersioningSuspended keeps existing versions, but new writes and
+	// deletes replace the version with the ID NullVersion.
+	VersioningSuspended VersioningStatus = "Suspended"
+)
+
+// NullVersion is the version ID of objects written while versioning was
+// disabled or suspended.
+const NullVersion = "null"
+
+// ObjectVersion describes a version of an object, or a delete marker.
+type ObjectVersion struct {
+	// Key is the key of the object.
+	Key string
+
+	// VersionID identifies the version.
+	VersionID string
+
+	// IsLatest reports whether this is the current version of the object.
+	// A delete marker that is the latest version hides the object.
+	IsLatest bool
+
+	// DeleteMarker reports whether the version records a deletion rather
+	// than object contents.
+	DeleteMarker bool
+
+	// Size is the size of the version in bytes.
+	Size int64
+
+	// ModTime is the time the version was created.
+	ModTime time.Time
+
+	// ETag is the entity tag of the version. It is nil for delete markers.
+	ETag []byte
+}
+
+// VersionPage is a page of object versions, ordered by key and from the
+// newest to the oldest version of each key.
+type VersionPage interface {
+	// Versions returns the versions and delete markers in this page.
+	Versions() []ObjectVersion
+
+	// NextPage returns the token to use for fetching the next page.
+	// Returns an empty string if this is the last page.
+	NextPage() string
+
+	// Last returns true if this is the last page of results.
+	Last() bool
+}
+
+// VersionedBucket is implemented by buckets that can keep the previous
+// versions of their objects.
+//
+// The methods of Bucket operate on the latest version: Put adds a version,
+// and Delete adds a delete marker, after which Get and Head report
+// ErrObjectNotFound. Deleting a specific version with DeleteVersion removes
+// it permanently, so deleting the latest version or delete marker restores
+// the previous version.
+//
+// Unlike S3, deleting a key without a current version, because it never
+// existed or is hidden by a delete marker, adds no delete marker: Delete and
+// DeleteIf return ErrObjectNotFound as they do in unversioned buckets.
+//
+// Operations on versions that do not exist return ErrVersionNotFound.
+// Reading a delete marker returns ErrObjectNotFound.
+type VersionedBucket interface {
+	Bucket
+
+	// Versioning returns the versioning state of the bucket.
+	Versioning(ctx context.Context) (VersioningStatus, error)
+
+	// SetVersioning enables or suspends versioning.
+	SetVersioning(ctx context.Context, status VersioningStatus) error
+
+	// ListVersions returns a page of the versions and delete markers of the
+	// objects with the given prefix. The token parameter is used for
+	// pagination; pass an empty string for the first page.
+	ListVersions(ctx context.Context, prefix, token string) (VersionPage, error)
+
+	// GetVersion retrieves a version of an object and returns a reader for its contents.
+	GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error)
+
+	// HeadVersion retrieves the metadata of a version of an object.
+	HeadVersion(ctx context.Context, key, versionID string) (ObjectHeader, error)
+
+	// DeleteVersion permanently removes a version or delete marker.
+	DeleteVersion(ctx context.Context, key, versionID string) error
+}
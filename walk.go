@@ -0,0 +1,132 @@
+package absos
+
+import (
+	"context"
+	"errors"
+)
+
+// SkipPrefix is returned by a WalkFunc to skip a common prefix. Returned for
+// an object, it skips the remaining entries of the prefix containing it.
+var SkipPrefix = errors.New("skip this prefix")
+
+// SkipAll is returned by a WalkFunc to stop the walk without an error.
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// WalkFunc is called by Walk for every object and common prefix. For common
+// prefixes, key is the prefix and obj is nil.
+//
+// Returning SkipPrefix or SkipAll changes the course of the walk; any other
+// error stops it and is returned by Walk.
+type WalkFunc func(key string, obj Object) error
+
+// Walk visits the objects with the given prefix in lexicographic order,
+// descending into the common prefixes formed by delimiter after calling fn
+// for them. With an empty delimiter, every object is visited without
+// prefixes. The next page of each level is fetched while fn processes the
+// current one.
+func Walk(ctx context.Context, b Bucket, prefix, delimiter string, fn WalkFunc) error {
+	err := walk(ctx, b, prefix, delimiter, fn)
+	if err == SkipPrefix || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walk visits one level of the hierarchy.
+func walk(ctx context.Context, b Bucket, prefix, delimiter string, fn WalkFunc) error {
+	p := newPager(ctx, b, prefix, delimiter)
+	defer p.close()
+
+	for {
+		page, err := p.next()
+		if page == nil || err != nil {
+			return err
+		}
+
+		objects, prefixes := page.Objects(), page.Prefixes()
+		for len(objects) > 0 || len(prefixes) > 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// Merge objects and prefixes, which are each sorted
+			if len(prefixes) == 0 || len(objects) > 0 && objects[0].Key() < prefixes[0] {
+				obj := objects[0]
+				objects = objects[1:]
+				if err := fn(obj.Key(), obj); err != nil {
+					return err
+				}
+				continue
+			}
+
+			sub := prefixes[0]
+			prefixes = prefixes[1:]
+
+			err := fn(sub, nil)
+			if err == nil {
+				err = walk(ctx, b, sub, delimiter, fn)
+			}
+			if err != nil && err != SkipPrefix {
+				return err
+			}
+		}
+	}
+}
+
+// pager iterates over the pages of a listing, fetching each page in the
+// background while the caller processes the previous one.
+type pager struct {
+	ctx               context.Context
+	cancel            context.CancelFunc
+	b                 Bucket
+	prefix, delimiter string
+	pending           chan pageResult
+}
+
+type pageResult struct {
+	page Page
+	err  error
+}
+
+// newPager starts fetching the first page of a listing.
+func newPager(ctx context.Context, b Bucket, prefix, delimiter string) *pager {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &pager{ctx: ctx, cancel: cancel, b: b, prefix: prefix, delimiter: delimiter}
+	p.fetch("")
+	return p
+}
+
+func (p *pager) fetch(token string) {
+	ch := make(chan pageResult, 1)
+	p.pending = ch
+
+	go func() {
+		page, err := p.b.ObjectPage(p.ctx, p.prefix, p.delimiter, token)
+		ch <- pageResult{page, err}
+	}()
+}
+
+// next waits for the pending page and starts fetching the following one.
+// It returns a nil page once the listing is exhausted.
+func (p *pager) next() (Page, error) {
+	if p.pending == nil {
+		return nil, nil
+	}
+
+	r := <-p.pending
+	p.pending = nil
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if token := r.page.NextPage(); !r.page.Last() && token != "" {
+		p.fetch(token)
+	}
+
+	return r.page, nil
+}
+
+// close cancels the fetch of a page that will not be used.
+func (p *pager) close() {
+	p.cancel()
+}
@@ -0,0 +1,111 @@
+package absos_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absos"
+	"github.com/absfs/absos/examples/memory"
+)
+
+// newListBucket returns a bucket listing two entries per page, holding an
+// empty object for each key.
+func newListBucket(t *testing.T, keys ...string) absos.Bucket {
+	t.Helper()
+
+	store := memory.NewStore(memory.WithPageSize(2))
+	ctx := context.Background()
+
+	if err := store.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	buckets, _ := store.ListBuckets(ctx)
+	for _, key := range keys {
+		if err := buckets[0].Put(ctx, key, strings.NewReader("")); err != nil {
+			t.Fatalf("failed to put object: %v", err)
+		}
+	}
+
+	return buckets[0]
+}
+
+var walkKeys = []string{"a", "b/1", "b/2", "b/c/3", "d", "e/4", "f"}
+
+func TestWalk(t *testing.T) {
+	b := newListBucket(t, walkKeys...)
+
+	tests := []struct {
+		name      string
+		delimiter string
+		fn        func(key string, obj absos.Object) error
+		expected  []string
+	}{
+		{"All", "/", nil, []string{"a", "b/", "b/1", "b/2", "b/c/", "b/c/3", "d", "e/", "e/4", "f"}},
+		{"NoDelimiter", "", nil, walkKeys},
+		{"SkipPrefix", "/", func(key string, obj absos.Object) error {
+			if key == "b/" {
+				return absos.SkipPrefix
+			}
+			return nil
+		}, []string{"a", "b/", "d", "e/", "e/4", "f"}},
+		{"SkipRest", "/", func(key string, obj absos.Object) error {
+			if key == "b/1" {
+				return absos.SkipPrefix
+			}
+			return nil
+		}, []string{"a", "b/", "b/1", "d", "e/", "e/4", "f"}},
+		{"SkipAll", "/", func(key string, obj absos.Object) error {
+			if key == "b/2" {
+				return absos.SkipAll
+			}
+			return nil
+		}, []string{"a", "b/", "b/1", "b/2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var visited []string
+			err := absos.Walk(context.Background(), b, "", tt.delimiter, func(key string, obj absos.Object) error {
+				visited = append(visited, key)
+				if (obj == nil) != strings.HasSuffix(key, "/") {
+					t.Errorf("unexpected object %v for %q", obj, key)
+				}
+				if tt.fn != nil {
+					return tt.fn(key, obj)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to walk: %v", err)
+			}
+
+			if strings.Join(visited, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, visited)
+			}
+		})
+	}
+}
+
+func TestWalkError(t *testing.T) {
+	b := newListBucket(t, walkKeys...)
+	errStop := errors.New("stop")
+
+	err := absos.Walk(context.Background(), b, "b/", "/", func(key string, obj absos.Object) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("expected %v, got %v", errStop, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = absos.Walk(ctx, b, "", "/", func(key string, obj absos.Object) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}